@@ -191,6 +191,12 @@ echo -e "${YELLOW}Building ${GOOS}/${GOARCH}...${NC}"
 go build -tags prod -trimpath -ldflags "${LDFLAGS}" -o "${BIN_DIR}/${OUTPUT_NAME}" ./cmd/yggstack-gui
 echo -e "${GREEN}✓ Built ${OUTPUT_NAME}${NC}"
 
+# Build CLI client next to the GUI so both use the same data directory
+CTL_NAME="yggstackctl"
+[ "$GOOS" = "windows" ] && CTL_NAME="yggstackctl.exe"
+go build -trimpath -ldflags "-X ${VERSION_PKG}.Version=${VERSION} -s -w" -o "${BIN_DIR}/${CTL_NAME}" ./cmd/yggstackctl
+echo -e "${GREEN}✓ Built ${CTL_NAME}${NC}"
+
 # Copy CEF framework files to bin directory
 echo ""
 echo -e "${YELLOW}Copying CEF framework files...${NC}"
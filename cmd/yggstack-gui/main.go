@@ -25,6 +25,12 @@ import (
 var resources embed.FS
 
 func main() {
+	// Headless mode runs the node without CEF, the browser window or the tray.
+	// Must be checked before CEF initialization so CEF libraries are never loaded.
+	if hasFlag("--headless") {
+		os.Exit(runHeadless())
+	}
+
 	// Initialize CEF globally first - required for all processes
 	cef.GlobalInit(nil, &resources)
 
@@ -40,13 +46,7 @@ func main() {
 	// === Main process initialization below ===
 
 	// Check if app should start minimized (from autostart)
-	startMinimized := false
-	for _, arg := range os.Args[1:] {
-		if arg == "--minimized" {
-			startMinimized = true
-			break
-		}
-	}
+	startMinimized := hasFlag("--minimized")
 
 	// If starting from autostart (minimized), add a small delay
 	// Task Scheduler already adds 30 second delay, but this extra delay
@@ -173,6 +173,41 @@ func main() {
 	cef.Run(cefApp)
 }
 
+// hasFlag reports whether the given flag was passed on the command line
+func hasFlag(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runHeadless runs the application in headless mode and returns the exit code
+func runHeadless() int {
+	log := logger.NewWithConfig(logger.Config{
+		Level:      "info",
+		FilePath:   logger.GetLogFilePath(),
+		Console:    true,
+		Production: false,
+	})
+	defer log.Sync()
+
+	log.Info("Starting Yggstack-GUI in headless mode", "version", version.Version)
+
+	headless := app.NewHeadless(app.HeadlessConfig{
+		Version: version.Version,
+		Logger:  log,
+	})
+
+	if err := headless.Run(); err != nil {
+		log.Error("Headless mode failed", "error", err)
+		return 1
+	}
+
+	return 0
+}
+
 // startAssetServer starts an HTTP server to serve embedded frontend assets
 func startAssetServer(log *logger.Logger) (string, error) {
 	// Get the dist subdirectory from embedded assets
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// apiPrefix must match ipc.ControlAPIPrefix
+const apiPrefix = "/api/v1/"
+
+// response mirrors ipc.Response with raw data
+type response struct {
+	Success   bool            `json:"success"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     *responseError  `json:"error,omitempty"`
+	RequestID string          `json:"requestId"`
+}
+
+// responseError mirrors ipc.Error
+type responseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+func (e *responseError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Code, e.Message, e.Details)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// client calls the control API of a running instance
+type client struct {
+	baseURL    string
+	token      string
+	node       string // Target node, empty for the primary node
+	httpClient *http.Client
+	requestSeq int
+}
+
+// newClient creates a control API client that sends requests to node
+func newClient(address, token, node string, timeout time.Duration) *client {
+	return &client{
+		baseURL:    "http://" + address + apiPrefix,
+		token:      token,
+		node:       node,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// call sends an IPC event with an optional payload and returns the response data
+func (c *client) call(event string, payload interface{}) (json.RawMessage, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	method := http.MethodPost
+	if event == "events" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+event, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.requestSeq++
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "yggstackctl-"+strconv.Itoa(c.requestSeq))
+	if c.node != "" {
+		req.Header.Set("X-Node-ID", c.node)
+	}
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach yggstack-gui: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	var resp response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid response (HTTP %d): %w", httpResp.StatusCode, err)
+	}
+
+	if !resp.Success {
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return nil, fmt.Errorf("request failed (HTTP %d)", httpResp.StatusCode)
+	}
+
+	return resp.Data, nil
+}
@@ -0,0 +1,928 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Event names, one-to-one with the constants in internal/ipc/events.go.
+// They are duplicated here so the CLI does not link the CEF bindings.
+const (
+	eventNodeStart      = "node:start"
+	eventNodeStop       = "node:stop"
+	eventNodeStatus     = "node:status"
+	eventPeersList      = "peers:list"
+	eventPeersAdd       = "peers:add"
+	eventPeersRemove    = "peers:remove"
+	eventPeersHealth    = "peers:health"
+	eventPeersDiscover  = "peers:discover"
+	eventSettingsGet    = "settings:get"
+	eventConfigImport   = "config:import"
+	eventConfigExport   = "config:export"
+	eventIdentityExport = "identity:export"
+	eventIdentityImport = "identity:import"
+	eventKeygenStart    = "keygen:start"
+	eventKeygenStop     = "keygen:stop"
+	eventKeygenStatus   = "keygen:status"
+	eventKeygenAdopt    = "keygen:adopt"
+	eventProxyConfig    = "proxy:config"
+	eventProxyStatus    = "proxy:status"
+	eventProxyStart     = "proxy:start"
+	eventProxyStop      = "proxy:stop"
+	eventProxyClients   = "proxy:clients"
+	eventProxyRouting   = "proxy:routing"
+	eventMappingList    = "mapping:list"
+	eventMappingAdd     = "mapping:add"
+	eventMappingRemove  = "mapping:remove"
+	eventMappingEnable  = "mapping:enable"
+	eventMappingDisable = "mapping:disable"
+	eventSessionsList   = "sessions:list"
+	eventSessionsStats  = "sessions:stats"
+	eventStatsHistory   = "stats:history"
+	eventLogList        = "log:list"
+	eventLogClear       = "log:clear"
+	eventProfileList    = "profile:list"
+	eventProfileCreate  = "profile:create"
+	eventProfileClone   = "profile:clone"
+	eventProfileRename  = "profile:rename"
+	eventProfileDelete  = "profile:delete"
+	eventProfileSwitch  = "profile:switch"
+	eventNodesList      = "nodes:list"
+	eventNodesAdd       = "nodes:add"
+	eventNodesRemove    = "nodes:remove"
+)
+
+// errUsage is returned when command arguments are invalid
+var errUsage = errors.New("invalid arguments")
+
+// command maps a CLI command onto an IPC event
+type command struct {
+	group       string
+	name        string
+	args        string
+	description string
+	event       string
+
+	// payload builds the request payload from command arguments
+	payload func(args []string) (interface{}, error)
+
+	// print renders the response data as human readable output
+	print func(w io.Writer, data json.RawMessage) error
+
+	// run replaces the default single request (used by logs tail)
+	run func(c *client, args []string, out *output) error
+}
+
+var peerColumns = []column{
+	{header: "URI", key: "uri"},
+	{header: "CONNECTED", key: "connected"},
+	{header: "INBOUND", key: "inbound"},
+	{header: "LATENCY", key: "latency", format: formatMillis},
+	{header: "RX", key: "rxBytes", format: formatBytes},
+	{header: "TX", key: "txBytes", format: formatBytes},
+}
+
+var peerHealthColumns = []column{
+	{header: "URI", key: "uri"},
+	{header: "STANDBY", key: "standby"},
+	{header: "ATTACHED", key: "attached"},
+	{header: "CONNECTED", key: "connected"},
+	{header: "SUCCESS", key: "successRate", format: formatPercent},
+	{header: "FAILURES", key: "consecutiveFailures"},
+	{header: "LATENCY", key: "averageLatency", format: formatMillis},
+}
+
+var directoryColumns = []column{
+	{header: "URI", key: "uri"},
+	{header: "REGION", key: "region"},
+	{header: "REACHABLE", key: "reachable"},
+	{header: "RTT", key: "rtt", format: formatMillis},
+	{header: "CONFIGURED", key: "configured"},
+}
+
+var historyColumns = []column{
+	{header: "SERIES", key: "name"},
+	{header: "IN", key: "bytesIn", format: formatBytes},
+	{header: "OUT", key: "bytesOut", format: formatBytes},
+}
+
+var mappingColumns = []column{
+	{header: "ID", key: "id"},
+	{header: "TYPE", key: "type"},
+	{header: "SOURCE", key: "source"},
+	{header: "TARGET", key: "target"},
+	{header: "ENABLED", key: "enabled"},
+	{header: "ACTIVE", key: "active"},
+	{header: "IN", key: "bytesIn", format: formatBytes},
+	{header: "OUT", key: "bytesOut", format: formatBytes},
+}
+
+var sessionColumns = []column{
+	{header: "ADDRESS", key: "address"},
+	{header: "PUBLIC KEY", key: "publicKey"},
+	{header: "RX", key: "rxBytes", format: formatBytes},
+	{header: "TX", key: "txBytes", format: formatBytes},
+	{header: "UPTIME", key: "uptime"},
+}
+
+var profileColumns = []column{
+	{header: "NAME", key: "name"},
+	{header: "ACTIVE", key: "active"},
+	{header: "ADDRESS", key: "ipv6Address"},
+}
+
+var nodeColumns = []column{
+	{header: "ID", key: "id"},
+	{header: "PRIMARY", key: "primary"},
+	{header: "STATE", key: "state"},
+	{header: "ADDRESS", key: "ipv6Address"},
+	{header: "PROXY", key: "proxy"},
+}
+
+var proxyClientColumns = []column{
+	{header: "CLIENT", key: "address"},
+	{header: "ALLOWED", key: "allowed"},
+	{header: "ACTIVE", key: "activeConnections"},
+	{header: "TOTAL", key: "totalConnections"},
+	{header: "REJECTED", key: "rejectedConnections"},
+	{header: "IN", key: "bytesIn", format: formatBytes},
+	{header: "OUT", key: "bytesOut", format: formatBytes},
+	{header: "LAST SEEN", key: "lastSeen", format: formatTimestamp},
+}
+
+var routingRuleColumns = []column{
+	{header: "MATCH", key: "match"},
+	{header: "ACTION", key: "action"},
+}
+
+var logColumns = []column{
+	{header: "TIME", key: "timestamp", format: formatTimestamp},
+	{header: "LEVEL", key: "level"},
+	{header: "SOURCE", key: "source"},
+	{header: "MESSAGE", key: "message"},
+}
+
+// commands lists all supported commands
+var commands = []*command{
+	{group: "node", name: "start", description: "Start the Yggdrasil node", event: eventNodeStart},
+	{group: "node", name: "stop", description: "Stop the Yggdrasil node", event: eventNodeStop},
+	{group: "node", name: "status", description: "Show node status", event: eventNodeStatus},
+
+	{group: "peers", name: "list", description: "List peers", event: eventPeersList,
+		print: tablePrinter(peerColumns)},
+	{group: "peers", name: "add", args: "<uri>", description: "Add a peer", event: eventPeersAdd,
+		payload: uriPayload},
+	{group: "peers", name: "remove", args: "<uri>", description: "Remove a peer", event: eventPeersRemove,
+		payload: uriPayload},
+	{group: "peers", name: "health", description: "Show peer probe history and failover state", event: eventPeersHealth,
+		print: tablePrinter(peerHealthColumns)},
+	{group: "peers", name: "discover", args: "[-source url] [-regions a,b] [-limit n]", event: eventPeersDiscover,
+		description: "Rank public peers by reachability, region and RTT", payload: peersDiscoverPayload, print: directoryPrinter},
+
+	{group: "mappings", name: "list", description: "List port mappings", event: eventMappingList,
+		print: tablePrinter(mappingColumns)},
+	{group: "mappings", name: "add", args: "[-up rate] [-down rate] [-max-conns n] [-max-sessions n] [-udp-idle 2m] [-allow list] [-deny list] <type> <source> <target> [id]", event: eventMappingAdd,
+		description: "Add a mapping (type: local-tcp, remote-tcp, local-udp, remote-udp)", payload: mappingAddPayload},
+	{group: "mappings", name: "remove", args: "<id>", description: "Remove a mapping", event: eventMappingRemove,
+		payload: idPayload},
+	{group: "mappings", name: "enable", args: "<id>", description: "Enable a mapping", event: eventMappingEnable,
+		payload: idPayload},
+	{group: "mappings", name: "disable", args: "<id>", description: "Disable a mapping", event: eventMappingDisable,
+		payload: idPayload},
+
+	{group: "proxy", name: "status", description: "Show SOCKS proxy status", event: eventProxyStatus},
+	{group: "proxy", name: "start", description: "Start the SOCKS proxy", event: eventProxyStart},
+	{group: "proxy", name: "stop", description: "Stop the SOCKS proxy", event: eventProxyStop},
+	{group: "proxy", name: "clients", description: "Show connections and traffic per SOCKS client", event: eventProxyClients,
+		print: tablePrinter(proxyClientColumns)},
+	{group: "proxy", name: "config", args: "[-user name] [-password-file file] [-no-auth] [-allow list] [-allow-all] [-http address] <listen-address> [nameserver]",
+		description: "Start the SOCKS proxy with the given address", event: eventProxyConfig,
+		payload: proxyConfigPayload},
+	{group: "proxy", name: "routing", args: "[-off] [-default direct|reject|upstream] [-upstream url] [match=action...]",
+		description: "Show the proxy routing, or replace it with the given rules", event: eventProxyRouting,
+		payload: proxyRoutingPayload, print: routingPrinter},
+
+	{group: "sessions", name: "list", description: "List active sessions", event: eventSessionsList,
+		print: tablePrinter(sessionColumns)},
+	{group: "sessions", name: "stats", description: "Show session statistics", event: eventSessionsStats},
+
+	{group: "stats", name: "history", args: "[-resolution minute|hour] [-since duration] [-csv file] [series...]",
+		description: "Show traffic per peer group, proxy and mapping", event: eventStatsHistory, run: runStatsHistory},
+
+	{group: "logs", name: "list", args: "[-n count]", description: "Show recent log entries", event: eventLogList,
+		payload: logListPayload, print: logPrinter},
+	{group: "logs", name: "tail", args: "[-n count] [-interval duration]", description: "Follow log entries",
+		event: eventLogList, run: runLogTail},
+	{group: "logs", name: "clear", description: "Clear the log buffer", event: eventLogClear},
+
+	{group: "profiles", name: "list", description: "List node profiles", event: eventProfileList,
+		print: profilePrinter},
+	{group: "profiles", name: "create", args: "<name>", description: "Create a profile with a new identity",
+		event: eventProfileCreate, payload: namePayload, print: profilePrinter},
+	{group: "profiles", name: "clone", args: "[-source name] [-new-identity] <name>",
+		description: "Copy a profile, the active one by default", event: eventProfileClone,
+		payload: profileClonePayload, print: profilePrinter},
+	{group: "profiles", name: "rename", args: "<name> <new-name>", description: "Rename a profile",
+		event: eventProfileRename, payload: profileRenamePayload, print: profilePrinter},
+	{group: "profiles", name: "delete", args: "<name>", description: "Delete an inactive profile",
+		event: eventProfileDelete, payload: namePayload, print: profilePrinter},
+	{group: "profiles", name: "switch", args: "<name>", description: "Switch profile, restarting a running node",
+		event: eventProfileSwitch, payload: namePayload, print: profilePrinter},
+
+	{group: "nodes", name: "list", description: "List nodes running in the instance", event: eventNodesList,
+		print: nodePrinter},
+	{group: "nodes", name: "add", args: "<profile>", description: "Run a profile as an additional node",
+		event: eventNodesAdd, payload: idPayload, print: nodePrinter},
+	{group: "nodes", name: "remove", args: "<profile>", description: "Stop and remove an additional node",
+		event: eventNodesRemove, payload: idPayload, print: nodePrinter},
+
+	{group: "settings", name: "get", description: "Show application settings", event: eventSettingsGet},
+
+	{group: "config", name: "import", args: "[-by-path] <file> [yggstack flags]", event: eventConfigImport,
+		description: "Import a yggdrasil.conf and yggstack -socks/-local-tcp style flags", payload: configImportPayload},
+	{group: "config", name: "export", args: "[-format hjson|json] [file]", event: eventConfigExport,
+		description: "Export the node config in yggdrasil.conf format", run: runConfigExport},
+
+	{group: "identity", name: "export", args: "[-peers] [-mappings] [-passphrase-file file] <file>", event: eventIdentityExport,
+		description: "Export the node keys as a passphrase-protected bundle", run: runIdentityExport},
+	{group: "identity", name: "import", args: "[-passphrase-file file] <file>", event: eventIdentityImport,
+		description: "Import a passphrase-protected identity bundle", payload: identityImportPayload},
+
+	{group: "keygen", name: "start", args: "[-workers n] [-prefix 200:abcd]", event: eventKeygenStart,
+		description: "Search for a key with a stronger or matching address", payload: keygenStartPayload},
+	{group: "keygen", name: "stop", description: "Stop the key search", event: eventKeygenStop},
+	{group: "keygen", name: "status", description: "Show key search progress", event: eventKeygenStatus},
+	{group: "keygen", name: "adopt", description: "Use the best key found as the node identity", event: eventKeygenAdopt},
+}
+
+// findCommand looks up a command by group and name
+func findCommand(group, name string) *command {
+	for _, cmd := range commands {
+		if cmd.group == group && cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// tablePrinter returns a printer that renders a list as a table
+func tablePrinter(columns []column) func(w io.Writer, data json.RawMessage) error {
+	return func(w io.Writer, data json.RawMessage) error {
+		return printTable(w, data, columns)
+	}
+}
+
+func uriPayload(args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	return map[string]string{"uri": args[0]}, nil
+}
+
+func idPayload(args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	return map[string]string{"id": args[0]}, nil
+}
+
+func mappingAddPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	up := fs.String("up", "", "upload limit in bytes per second (K, M, G suffixes)")
+	down := fs.String("down", "", "download limit in bytes per second (K, M, G suffixes)")
+	maxConns := fs.Int("max-conns", 0, "maximum concurrent TCP connections")
+	maxSessions := fs.Int("max-sessions", 0, "maximum concurrent UDP sessions")
+	udpIdle := fs.Duration("udp-idle", 0, "close UDP sessions idle for this long")
+	allow := fs.String("allow", "", "allowed public keys, addresses or subnets, comma separated")
+	deny := fs.String("deny", "", "denied public keys, addresses or subnets, comma separated")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 3 || fs.NArg() > 4 {
+		return nil, errUsage
+	}
+	upLimit, err := parseByteRate(*up)
+	if err != nil {
+		return nil, err
+	}
+	downLimit, err := parseByteRate(*down)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{
+		"type":    fs.Arg(0),
+		"source":  fs.Arg(1),
+		"target":  fs.Arg(2),
+		"enabled": true,
+	}
+	if fs.NArg() == 4 {
+		payload["id"] = fs.Arg(3)
+	}
+	if upLimit > 0 {
+		payload["uploadLimit"] = upLimit
+	}
+	if downLimit > 0 {
+		payload["downloadLimit"] = downLimit
+	}
+	if *maxConns > 0 {
+		payload["maxConnections"] = *maxConns
+	}
+	if *maxSessions > 0 {
+		payload["maxSessions"] = *maxSessions
+	}
+	if *udpIdle > 0 {
+		payload["udpIdleTimeout"] = int((*udpIdle + time.Second - 1) / time.Second)
+	}
+	if *allow != "" {
+		payload["allow"] = strings.Split(*allow, ",")
+	}
+	if *deny != "" {
+		payload["deny"] = strings.Split(*deny, ",")
+	}
+	return payload, nil
+}
+
+// parseByteRate parses a rate such as 500K or 2M into bytes per second.
+// An empty string means unlimited.
+func parseByteRate(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	digits := s
+	if multiplier > 1 {
+		digits = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return n * multiplier, nil
+}
+
+func proxyConfigPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	user := fs.String("user", "", "require this username")
+	passwordFile := fs.String("password-file", "", "file with the password")
+	noAuth := fs.Bool("no-auth", false, "remove the saved credentials")
+	allow := fs.String("allow", "", "allowed client addresses or subnets, comma separated")
+	allowAll := fs.Bool("allow-all", false, "remove the client allowlist")
+	httpAddr := fs.String("http", "", "also serve an HTTP proxy on this address")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 || fs.NArg() > 2 ||
+		(*user != "" && *noAuth) || (*allow != "" && *allowAll) {
+		return nil, errUsage
+	}
+
+	payload := map[string]interface{}{
+		"enabled":       true,
+		"listenAddress": fs.Arg(0),
+	}
+	if fs.NArg() == 2 {
+		payload["nameserver"] = fs.Arg(1)
+	}
+	if *httpAddr != "" {
+		payload["httpListenAddress"] = *httpAddr
+	}
+	if *user != "" {
+		password, err := readSecret(*passwordFile, envProxyPassword, "password", "-password-file")
+		if err != nil {
+			return nil, err
+		}
+		payload["username"] = *user
+		payload["password"] = password
+	}
+	if *noAuth {
+		payload["auth"] = false
+	}
+	if *allow != "" {
+		payload["allowedClients"] = strings.Split(*allow, ",")
+	}
+	if *allowAll {
+		payload["allowedClients"] = []string{}
+	}
+	return payload, nil
+}
+
+// proxyRoutingPayload builds the routing config. Without arguments the
+// saved routing is shown.
+func proxyRoutingPayload(args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	fs := flag.NewFlagSet("routing", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	off := fs.Bool("off", false, "send every destination over Yggdrasil")
+	fallback := fs.String("default", "", "action for destinations outside Yggdrasil")
+	upstream := fs.String("upstream", "", "upstream proxy URL")
+	if err := fs.Parse(args); err != nil {
+		return nil, errUsage
+	}
+
+	rules := []map[string]string{}
+	for _, arg := range fs.Args() {
+		match, action, ok := strings.Cut(arg, "=")
+		if !ok || match == "" || action == "" {
+			return nil, errUsage
+		}
+		rules = append(rules, map[string]string{"match": match, "action": action})
+	}
+
+	return map[string]interface{}{
+		"enabled":  !*off,
+		"default":  *fallback,
+		"upstream": *upstream,
+		"rules":    rules,
+	}, nil
+}
+
+func keygenStartPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	workers := fs.Int("workers", 0, "search goroutines")
+	prefix := fs.String("prefix", "", "address prefix")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return nil, errUsage
+	}
+	return map[string]interface{}{
+		"workers": *workers,
+		"prefix":  *prefix,
+	}, nil
+}
+
+func peersDiscoverPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	source := fs.String("source", "", "peer list URL")
+	regions := fs.String("regions", "", "preferred regions, comma separated")
+	limit := fs.Int("limit", 20, "maximum number of peers")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return nil, errUsage
+	}
+
+	payload := map[string]interface{}{"limit": *limit}
+	if *source != "" {
+		payload["source"] = *source
+	}
+	if *regions != "" {
+		payload["regions"] = strings.Split(*regions, ",")
+	}
+	return payload, nil
+}
+
+func namePayload(args []string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errUsage
+	}
+	return map[string]string{"name": args[0]}, nil
+}
+
+func profileClonePayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("clone", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	source := fs.String("source", "", "profile to copy")
+	newIdentity := fs.Bool("new-identity", false, "generate new keys")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return nil, errUsage
+	}
+	return map[string]interface{}{
+		"source":      *source,
+		"name":        fs.Arg(0),
+		"newIdentity": *newIdentity,
+	}, nil
+}
+
+func profileRenamePayload(args []string) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errUsage
+	}
+	return map[string]string{"name": args[0], "newName": args[1]}, nil
+}
+
+// profilePrinter prints the profile list returned by profile events
+func profilePrinter(w io.Writer, data json.RawMessage) error {
+	var list struct {
+		Profiles json.RawMessage `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil || list.Profiles == nil {
+		return printJSON(w, data)
+	}
+	return printTable(w, list.Profiles, profileColumns)
+}
+
+// nodePrinter prints the node list returned by node registry events
+func nodePrinter(w io.Writer, data json.RawMessage) error {
+	var list struct {
+		Nodes json.RawMessage `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil || list.Nodes == nil {
+		return printJSON(w, data)
+	}
+	return printTable(w, list.Nodes, nodeColumns)
+}
+
+// routingPrinter prints the proxy routing settings and their rules
+func routingPrinter(w io.Writer, data json.RawMessage) error {
+	var routing struct {
+		Enabled  bool            `json:"enabled"`
+		Default  string          `json:"default"`
+		Upstream string          `json:"upstream"`
+		Rules    json.RawMessage `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &routing); err != nil || routing.Rules == nil {
+		return printJSON(w, data)
+	}
+
+	if !routing.Enabled {
+		fmt.Fprintln(w, "Routing off, every destination goes over Yggdrasil")
+		return nil
+	}
+	if routing.Default == "" {
+		routing.Default = "direct"
+	}
+	fmt.Fprintf(w, "Default: %s\n", routing.Default)
+	if routing.Upstream != "" {
+		fmt.Fprintf(w, "Upstream: %s\n", routing.Upstream)
+	}
+	return printTable(w, routing.Rules, routingRuleColumns)
+}
+
+// directoryPrinter prints the ranked peers returned by peers:discover
+func directoryPrinter(w io.Writer, data json.RawMessage) error {
+	var result struct {
+		Peers json.RawMessage `json:"peers"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.Peers == nil {
+		return printJSON(w, data)
+	}
+	return printTable(w, result.Peers, directoryColumns)
+}
+
+// configImportPayload reads the config file and passes the remaining
+// arguments as yggstack flags. With -by-path the application reads the
+// file itself, which is needed for a PrivateKeyPath in it.
+func configImportPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	byPath := fs.Bool("by-path", false, "let the application read the file and its PrivateKeyPath")
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 {
+		return nil, errUsage
+	}
+	file, flags := fs.Arg(0), strings.Join(fs.Args()[1:], " ")
+
+	if *byPath {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"path": path,
+			"args": flags,
+		}, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"content": string(data),
+		"args":    flags,
+	}, nil
+}
+
+// runConfigExport prints the exported config or writes it to a file
+func runConfigExport(c *client, args []string, out *output) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "hjson", "hjson or json")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 {
+		return errUsage
+	}
+
+	data, err := c.call(eventConfigExport, map[string]string{"format": *format})
+	if err != nil {
+		return err
+	}
+	if out.json {
+		return printJSON(out.w, data)
+	}
+
+	var result struct {
+		Content string `json:"content"`
+		Args    string `json:"args"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		_, err := io.WriteString(out.w, result.Content)
+		return err
+	}
+
+	// The file is written locally and contains the private key
+	if err := os.WriteFile(fs.Arg(0), []byte(result.Content), 0600); err != nil {
+		return err
+	}
+	fmt.Fprintln(out.w, "Config written to", fs.Arg(0))
+	if result.Args != "" {
+		fmt.Fprintln(out.w, "yggstack flags:", result.Args)
+	}
+	return nil
+}
+
+// runStatsHistory prints traffic totals per series or writes the history
+// as CSV
+func runStatsHistory(c *client, args []string, out *output) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	resolution := fs.String("resolution", "hour", "minute or hour")
+	since := fs.Duration("since", 7*24*time.Hour, "how far back to look")
+	csvFile := fs.String("csv", "", "write the history as CSV to file, - for stdout")
+	if err := fs.Parse(args); err != nil || *since <= 0 {
+		return errUsage
+	}
+
+	payload := map[string]interface{}{
+		"resolution": *resolution,
+		"from":       time.Now().Add(-*since).UnixMilli(),
+	}
+	if fs.NArg() > 0 {
+		payload["series"] = fs.Args()
+	}
+	if *csvFile != "" {
+		payload["format"] = "csv"
+	}
+
+	data, err := c.call(eventStatsHistory, payload)
+	if err != nil {
+		return err
+	}
+	if out.json {
+		return printJSON(out.w, data)
+	}
+
+	var result struct {
+		Series  json.RawMessage `json:"series"`
+		Content string          `json:"content"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	switch *csvFile {
+	case "":
+		if result.Series == nil {
+			result.Series = json.RawMessage("[]")
+		}
+		return printTable(out.w, result.Series, historyColumns)
+	case "-":
+		_, err := io.WriteString(out.w, result.Content)
+		return err
+	default:
+		if err := os.WriteFile(*csvFile, []byte(result.Content), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(out.w, "History written to", *csvFile)
+		return nil
+	}
+}
+
+// readPassphrase returns the identity passphrase from file, from the
+// environment or from the first line of standard input
+func readPassphrase(file string) (string, error) {
+	return readSecret(file, envPassphrase, "passphrase", "-passphrase-file")
+}
+
+// readSecret returns a secret from file, from the environment variable env
+// or from the first line of standard input. A terminal is read without echo.
+func readSecret(file, env, name, fileFlag string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if value := os.Getenv(env); value != "" {
+		return value, nil
+	}
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "%s: ", strings.ToUpper(name[:1])+name[1:])
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(secret), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("%s required: pass %s, set %s or write it to stdin", name, fileFlag, env)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// identityImportPayload reads the bundle file and the passphrase
+func identityImportPayload(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	passphraseFile := fs.String("passphrase-file", "", "file with the passphrase")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return nil, errUsage
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"content":    string(data),
+		"passphrase": passphrase,
+	}, nil
+}
+
+// runIdentityExport writes an identity bundle to a file
+func runIdentityExport(c *client, args []string, out *output) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	peers := fs.Bool("peers", false, "include peers")
+	mappings := fs.Bool("mappings", false, "include port mappings")
+	passphraseFile := fs.String("passphrase-file", "", "file with the passphrase")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := c.call(eventIdentityExport, map[string]interface{}{
+		"passphrase": passphrase,
+		"peers":      *peers,
+		"mappings":   *mappings,
+	})
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Content     string `json:"content"`
+		IPv6Address string `json:"ipv6Address"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(fs.Arg(0), []byte(result.Content), 0600); err != nil {
+		return err
+	}
+	if out.json {
+		return printJSON(out.w, data)
+	}
+	fmt.Fprintln(out.w, "Identity", result.IPv6Address, "written to", fs.Arg(0))
+	return nil
+}
+
+// logFlags parses flags shared by logs list and logs tail
+func logFlags(args []string) (limit int, interval time.Duration, err error) {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&limit, "n", 50, "number of entries")
+	fs.DurationVar(&interval, "interval", time.Second, "poll interval")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return 0, 0, errUsage
+	}
+	return limit, interval, nil
+}
+
+func logListPayload(args []string) (interface{}, error) {
+	limit, _, err := logFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"limit": limit}, nil
+}
+
+// logListData is the response data of log:list
+type logListData struct {
+	Logs  json.RawMessage `json:"logs"`
+	Count int             `json:"count"`
+}
+
+func logPrinter(w io.Writer, data json.RawMessage) error {
+	var list logListData
+	if err := json.Unmarshal(data, &list); err != nil {
+		return printJSON(w, data)
+	}
+	return printTable(w, list.Logs, logColumns)
+}
+
+// logCursor remembers the last log entry shown by logs tail. Several
+// entries can share a millisecond, so it also counts the entries shown
+// with the last timestamp.
+type logCursor struct {
+	timestamp int64
+	shown     int
+}
+
+// since returns the since value of the next log:list request. It is one
+// before the last timestamp, so later entries of the same millisecond are
+// returned as well.
+func (c *logCursor) since() int64 {
+	if c.timestamp == 0 {
+		return 0
+	}
+	return c.timestamp - 1
+}
+
+// filter returns the entries that were not shown yet and moves the cursor
+// past them
+func (c *logCursor) filter(entries []map[string]interface{}) []map[string]interface{} {
+	var fresh []map[string]interface{}
+	seen := 0
+	for _, entry := range entries {
+		ts, _ := entry["timestamp"].(float64)
+		switch timestamp := int64(ts); {
+		case timestamp < c.timestamp:
+			continue
+		case timestamp == c.timestamp:
+			seen++
+			if seen <= c.shown {
+				continue
+			}
+			c.shown++
+		default:
+			c.timestamp, c.shown, seen = timestamp, 1, 1
+		}
+		fresh = append(fresh, entry)
+	}
+	return fresh
+}
+
+// runLogTail polls log:list and prints new entries until interrupted
+func runLogTail(c *client, args []string, out *output) error {
+	limit, interval, err := logFlags(args)
+	if err != nil {
+		return err
+	}
+
+	var cursor logCursor
+	payload := map[string]interface{}{"limit": limit}
+
+	for {
+		data, err := c.call(eventLogList, payload)
+		if err != nil {
+			return err
+		}
+
+		var list struct {
+			Logs []map[string]interface{} `json:"logs"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+
+		for _, entry := range cursor.filter(list.Logs) {
+			if out.json {
+				line, _ := json.Marshal(entry)
+				fmt.Fprintln(out.w, string(line))
+				continue
+			}
+			fmt.Fprintf(out.w, "%s %-5s %s\n",
+				formatTimestamp(entry["timestamp"]),
+				formatValue(entry["level"]),
+				formatValue(entry["message"]))
+		}
+
+		payload = map[string]interface{}{"since": cursor.since(), "limit": 1000}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,225 @@
+// Command yggstackctl controls a running yggstack-gui instance
+// through its local control API.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/platform"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/version"
+)
+
+// Environment variables that override the defaults
+const (
+	envAddress       = "YGGSTACK_CONTROL_ADDR"
+	envToken         = "YGGSTACK_CONTROL_TOKEN"
+	envPassphrase    = "YGGSTACK_IDENTITY_PASSPHRASE"
+	envProxyPassword = "YGGSTACK_PROXY_PASSWORD"
+)
+
+// output holds output settings
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("yggstackctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { printUsage(stderr, fs) }
+
+	address := fs.String("addr", "", "control API address (default from data/config.json)")
+	token := fs.String("token", "", "control API token (default from secure storage)")
+	node := fs.String("node", "", "target node, the profile it runs (default the primary node)")
+	jsonOutput := fs.Bool("json", false, "print raw JSON output")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	showVersion := fs.Bool("version", false, "print version and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *showVersion {
+		fmt.Fprintln(stdout, "yggstackctl", version.Version)
+		return 0
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	out := &output{w: stdout, json: *jsonOutput}
+
+	addr := resolveAddress(*address)
+	tok, err := resolveToken(*token)
+	if err != nil {
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+
+	c := newClient(addr, tok, *node, *timeout)
+
+	if err := dispatch(c, rest, out); err != nil {
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+// dispatch runs the command given by args
+func dispatch(c *client, args []string, out *output) error {
+	switch args[0] {
+	case "events":
+		// List events registered in the running instance
+		data, err := c.call("events", nil)
+		if err != nil {
+			return err
+		}
+		if out.json {
+			return printJSON(out.w, data)
+		}
+		var events []string
+		if err := json.Unmarshal(data, &events); err != nil {
+			return err
+		}
+		for _, e := range events {
+			fmt.Fprintln(out.w, e)
+		}
+		return nil
+
+	case "call":
+		// Send any event with a raw JSON payload
+		if len(args) < 2 || len(args) > 3 {
+			return errUsage
+		}
+		var payload interface{}
+		if len(args) == 3 {
+			payload = json.RawMessage(args[2])
+		}
+		data, err := c.call(args[1], payload)
+		if err != nil {
+			return err
+		}
+		return printJSON(out.w, data)
+	}
+
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	cmd := findCommand(args[0], args[1])
+	if cmd == nil {
+		return errUsage
+	}
+
+	cmdArgs := args[2:]
+	if cmd.run != nil {
+		return cmd.run(c, cmdArgs, out)
+	}
+
+	var payload interface{}
+	if cmd.payload != nil {
+		var err error
+		if payload, err = cmd.payload(cmdArgs); err != nil {
+			return err
+		}
+	} else if len(cmdArgs) > 0 {
+		return errUsage
+	}
+
+	data, err := c.call(cmd.event, payload)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return printJSON(out.w, data)
+	}
+	if cmd.print != nil {
+		return cmd.print(out.w, data)
+	}
+	return printObject(out.w, data)
+}
+
+// resolveAddress returns the control API address from flag, environment or settings
+func resolveAddress(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(envAddress); env != "" {
+		return env
+	}
+
+	store := config.NewStore()
+	if err := store.Load(); err == nil {
+		if addr := store.Get().Control.ListenAddress; addr != "" {
+			return addr
+		}
+	}
+
+	return config.DefaultControlAddress
+}
+
+// resolveToken returns the control API token from flag, environment or secure storage
+func resolveToken(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if env := os.Getenv(envToken); env != "" {
+		return env, nil
+	}
+
+	store, err := security.NewSecureStore(platform.GetSecureStorePath(), platform.GetSecureStoreKeyPath())
+	if err != nil {
+		return "", fmt.Errorf("failed to open secure storage: %w", err)
+	}
+	defer store.Close()
+
+	data, err := store.Retrieve(security.ControlTokenAccount)
+	if err != nil {
+		return "", fmt.Errorf("control API token not found, use -token or %s", envToken)
+	}
+	token := string(data)
+	security.ZeroBytes(data)
+
+	return token, nil
+}
+
+// printUsage prints the command list
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: yggstackctl [flags] <group> <command> [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s %s %s\t%s\t[%s]\n", cmd.group, cmd.name, cmd.args, cmd.description, cmd.event)
+	}
+	fmt.Fprintf(tw, "  events\tList events of the running instance\t\n")
+	fmt.Fprintf(tw, "  call <event> [json]\tSend any event with a JSON payload\t\n")
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs.PrintDefaults()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// column describes one column of table output
+type column struct {
+	header string
+	key    string
+	format func(v interface{}) string
+}
+
+// printJSON prints raw response data as indented JSON
+func printJSON(w io.Writer, data json.RawMessage) error {
+	if len(data) == 0 {
+		data = json.RawMessage("null")
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// printObject prints a JSON object as sorted "key  value" lines.
+// Nested objects are flattened with dotted keys.
+func printObject(w io.Writer, data json.RawMessage) error {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		// Not an object, fall back to JSON
+		return printJSON(w, data)
+	}
+
+	flat := make(map[string]interface{})
+	flatten("", obj, flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", k, formatValue(flat[k]))
+	}
+	return tw.Flush()
+}
+
+// printTable prints a JSON array of objects as a table
+func printTable(w io.Writer, data json.RawMessage, columns []column) error {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return printJSON(w, data)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			format := formatValue
+			if c.format != nil {
+				format = c.format
+			}
+			cells[i] = format(row[c.key])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// flatten converts nested objects into dotted keys
+func flatten(prefix string, obj map[string]interface{}, out map[string]interface{}) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// formatValue formats a decoded JSON value for table output
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if val == "" {
+			return "-"
+		}
+		return val
+	case bool:
+		if val {
+			return "yes"
+		}
+		return "no"
+	case float64:
+		if val == float64(int64(val)) {
+			return fmt.Sprintf("%d", int64(val))
+		}
+		return fmt.Sprintf("%.2f", val)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// formatBytes formats a byte count as a human readable size
+func formatBytes(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok {
+		return formatValue(v)
+	}
+
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", int64(n))
+	}
+	div, exp := float64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", n/div, "KMGTPE"[exp])
+}
+
+// formatMillis formats a latency in milliseconds
+func formatMillis(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok || n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f ms", n)
+}
+
+// formatPercent formats a ratio between 0 and 1 as a percentage
+func formatPercent(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok {
+		return formatValue(v)
+	}
+	return fmt.Sprintf("%.0f%%", n*100)
+}
+
+// formatTimestamp formats a Unix timestamp in milliseconds
+func formatTimestamp(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok {
+		return formatValue(v)
+	}
+	return time.UnixMilli(int64(n)).Format("15:04:05.000")
+}
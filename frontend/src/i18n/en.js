@@ -89,6 +89,9 @@ export default {
     nameserver: 'DNS Server',
     nameserverDesc: 'Custom DNS server for resolving hostnames (optional)',
     nameserverPlaceholder: 'e.g., 302:db60::53',
+    httpListenAddress: 'HTTP Proxy Address',
+    httpListenAddressDesc: 'HTTP proxy with CONNECT support for tools without SOCKS5 (optional)',
+    httpListenPlaceholder: '127.0.0.1:8080',
     status: 'Proxy Status',
     running: 'Running',
     stopped: 'Stopped',
@@ -188,6 +191,20 @@ export default {
       listen: 'Listen address',
       nameserver: 'Nameserver (optional)'
     },
+    profiles: {
+      title: 'Profiles',
+      active: 'Active',
+      noIdentity: 'No keys yet, generated on first start',
+      switch: 'Switch',
+      rename: 'Rename',
+      create: 'Create',
+      clone: 'Clone current',
+      namePlaceholder: 'New profile name',
+      renamePrompt: 'New profile name',
+      deleteConfirm: 'Delete profile "{name}" and its keys?',
+      description: 'Each profile has its own keys, peers, proxy and mappings. Switching restarts a running node.',
+      failed: 'Profile operation failed'
+    },
     about: 'About',
     version: 'Version',
     framework: 'Framework',
@@ -89,6 +89,9 @@ export default {
     nameserver: 'DNS сервер',
     nameserverDesc: 'Пользовательский DNS-сервер для разрешения имён (опционально)',
     nameserverPlaceholder: 'например, 302:db60::53',
+    httpListenAddress: 'Адрес HTTP-прокси',
+    httpListenAddressDesc: 'HTTP-прокси с поддержкой CONNECT для программ без SOCKS5 (опционально)',
+    httpListenPlaceholder: '127.0.0.1:8080',
     status: 'Статус прокси',
     running: 'Запущен',
     stopped: 'Остановлен',
@@ -188,6 +191,20 @@ export default {
       listen: 'Адрес прослушивания',
       nameserver: 'DNS сервер (опционально)'
     },
+    profiles: {
+      title: 'Профили',
+      active: 'Активный',
+      noIdentity: 'Ключей пока нет, они создаются при первом запуске',
+      switch: 'Переключить',
+      rename: 'Переименовать',
+      create: 'Создать',
+      clone: 'Копировать текущий',
+      namePlaceholder: 'Имя нового профиля',
+      renamePrompt: 'Новое имя профиля',
+      deleteConfirm: 'Удалить профиль "{name}" вместе с ключами?',
+      description: 'У каждого профиля свои ключи, пиры, прокси и перенаправления. Переключение перезапускает работающий узел.',
+      failed: 'Не удалось выполнить операцию с профилем'
+    },
     about: 'О программе',
     version: 'Версия',
     framework: 'Фреймворк',
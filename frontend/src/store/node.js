@@ -9,6 +9,8 @@ export const useNodeStore = defineStore('node', {
       uptime: 0,
       rxBytes: 0,
       txBytes: 0,
+      rxRate: 0,
+      txRate: 0,
       peerCount: 0,
       sessionCount: 0
     },
@@ -37,6 +39,7 @@ export const useNodeStore = defineStore('node', {
 
       // Subscribe to stats updates
       ipc.on(Events.STATS_UPDATE, (data) => {
+        if (data.nodeId) return
         if (data.peerCount !== undefined) {
           this.stats.peerCount = data.peerCount
         }
@@ -46,6 +49,12 @@ export const useNodeStore = defineStore('node', {
         if (data.sessionCount !== undefined) {
           this.stats.sessionCount = data.sessionCount
         }
+        if (data.totalRxBytes !== undefined) {
+          this.stats.rxBytes = data.totalRxBytes
+          this.stats.txBytes = data.totalTxBytes
+          this.stats.rxRate = data.rxBytesPerSec
+          this.stats.txRate = data.txBytesPerSec
+        }
       })
 
       // Subscribe to errors
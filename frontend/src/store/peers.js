@@ -25,8 +25,9 @@ export const usePeersStore = defineStore('peers', {
   actions: {
     // Initialize store and subscribe to events
     init() {
-      // Subscribe to peer updates
+      // Subscribe to peer updates, additional nodes carry a nodeId
       ipc.on(Events.PEERS_UPDATE, (data) => {
+        if (data.nodeId) return
         if (Array.isArray(data)) {
           this.peers = data
         } else if (data.peers) {
@@ -37,7 +38,7 @@ export const usePeersStore = defineStore('peers', {
 
       // Subscribe to peer connected events
       ipc.on(Events.PEER_CONNECTED, (data) => {
-        if (data.peer) {
+        if (data.peer && !data.nodeId) {
           const index = this.peers.findIndex(p => p.uri === data.peer.uri)
           if (index !== -1) {
             this.peers[index] = { ...this.peers[index], ...data.peer, connected: true }
@@ -49,7 +50,7 @@ export const usePeersStore = defineStore('peers', {
 
       // Subscribe to peer disconnected events
       ipc.on(Events.PEER_DISCONNECTED, (data) => {
-        if (data.peer) {
+        if (data.peer && !data.nodeId) {
           const index = this.peers.findIndex(p => p.uri === data.peer.uri)
           if (index !== -1) {
             this.peers[index].connected = false
@@ -99,6 +99,7 @@ export interface ProxyConfig {
   enabled: boolean
   listenAddress: string
   nameserver?: string
+  httpListenAddress?: string
 }
 
 // Proxy status
@@ -109,6 +110,23 @@ export interface ProxyStatus {
   totalConnections: number
   bytesIn: number
   bytesOut: number
+  httpListenAddress?: string
+  udpAssociations: number
+}
+
+// Proxy split routing
+export type RouteAction = 'yggdrasil' | 'direct' | 'reject' | 'upstream'
+
+export interface ProxyRoutingRule {
+  match: string
+  action: RouteAction
+}
+
+export interface ProxyRouting {
+  enabled: boolean
+  default?: Exclude<RouteAction, 'yggdrasil'>
+  upstream?: string
+  rules: ProxyRoutingRule[]
 }
 
 // Port mapping types
@@ -25,6 +25,9 @@ export const Events = {
   PEERS_UPDATE: 'peers:update',
   PEER_CONNECTED: 'peer:connected',
   PEER_DISCONNECTED: 'peer:disconnected',
+  PEERS_HEALTH: 'peers:health',
+  PEERS_FAILOVER: 'peers:failover',
+  PEERS_DISCOVER: 'peers:discover',
 
   // Session events
   SESSIONS_LIST: 'sessions:list',
@@ -33,6 +36,19 @@ export const Events = {
   // Config events
   CONFIG_LOAD: 'config:load',
   CONFIG_SAVE: 'config:save',
+  CONFIG_IMPORT: 'config:import',
+  CONFIG_EXPORT: 'config:export',
+
+  // Identity events
+  IDENTITY_EXPORT: 'identity:export',
+  IDENTITY_IMPORT: 'identity:import',
+
+  // Key generation events
+  KEYGEN_START: 'keygen:start',
+  KEYGEN_STOP: 'keygen:stop',
+  KEYGEN_STATUS: 'keygen:status',
+  KEYGEN_ADOPT: 'keygen:adopt',
+  KEYGEN_PROGRESS: 'keygen:progress',
 
   // Settings events
   SETTINGS_GET: 'settings:get',
@@ -43,11 +59,30 @@ export const Events = {
   PROXY_STATUS: 'proxy:status',
   PROXY_START: 'proxy:start',
   PROXY_STOP: 'proxy:stop',
+  PROXY_CLIENTS: 'proxy:clients',
+  PROXY_ROUTING: 'proxy:routing',
 
   // Mapping events
   MAPPING_LIST: 'mapping:list',
   MAPPING_ADD: 'mapping:add',
   MAPPING_REMOVE: 'mapping:remove',
+  MAPPING_ENABLE: 'mapping:enable',
+  MAPPING_DISABLE: 'mapping:disable',
+
+  // Profile events
+  PROFILE_LIST: 'profile:list',
+  PROFILE_CREATE: 'profile:create',
+  PROFILE_CLONE: 'profile:clone',
+  PROFILE_RENAME: 'profile:rename',
+  PROFILE_DELETE: 'profile:delete',
+  PROFILE_SWITCH: 'profile:switch',
+  PROFILE_CHANGED: 'profile:changed',
+
+  // Node registry
+  NODES_LIST: 'nodes:list',
+  NODES_ADD: 'nodes:add',
+  NODES_REMOVE: 'nodes:remove',
+  NODES_CHANGED: 'nodes:changed',
 
   // State sync events
   STATE_CHANGED: 'state:changed',
@@ -55,6 +90,7 @@ export const Events = {
 
   // Stats events
   STATS_UPDATE: 'stats:update',
+  STATS_HISTORY: 'stats:history',
 
   // Log events
   LOG_ENTRY: 'log:entry',
@@ -238,7 +274,7 @@ class IPCBridge {
    * Emit an event to the backend
    * @param {string} event - Event name
    * @param {object} payload - Event payload
-   * @param {object} options - Options (timeout, etc.)
+   * @param {object} options - Options (timeout, nodeId of a non-primary node)
    * @returns {Promise<object>} Response from backend
    */
   async emit(event, payload = {}, options = {}) {
@@ -274,6 +310,7 @@ class IPCBridge {
       // Prepare message for backend
       const message = JSON.stringify({
         requestId,
+        nodeId: options.nodeId || undefined,
         payload,
         timestamp: Date.now()
       })
@@ -407,6 +444,15 @@ class IPCBridge {
       [Events.PROXY_STATUS]: {
         success: true,
         data: { enabled: false, listenAddress: '127.0.0.1:1080' }
+      },
+      [Events.MAPPING_LIST]: { success: true, data: [] },
+      [Events.PROFILE_LIST]: {
+        success: true,
+        data: { active: 'default', profiles: [{ name: 'default', active: true }] }
+      },
+      [Events.NODES_LIST]: {
+        success: true,
+        data: { nodes: [{ id: 'default', primary: true, state: 'stopped' }] }
       }
     }
 
@@ -7,11 +7,15 @@ require (
 	github.com/energye/energy/v2 v2.5.6
 	github.com/energye/golcl v1.1.2
 	github.com/gologme/log v1.3.0
+	github.com/hjson/hjson-go/v4 v4.5.0
 	github.com/rickb777/date v1.20.6
 	github.com/things-go/go-socks5 v0.0.5
 	github.com/yggdrasil-network/yggdrasil-go v0.5.13-0.20251124092915-ae405adf7c4c
 	go.uber.org/zap v1.27.1
 	golang.org/x/crypto v0.43.0
+	golang.org/x/net v0.46.0
+	golang.org/x/term v0.38.0
+	golang.org/x/time v0.12.0
 	gvisor.dev/gvisor v0.0.0-20240810013311-326fe0f2a77f
 )
 
@@ -24,14 +28,11 @@ require (
 	github.com/go-ole/go-ole v1.2.4 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/google/btree v1.1.2 // indirect
-	github.com/hjson/hjson-go/v4 v4.5.0 // indirect
 	github.com/quic-go/quic-go v0.57.1 // indirect
 	github.com/rickb777/plural v1.4.1 // indirect
 	github.com/tevino/abool v0.0.0-20220530134649-2bfc934cb23c // indirect
 	github.com/wlynxg/anet v0.0.5 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
-	golang.org/x/net v0.46.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
-	golang.org/x/time v0.12.0 // indirect
 )
@@ -34,6 +34,8 @@ type Application struct {
 	browserWindow cef.IBrowserWindow
 	ipcBridge     *ipc.Bridge
 	ipcHandlers   *ipc.Handlers
+	controlServer *ipc.ControlServer
+	metricsServer *ipc.MetricsServer
 	yggService    *yggdrasil.Service
 	logger        *logger.Logger
 	zapLogger     *zap.Logger
@@ -108,10 +110,22 @@ func (a *Application) initialize() {
 		})
 	}
 
+	// Initialize secure storage (keychain or encrypted file)
+	a.secureStore = openSecureStore(a.logger)
+
 	// Initialize IPC bridge and handlers with Yggdrasil integration
 	a.ipcBridge = ipc.NewBridge(a.logger)
 	a.ipcHandlers = ipc.NewHandlers(a.logger)
 	a.ipcHandlers.SetConfigStore(a.configStore) // Connect config store to handlers
+	a.ipcHandlers.SetAuditLogger(a.auditLogger) // Peer connections go to the audit log
+	a.ipcHandlers.SetKeyStore(a.secureStore)    // Keep private keys out of yggdrasil.conf
+	a.ipcHandlers.LoadProfile()                 // Use the identity of the active profile
+	a.ipcHandlers.MigrateLegacyConfig()         // Move proxy/mappings out of yggdrasil.conf
+	a.ipcHandlers.LoadMappings()                // Saved mappings start with the node
+	a.ipcHandlers.LoadNodes()                   // Profiles hosted as additional nodes
+	a.ipcHandlers.ApplyFailover()               // Peer health monitoring and failover
+	a.ipcHandlers.ApplyStats()                  // Live peer and traffic events
+	a.ipcHandlers.SetTrafficHistory(openTrafficHistory(a.logger))
 	a.yggService = a.ipcHandlers.GetService()
 
 	// Setup IPC log emitter to send logs to frontend
@@ -166,6 +180,13 @@ func (a *Application) SetWindow(window cef.IBrowserWindow) {
 	// Register IPC handlers with Yggdrasil integration
 	a.ipcHandlers.RegisterAll(a.ipcBridge)
 
+	// Expose the same handlers over the local control API
+	a.controlServer = newControlServer(a.ipcBridge, a.ipcHandlers, a.configStore.Get(),
+		a.secureStore, a.logger, a.auditLogger)
+
+	// Prometheus metrics, if enabled in settings
+	a.metricsServer = newMetricsServer(a.ipcBridge, a.ipcHandlers, a.configStore.Get(), a.logger)
+
 	a.logger.Info("Browser window set")
 }
 
@@ -212,12 +233,8 @@ func (a *Application) autoStartNode() {
 
 	// Auto-start SOCKS proxy with settings from config store
 	if a.configStore != nil && a.ipcHandlers != nil {
-		appSettings := a.configStore.Get()
-		socksConfig := yggdrasil.SOCKSConfig{
-			Enabled:       true,
-			ListenAddress: appSettings.Proxy.ListenAddress,
-			Nameserver:    appSettings.Proxy.Nameserver,
-		}
+		socksConfig := a.ipcHandlers.SOCKSConfig(a.configStore.ActiveProfile())
+		socksConfig.Enabled = true
 		// Get SOCKS proxy from handlers
 		if socksProxy := a.ipcHandlers.GetSOCKSProxy(); socksProxy != nil {
 			if err := socksProxy.Start(socksConfig); err != nil {
@@ -227,6 +244,11 @@ func (a *Application) autoStartNode() {
 			}
 		}
 	}
+
+	// Additional nodes start together with the primary one
+	if a.ipcHandlers != nil {
+		a.ipcHandlers.StartAdditionalNodes()
+	}
 }
 
 // BrowserWindow returns the browser window
@@ -252,7 +274,27 @@ func (a *Application) performShutdown() {
 		a.auditLogger.LogSuccess(logger.AuditEventAppStop, "Application stopping", nil)
 	}
 
-	// Stop Yggdrasil service if running
+	// Stop control API
+	if a.controlServer != nil {
+		if err := a.controlServer.Stop(); err != nil {
+			a.logger.Warn("Error stopping control API", "error", err)
+		}
+	}
+
+	// Stop metrics server
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Stop(); err != nil {
+			a.logger.Warn("Error stopping metrics server", "error", err)
+		}
+	}
+
+	// Stop key search, traffic history, additional nodes and the Yggdrasil
+	// service if running
+	if a.ipcHandlers != nil {
+		a.ipcHandlers.StopKeySearch()
+		a.ipcHandlers.StopTrafficHistory()
+		a.ipcHandlers.StopAdditionalNodes()
+	}
 	if a.yggService != nil && a.yggService.IsRunning() {
 		a.logger.Info("Stopping Yggdrasil service")
 		if err := a.yggService.Stop(); err != nil {
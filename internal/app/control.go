@@ -0,0 +1,65 @@
+package app
+
+import (
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/ipc"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/platform"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
+)
+
+// openSecureStore opens the platform keychain with encrypted file fallback
+func openSecureStore(log *logger.Logger) *security.SecureStore {
+	store, err := security.NewSecureStore(platform.GetSecureStorePath(), platform.GetSecureStoreKeyPath())
+	if err != nil {
+		log.Warn("Failed to open secure storage", "error", err)
+		return nil
+	}
+	return store
+}
+
+// newMetricsServer creates the Prometheus metrics server and starts it if
+// it is enabled in settings
+func newMetricsServer(bridge *ipc.Bridge, handlers *ipc.Handlers, settings config.Settings, log *logger.Logger) *ipc.MetricsServer {
+	server := ipc.NewMetricsServer(handlers, bridge, log)
+	handlers.SetMetricsServer(server)
+
+	if err := server.Apply(settings.Metrics); err != nil {
+		log.Warn("Failed to start metrics server", "error", err)
+	}
+
+	return server
+}
+
+// openTrafficHistory loads the traffic history. A history that cannot be
+// read starts over.
+func openTrafficHistory(log *logger.Logger) *yggdrasil.TrafficHistory {
+	history := yggdrasil.NewTrafficHistory(platform.GetTrafficHistoryPath(), log)
+	if err := history.Load(); err != nil {
+		log.Warn("Failed to load traffic history", "error", err)
+	}
+	return history
+}
+
+// newControlServer creates the local control API server for the bridge
+// and starts it if it is enabled in settings
+func newControlServer(bridge *ipc.Bridge, handlers *ipc.Handlers, settings config.Settings,
+	secureStore *security.SecureStore, log *logger.Logger, auditLogger *logger.AuditLogger) *ipc.ControlServer {
+
+	server := ipc.NewControlServer(bridge, log, auditLogger)
+	handlers.SetControlServer(server)
+
+	token, err := ipc.LoadControlToken(secureStore)
+	if err != nil {
+		log.Warn("Control API token unavailable", "error", err)
+		return server
+	}
+	server.SetToken(token)
+
+	if err := server.Apply(settings.Control); err != nil {
+		log.Warn("Failed to start control API", "error", err)
+	}
+
+	return server
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/ipc"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
+)
+
+// HeadlessConfig holds configuration for headless (daemon) mode
+type HeadlessConfig struct {
+	Version string
+	Logger  *logger.Logger
+}
+
+// Headless runs the Yggdrasil node, SOCKS proxy and port mappings
+// without CEF, the browser window or the system tray.
+// It uses the same config files as the desktop application.
+type Headless struct {
+	config    HeadlessConfig
+	logger    *logger.Logger
+	zapLogger *zap.Logger
+
+	lifecycleManager *LifecycleManager
+	configStore      *config.Store
+	auditLogger      *logger.AuditLogger
+	secureStore      *security.SecureStore
+
+	ipcBridge      *ipc.Bridge
+	ipcHandlers    *ipc.Handlers
+	controlServer  *ipc.ControlServer
+	metricsServer  *ipc.MetricsServer
+	yggService     *yggdrasil.Service
+	socksProxy     *yggdrasil.SOCKSProxy
+	mappingManager *yggdrasil.MappingManager
+}
+
+// NewHeadless creates a new headless runner
+func NewHeadless(cfg HeadlessConfig) *Headless {
+	h := &Headless{
+		config:    cfg,
+		logger:    cfg.Logger,
+		zapLogger: cfg.Logger.Zap(),
+	}
+
+	h.initialize()
+	return h
+}
+
+// initialize sets up services from the saved configuration
+func (h *Headless) initialize() {
+	h.logger.Info("Initializing headless mode")
+
+	// Lifecycle manager installs SIGINT/SIGTERM handlers
+	h.lifecycleManager = NewLifecycleManager(h.zapLogger)
+	// There is no window, so the app is always in background
+	h.lifecycleManager.SetStartMinimized(true)
+	h.lifecycleManager.SetOnShutdown(h.performShutdown)
+
+	// Load application settings (config.json)
+	h.configStore = config.NewStore()
+	if err := h.configStore.Load(); err != nil {
+		h.logger.Warn("Failed to load config, using defaults", "error", err)
+	}
+
+	// Initialize audit logger for security events
+	auditCfg := logger.DefaultAuditConfig()
+	auditLogger, err := logger.NewAuditLogger(auditCfg)
+	if err != nil {
+		h.logger.Warn("Failed to initialize audit logger", "error", err)
+	} else {
+		h.auditLogger = auditLogger
+	}
+
+	if h.auditLogger != nil {
+		h.auditLogger.LogSuccess(logger.AuditEventAppStart, "Application started (headless)", map[string]interface{}{
+			"version": h.config.Version,
+		})
+	}
+
+	// Initialize secure storage (keychain or encrypted file)
+	h.secureStore = openSecureStore(h.logger)
+
+	// Handlers own the service, SOCKS proxy and mapping manager. Private
+	// keys are kept in secure storage rather than in yggdrasil.conf.
+	h.ipcHandlers = ipc.NewHandlers(h.logger)
+	h.ipcHandlers.SetConfigStore(h.configStore)
+	h.ipcHandlers.SetAuditLogger(h.auditLogger)
+	h.ipcHandlers.SetKeyStore(h.secureStore)
+	h.yggService = h.ipcHandlers.GetService()
+	h.socksProxy = h.ipcHandlers.GetSOCKSProxy()
+	h.mappingManager = h.ipcHandlers.GetMappingManager()
+
+	// The active profile selects the node identity. Proxy and mappings from
+	// older yggdrasil.conf files move to config.json. Saved mappings start
+	// automatically once the node is running. Other profiles can run as
+	// additional nodes next to it, and every node gets peer failover and
+	// live peer and traffic events.
+	h.ipcHandlers.LoadProfile()
+	h.ipcHandlers.MigrateLegacyConfig()
+	h.ipcHandlers.LoadMappings()
+	h.ipcHandlers.LoadNodes()
+	h.ipcHandlers.ApplyFailover()
+	h.ipcHandlers.ApplyStats()
+	h.ipcHandlers.SetTrafficHistory(openTrafficHistory(h.logger))
+
+	// Bridge has no window in headless mode, it only serves the control API
+	h.ipcBridge = ipc.NewBridge(h.logger)
+	h.ipcBridge.Use(ipc.NewSecurityMiddleware(ipc.DefaultSecurityConfig(), h.logger, h.auditLogger).Middleware())
+	h.ipcBridge.Use(ipc.ValidationMiddleware(security.NewValidator(), h.logger))
+	h.ipcBridge.RegisterHandlers()
+	h.ipcHandlers.RegisterAll(h.ipcBridge)
+}
+
+// Run starts the node and blocks until a shutdown signal is received
+func (h *Headless) Run() error {
+	// Load node configuration (yggdrasil.conf). On failure the services
+	// started in initialize still go through the normal shutdown.
+	if err := h.yggService.ConfigManager().Load(); err != nil {
+		h.lifecycleManager.RequestShutdown()
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := h.yggService.Start(nil); err != nil {
+		h.lifecycleManager.RequestShutdown()
+		return fmt.Errorf("failed to start Yggdrasil node: %w", err)
+	}
+
+	if info := h.yggService.GetNodeInfo(); info != nil {
+		h.logger.Info("Yggdrasil node started", "address", info.IPv6Address, "subnet", info.Subnet)
+	}
+
+	// Start SOCKS proxy with settings from config store (same as the GUI)
+	appSettings := h.configStore.Get()
+	socksConfig := h.ipcHandlers.SOCKSConfig(h.configStore.ActiveProfile())
+	socksConfig.Enabled = true
+	if err := h.socksProxy.Start(socksConfig); err != nil {
+		h.logger.Warn("Failed to start SOCKS proxy", "error", err)
+	} else {
+		h.logger.Info("SOCKS proxy started", "address", socksConfig.ListenAddress)
+	}
+
+	h.ipcHandlers.StartAdditionalNodes()
+
+	// Start control API if enabled in settings
+	h.controlServer = newControlServer(h.ipcBridge, h.ipcHandlers, appSettings,
+		h.secureStore, h.logger, h.auditLogger)
+
+	// Start Prometheus metrics if enabled in settings
+	h.metricsServer = newMetricsServer(h.ipcBridge, h.ipcHandlers, appSettings, h.logger)
+
+	h.lifecycleManager.Ready()
+	h.logger.Info("Headless mode ready, press Ctrl+C to stop")
+
+	<-h.lifecycleManager.Done()
+	return nil
+}
+
+// performShutdown is called by lifecycle manager during shutdown
+func (h *Headless) performShutdown() {
+	if h.auditLogger != nil {
+		h.auditLogger.LogSuccess(logger.AuditEventAppStop, "Application stopping (headless)", nil)
+	}
+
+	if h.controlServer != nil {
+		if err := h.controlServer.Stop(); err != nil {
+			h.logger.Warn("Error stopping control API", "error", err)
+		}
+	}
+
+	if h.metricsServer != nil {
+		if err := h.metricsServer.Stop(); err != nil {
+			h.logger.Warn("Error stopping metrics server", "error", err)
+		}
+	}
+
+	h.ipcHandlers.StopKeySearch()
+	h.ipcHandlers.StopTrafficHistory()
+	h.ipcHandlers.StopAdditionalNodes()
+	h.mappingManager.StopAll()
+
+	if h.socksProxy.IsRunning() {
+		if err := h.socksProxy.Stop(); err != nil {
+			h.logger.Warn("Error stopping SOCKS proxy", "error", err)
+		}
+	}
+
+	if h.yggService.IsRunning() {
+		h.logger.Info("Stopping Yggdrasil service")
+		if err := h.yggService.Stop(); err != nil {
+			h.logger.Warn("Error stopping Yggdrasil service", "error", err)
+		}
+	}
+
+	if h.secureStore != nil {
+		if err := h.secureStore.Close(); err != nil {
+			h.logger.Warn("Error closing secure store", "error", err)
+		}
+	}
+
+	if h.auditLogger != nil {
+		h.auditLogger.Flush()
+		if err := h.auditLogger.Close(); err != nil {
+			h.logger.Warn("Error closing audit logger", "error", err)
+		}
+	}
+}
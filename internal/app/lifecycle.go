@@ -64,6 +64,10 @@ type LifecycleManager struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// Closed once shutdown has completed
+	done     chan struct{}
+	doneOnce sync.Once
+
 	// Shutdown timeout
 	shutdownTimeout time.Duration
 }
@@ -80,6 +84,7 @@ func NewLifecycleManager(logger *zap.Logger) *LifecycleManager {
 		shutdownTimeout: 10 * time.Second,
 		ctx:             ctx,
 		cancel:          cancel,
+		done:            make(chan struct{}),
 	}
 
 	// Setup signal handlers
@@ -244,6 +249,12 @@ func (lm *LifecycleManager) RequestShutdown() {
 	}
 
 	lm.SetState(StateTerminated)
+	lm.doneOnce.Do(func() { close(lm.done) })
+}
+
+// Done returns a channel that is closed once shutdown has completed
+func (lm *LifecycleManager) Done() <-chan struct{} {
+	return lm.done
 }
 
 // Context returns the lifecycle context (cancelled on shutdown)
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"fmt"
 	"runtime"
 	"sync"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/energye/energy/v2/pkgs/systray"
 	"go.uber.org/zap"
 
+	"github.com/JB-SelfCompany/yggstack-gui/internal/ipc"
 	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
 )
 
@@ -38,9 +40,13 @@ type TrayManager struct {
 	// Menu items
 	mShow      *systray.MenuItem
 	mStartStop *systray.MenuItem
+	mProfiles  *systray.MenuItem
 	mSeparator *systray.MenuItem
 	mQuit      *systray.MenuItem
 
+	// Profiles submenu items, rebuilt when profiles change
+	profileItems []*systray.MenuItem
+
 	// State
 	isRunning     bool
 	currentIcon   TrayIcon
@@ -144,6 +150,7 @@ func (t *TrayManager) onReady() {
 	t.mShow = systray.AddMenuItem("Show Window", "Show the main window")
 	systray.AddSeparator()
 	t.mStartStop = systray.AddMenuItem("Start Node", "Start/Stop Yggdrasil node")
+	t.mProfiles = systray.AddMenuItem("Profiles", "Switch node profile")
 	systray.AddSeparator()
 	t.mQuit = systray.AddMenuItem("Exit", "Exit application")
 
@@ -161,6 +168,16 @@ func (t *TrayManager) onReady() {
 		t.syncInitialState()
 	}
 
+	// Rebuild the profiles submenu whenever profiles change
+	t.refreshProfiles()
+	if t.app != nil && t.app.ipcBridge != nil {
+		t.app.ipcBridge.Subscribe(ipc.EventProfileChanged, func(event string, data interface{}) {
+			cef.QueueAsyncCall(func(id int) {
+				t.refreshProfiles()
+			})
+		})
+	}
+
 	t.mu.Lock()
 	t.isInitialized = true
 	t.mu.Unlock()
@@ -284,6 +301,125 @@ func (t *TrayManager) toggleNode() {
 	}
 }
 
+// profileHandlers returns the IPC handlers that manage profiles
+func (t *TrayManager) profileHandlers() *ipc.Handlers {
+	if t.app == nil {
+		return nil
+	}
+	return t.app.ipcHandlers
+}
+
+// refreshProfiles rebuilds the profiles submenu
+func (t *TrayManager) refreshProfiles() {
+	handlers := t.profileHandlers()
+	if handlers == nil || t.mProfiles == nil {
+		return
+	}
+
+	list, err := handlers.ListProfiles()
+	if err != nil {
+		t.logger.Warn("Failed to list profiles", zap.Error(err))
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, item := range t.profileItems {
+		item.Remove()
+	}
+	t.profileItems = t.profileItems[:0]
+
+	for _, profile := range list.Profiles {
+		name := profile.Name
+		tooltip := "Switch to profile " + name
+		if profile.IPv6Address != "" {
+			tooltip = profile.IPv6Address
+		}
+
+		item := t.mProfiles.AddSubMenuItemCheckbox(name, tooltip, profile.Active)
+		item.Click(func() {
+			t.logger.Debug("Tray menu: profile clicked", zap.String("profile", name))
+			go t.switchProfile(name)
+		})
+		t.profileItems = append(t.profileItems, item)
+	}
+
+	mNew := t.mProfiles.AddSubMenuItem("New Profile", "Create a profile with a new identity")
+	mNew.Click(func() {
+		go t.createProfile()
+	})
+
+	mClone := t.mProfiles.AddSubMenuItem("Clone Current Profile", "Copy the current profile with a new identity")
+	mClone.Click(func() {
+		go t.cloneProfile()
+	})
+
+	mManage := t.mProfiles.AddSubMenuItem("Manage Profiles...", "Rename or delete profiles in the main window")
+	mManage.Click(func() {
+		t.executeCallbackSafely("manageProfiles", func() func() {
+			return t.onShowWindow
+		})
+	})
+
+	t.profileItems = append(t.profileItems, mNew, mClone, mManage)
+}
+
+// switchProfile switches to a profile, restarting a running node
+func (t *TrayManager) switchProfile(name string) {
+	handlers := t.profileHandlers()
+	if handlers == nil {
+		return
+	}
+
+	t.logger.Info("Switching profile from tray", zap.String("profile", name))
+	if err := handlers.SwitchProfile(name); err != nil {
+		t.logger.Error("Failed to switch profile", zap.String("profile", name), zap.Error(err))
+		// Restore the check marks of the active profile
+		cef.QueueAsyncCall(func(id int) {
+			t.refreshProfiles()
+		})
+	}
+}
+
+// createProfile creates a profile with the first free "Profile N" name
+func (t *TrayManager) createProfile() {
+	handlers := t.profileHandlers()
+	if handlers == nil {
+		return
+	}
+
+	name := t.freeProfileName("Profile %d", 2)
+	if err := handlers.CreateProfile(name); err != nil {
+		t.logger.Error("Failed to create profile", zap.String("profile", name), zap.Error(err))
+	}
+}
+
+// cloneProfile clones the active profile with a new identity
+func (t *TrayManager) cloneProfile() {
+	handlers := t.profileHandlers()
+	if handlers == nil {
+		return
+	}
+
+	active := handlers.GetProfileManager().Active()
+	name := t.freeProfileName(active+" copy %d", 1)
+	if err := handlers.CloneProfile(active, name, true); err != nil {
+		t.logger.Error("Failed to clone profile", zap.String("profile", active), zap.Error(err))
+	}
+}
+
+// freeProfileName returns the first unused name from format, counting from n
+func (t *TrayManager) freeProfileName(format string, n int) string {
+	profiles := t.profileHandlers().GetProfileManager()
+	for ; ; n++ {
+		name := fmt.Sprintf(format, n)
+		if !profiles.Exists(name) {
+			return name
+		}
+	}
+}
+
 // handleStateChange updates the tray based on service state changes
 func (t *TrayManager) handleStateChange(state yggdrasil.ServiceState, info *yggdrasil.NodeInfo) {
 	t.logger.Debug("Service state changed", zap.Int("state", int(state)))
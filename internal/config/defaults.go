@@ -1,5 +1,25 @@
 package config
 
+// DefaultControlAddress is the default listen address of the control API
+const DefaultControlAddress = "127.0.0.1:9001"
+
+// DefaultMetricsAddress is the default listen address of the Prometheus
+// metrics
+const DefaultMetricsAddress = "127.0.0.1:9101"
+
+// DefaultPeerDirectory is the public peer list offered for peer discovery
+const DefaultPeerDirectory = "https://publicpeers.neilalexander.dev/publicnodes.json"
+
+// MinFailoverInterval is the shortest peer probe interval in seconds
+const MinFailoverInterval = 5
+
+// DefaultStatsInterval is the default time between stats updates in seconds
+const DefaultStatsInterval = 2
+
+// DefaultProfile is the name of the node profile used before any other
+// profile is created
+const DefaultProfile = "default"
+
 // DefaultSettings returns the default application settings
 func DefaultSettings() *Settings {
 	return &Settings{
@@ -14,6 +34,7 @@ func DefaultSettings() *Settings {
 		Node: NodeSettings{
 			ConfigPath:  "",
 			AutoConnect: true,
+			Profile:     DefaultProfile,
 		},
 		Proxy: ProxySettings{
 			Enabled:       false,
@@ -26,6 +47,28 @@ func DefaultSettings() *Settings {
 			LocalUDP:  []PortMapping{},
 			RemoteUDP: []PortMapping{},
 		},
+		Control: ControlSettings{
+			Enabled:       false,
+			ListenAddress: DefaultControlAddress,
+		},
+		Failover: FailoverSettings{
+			Enabled:   false,
+			MinPeers:  1,
+			Interval:  30,
+			DeadAfter: 3,
+			Standby:   []string{},
+		},
+		Directory: DirectorySettings{
+			Source:  DefaultPeerDirectory,
+			Regions: []string{},
+		},
+		Stats: StatsSettings{
+			Interval: DefaultStatsInterval,
+		},
+		Metrics: MetricsSettings{
+			Enabled:       false,
+			ListenAddress: DefaultMetricsAddress,
+		},
 	}
 }
 
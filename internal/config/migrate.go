@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// LegacySettings contains proxy and mapping settings found in older
+// Yggdrasil config files. config.json is the only place they are kept now.
+type LegacySettings struct {
+	Proxy    *ProxySettings
+	Mappings MappingsSettings
+}
+
+// Conflict describes a value that differs between config.json and a legacy source
+type Conflict struct {
+	Field     string `json:"field"`
+	Kept      string `json:"kept"`      // Value from config.json
+	Discarded string `json:"discarded"` // Value from the legacy source
+}
+
+// MigrationReport describes the result of merging legacy settings
+type MigrationReport struct {
+	ProxyMigrated bool       `json:"proxyMigrated"`
+	MappingsAdded int        `json:"mappingsAdded"`
+	Conflicts     []Conflict `json:"conflicts"`
+}
+
+// Changed reports whether the merge modified the settings
+func (r *MigrationReport) Changed() bool {
+	return r.ProxyMigrated || r.MappingsAdded > 0
+}
+
+// MergeLegacy merges legacy settings into the store.
+// Values already set in config.json win; differing legacy values are
+// reported as conflicts. Default values never cause a conflict.
+func (s *Store) MergeLegacy(legacy *LegacySettings) *MigrationReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &MigrationReport{Conflicts: []Conflict{}}
+	if legacy == nil {
+		return report
+	}
+
+	if legacy.Proxy != nil {
+		mergeProxy(&s.settings.Proxy, *legacy.Proxy, report)
+	}
+
+	groups := []struct {
+		name    string
+		current *[]PortMapping
+		legacy  []PortMapping
+	}{
+		{"localTcp", &s.settings.Mappings.LocalTCP, legacy.Mappings.LocalTCP},
+		{"remoteTcp", &s.settings.Mappings.RemoteTCP, legacy.Mappings.RemoteTCP},
+		{"localUdp", &s.settings.Mappings.LocalUDP, legacy.Mappings.LocalUDP},
+		{"remoteUdp", &s.settings.Mappings.RemoteUDP, legacy.Mappings.RemoteUDP},
+	}
+
+	for _, group := range groups {
+		for _, m := range group.legacy {
+			mergeMapping(group.name, group.current, m, report)
+		}
+	}
+
+	return report
+}
+
+// equalProxy reports whether two proxy settings are the same
+func equalProxy(a, b ProxySettings) bool {
+	return a.Enabled == b.Enabled &&
+		a.ListenAddress == b.ListenAddress &&
+		a.Nameserver == b.Nameserver &&
+		a.Auth == b.Auth &&
+		a.HTTPListenAddress == b.HTTPListenAddress &&
+		slices.Equal(a.AllowedClients, b.AllowedClients) &&
+		equalRouting(a.Routing, b.Routing)
+}
+
+// equalRouting reports whether two proxy routing settings are the same
+func equalRouting(a, b ProxyRoutingSettings) bool {
+	return a.Enabled == b.Enabled &&
+		a.Default == b.Default &&
+		a.Upstream == b.Upstream &&
+		a.UpstreamAuth == b.UpstreamAuth &&
+		slices.Equal(a.Rules, b.Rules)
+}
+
+// mergeProxy merges legacy proxy settings into current
+func mergeProxy(current *ProxySettings, legacy ProxySettings, report *MigrationReport) {
+	defaults := DefaultSettings().Proxy
+
+	if equalProxy(legacy, *current) || equalProxy(legacy, defaults) {
+		return
+	}
+
+	// Nothing configured in config.json yet, take the legacy values
+	if equalProxy(*current, defaults) {
+		*current = legacy
+		report.ProxyMigrated = true
+		return
+	}
+
+	if current.Enabled != legacy.Enabled {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Field:     "proxy.enabled",
+			Kept:      fmt.Sprint(current.Enabled),
+			Discarded: fmt.Sprint(legacy.Enabled),
+		})
+	}
+	if current.ListenAddress != legacy.ListenAddress {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Field:     "proxy.listenAddress",
+			Kept:      current.ListenAddress,
+			Discarded: legacy.ListenAddress,
+		})
+	}
+	if current.Nameserver != legacy.Nameserver {
+		report.Conflicts = append(report.Conflicts, Conflict{
+			Field:     "proxy.nameserver",
+			Kept:      current.Nameserver,
+			Discarded: legacy.Nameserver,
+		})
+	}
+}
+
+// mergeMapping adds a legacy mapping unless it is already present
+func mergeMapping(group string, current *[]PortMapping, legacy PortMapping, report *MigrationReport) {
+	for _, m := range *current {
+		sameRule := m.Source == legacy.Source && m.Target == legacy.Target
+
+		if m.ID == legacy.ID {
+			if !sameRule {
+				report.Conflicts = append(report.Conflicts, Conflict{
+					Field:     fmt.Sprintf("mappings.%s[%s]", group, m.ID),
+					Kept:      m.Source + " -> " + m.Target,
+					Discarded: legacy.Source + " -> " + legacy.Target,
+				})
+			}
+			return
+		}
+
+		// Same rule under another ID
+		if sameRule {
+			return
+		}
+	}
+
+	*current = append(*current, legacy)
+	report.MappingsAdded++
+}
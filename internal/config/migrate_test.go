@@ -0,0 +1,114 @@
+package config
+
+import "testing"
+
+func TestMergeLegacyProxy(t *testing.T) {
+	custom := ProxySettings{Enabled: true, ListenAddress: "127.0.0.1:2080", Nameserver: "[308::1]:53"}
+
+	tests := []struct {
+		name          string
+		current       ProxySettings
+		legacy        ProxySettings
+		want          ProxySettings
+		wantMigrated  bool
+		wantConflicts int
+	}{
+		{
+			name:         "defaults take legacy",
+			current:      DefaultSettings().Proxy,
+			legacy:       custom,
+			want:         custom,
+			wantMigrated: true,
+		},
+		{
+			name:    "legacy defaults ignored",
+			current: custom,
+			legacy:  DefaultSettings().Proxy,
+			want:    custom,
+		},
+		{
+			name:    "same values",
+			current: custom,
+			legacy:  custom,
+			want:    custom,
+		},
+		{
+			name:          "conflict keeps settings",
+			current:       custom,
+			legacy:        ProxySettings{Enabled: true, ListenAddress: "127.0.0.1:3080", Nameserver: "[308::2]:53"},
+			want:          custom,
+			wantConflicts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStoreWithPath("")
+			s.Update(func(st *Settings) { st.Proxy = tt.current })
+
+			legacy := tt.legacy
+			report := s.MergeLegacy(&LegacySettings{Proxy: &legacy})
+
+			if got := s.Get().Proxy; !equalProxy(got, tt.want) {
+				t.Errorf("Proxy = %+v, want %+v", got, tt.want)
+			}
+			if report.ProxyMigrated != tt.wantMigrated {
+				t.Errorf("ProxyMigrated = %v, want %v", report.ProxyMigrated, tt.wantMigrated)
+			}
+			if len(report.Conflicts) != tt.wantConflicts {
+				t.Errorf("len(Conflicts) = %d, want %d: %v", len(report.Conflicts), tt.wantConflicts, report.Conflicts)
+			}
+		})
+	}
+}
+
+func TestMergeLegacyMappings(t *testing.T) {
+	s := NewStoreWithPath("")
+	s.Update(func(st *Settings) {
+		st.Mappings.LocalTCP = []PortMapping{
+			{ID: "web", Source: "127.0.0.1:8080", Target: "[200::1]:80", Enabled: true},
+		}
+	})
+
+	report := s.MergeLegacy(&LegacySettings{
+		Mappings: MappingsSettings{
+			LocalTCP: []PortMapping{
+				{ID: "web", Source: "127.0.0.1:8081", Target: "[200::1]:80"},   // conflict
+				{ID: "other", Source: "127.0.0.1:8080", Target: "[200::1]:80"}, // duplicate rule
+				{ID: "ssh", Source: "127.0.0.1:2222", Target: "[200::2]:22"},   // new
+			},
+			RemoteUDP: []PortMapping{
+				{ID: "dns", Source: ":53", Target: "127.0.0.1:53", Enabled: true}, // new
+			},
+		},
+	})
+
+	settings := s.Get()
+	if len(settings.Mappings.LocalTCP) != 2 {
+		t.Errorf("len(LocalTCP) = %d, want 2", len(settings.Mappings.LocalTCP))
+	}
+	if settings.Mappings.LocalTCP[0].Source != "127.0.0.1:8080" {
+		t.Errorf("existing mapping changed: %+v", settings.Mappings.LocalTCP[0])
+	}
+	if len(settings.Mappings.RemoteUDP) != 1 {
+		t.Errorf("len(RemoteUDP) = %d, want 1", len(settings.Mappings.RemoteUDP))
+	}
+	if report.MappingsAdded != 2 {
+		t.Errorf("MappingsAdded = %d, want 2", report.MappingsAdded)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Field != "mappings.localTcp[web]" {
+		t.Errorf("Conflicts = %v, want one for mappings.localTcp[web]", report.Conflicts)
+	}
+	if !report.Changed() {
+		t.Error("Changed() = false, want true")
+	}
+}
+
+func TestMergeLegacyNil(t *testing.T) {
+	s := NewStoreWithPath("")
+	report := s.MergeLegacy(nil)
+
+	if report.Changed() || len(report.Conflicts) != 0 {
+		t.Errorf("MergeLegacy(nil) = %+v, want empty report", report)
+	}
+}
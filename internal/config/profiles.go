@@ -0,0 +1,150 @@
+package config
+
+// ActiveProfile returns the name of the active node profile
+func (s *Store) ActiveProfile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return activeProfile(s.settings)
+}
+
+// SwitchProfile parks the proxy and mapping settings of the active profile
+// and restores the ones saved for name. A profile without saved settings
+// starts with the defaults.
+func (s *Store) SwitchProfile(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := activeProfile(s.settings)
+	if current == name {
+		return
+	}
+
+	if s.settings.Profiles == nil {
+		s.settings.Profiles = make(map[string]ProfileSettings)
+	}
+	s.settings.Profiles[current] = ProfileSettings{
+		Proxy:    s.settings.Proxy,
+		Mappings: s.settings.Mappings,
+	}
+
+	next, ok := s.settings.Profiles[name]
+	if !ok {
+		defaults := DefaultSettings()
+		next = ProfileSettings{Proxy: defaults.Proxy, Mappings: defaults.Mappings}
+	}
+	delete(s.settings.Profiles, name)
+
+	s.settings.Proxy = next.Proxy
+	s.settings.Mappings = next.Mappings
+	s.settings.Node.Profile = name
+}
+
+// CloneProfile copies the proxy and mapping settings of src to dst
+func (s *Store) CloneProfile(src, dst string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var source ProfileSettings
+	if src == activeProfile(s.settings) {
+		source = ProfileSettings{Proxy: s.settings.Proxy, Mappings: s.settings.Mappings}
+	} else if saved, ok := s.settings.Profiles[src]; ok {
+		source = saved
+	} else {
+		return
+	}
+
+	if s.settings.Profiles == nil {
+		s.settings.Profiles = make(map[string]ProfileSettings)
+	}
+	s.settings.Profiles[dst] = ProfileSettings{
+		Proxy:    source.Proxy,
+		Mappings: copyMappings(source.Mappings),
+	}
+}
+
+// RenameProfile moves the settings of a profile to a new name
+func (s *Store) RenameProfile(oldName, newName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if activeProfile(s.settings) == oldName {
+		s.settings.Node.Profile = newName
+		return
+	}
+
+	if saved, ok := s.settings.Profiles[oldName]; ok {
+		s.settings.Profiles[newName] = saved
+		delete(s.settings.Profiles, oldName)
+	}
+}
+
+// DeleteProfile drops the saved settings of an inactive profile
+func (s *Store) DeleteProfile(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.settings.Profiles, name)
+}
+
+// Profile returns the proxy and mapping settings of a profile. A profile
+// without saved settings gets the defaults.
+func (s *Store) Profile(name string) ProfileSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var p ProfileSettings
+	if name == activeProfile(s.settings) {
+		p = ProfileSettings{Proxy: s.settings.Proxy, Mappings: s.settings.Mappings}
+	} else if saved, ok := s.settings.Profiles[name]; ok {
+		p = saved
+	} else {
+		defaults := DefaultSettings()
+		p = ProfileSettings{Proxy: defaults.Proxy, Mappings: defaults.Mappings}
+	}
+
+	p.Mappings = copyMappings(p.Mappings)
+	return p
+}
+
+// UpdateProfile updates the proxy and mapping settings of a profile
+func (s *Store) UpdateProfile(name string, fn func(*ProfileSettings)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name == activeProfile(s.settings) {
+		p := ProfileSettings{Proxy: s.settings.Proxy, Mappings: s.settings.Mappings}
+		fn(&p)
+		s.settings.Proxy = p.Proxy
+		s.settings.Mappings = p.Mappings
+		return
+	}
+
+	p, ok := s.settings.Profiles[name]
+	if !ok {
+		defaults := DefaultSettings()
+		p = ProfileSettings{Proxy: defaults.Proxy, Mappings: defaults.Mappings}
+	}
+	fn(&p)
+
+	if s.settings.Profiles == nil {
+		s.settings.Profiles = make(map[string]ProfileSettings)
+	}
+	s.settings.Profiles[name] = p
+}
+
+// activeProfile returns the active profile name of settings
+func activeProfile(settings *Settings) string {
+	if settings.Node.Profile == "" {
+		return DefaultProfile
+	}
+	return settings.Node.Profile
+}
+
+// copyMappings returns a deep copy of mapping settings
+func copyMappings(m MappingsSettings) MappingsSettings {
+	return MappingsSettings{
+		LocalTCP:  append([]PortMapping{}, m.LocalTCP...),
+		RemoteTCP: append([]PortMapping{}, m.RemoteTCP...),
+		LocalUDP:  append([]PortMapping{}, m.LocalUDP...),
+		RemoteUDP: append([]PortMapping{}, m.RemoteUDP...),
+	}
+}
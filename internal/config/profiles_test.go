@@ -0,0 +1,140 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_SwitchProfile(t *testing.T) {
+	s := NewStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+
+	s.Update(func(settings *Settings) {
+		settings.Proxy.ListenAddress = "127.0.0.1:2080"
+		settings.Mappings.LocalTCP = []PortMapping{{ID: "web", Source: "127.0.0.1:8080", Target: "[200::1]:80", Enabled: true}}
+	})
+
+	if got := s.ActiveProfile(); got != DefaultProfile {
+		t.Fatalf("ActiveProfile() = %q, want %q", got, DefaultProfile)
+	}
+
+	// A new profile starts with default proxy and mapping settings
+	s.SwitchProfile("lab")
+	settings := s.Get()
+	if settings.Node.Profile != "lab" {
+		t.Errorf("Node.Profile = %q, want lab", settings.Node.Profile)
+	}
+	if !equalProxy(settings.Proxy, DefaultSettings().Proxy) {
+		t.Errorf("Proxy = %+v, want defaults", settings.Proxy)
+	}
+	if len(settings.Mappings.LocalTCP) != 0 {
+		t.Errorf("LocalTCP = %+v, want none", settings.Mappings.LocalTCP)
+	}
+
+	s.Update(func(settings *Settings) {
+		settings.Proxy.ListenAddress = "127.0.0.1:3080"
+	})
+
+	// Switching back restores the parked settings
+	s.SwitchProfile(DefaultProfile)
+	settings = s.Get()
+	if settings.Proxy.ListenAddress != "127.0.0.1:2080" {
+		t.Errorf("Proxy.ListenAddress = %q, want 127.0.0.1:2080", settings.Proxy.ListenAddress)
+	}
+	if len(settings.Mappings.LocalTCP) != 1 || settings.Mappings.LocalTCP[0].ID != "web" {
+		t.Errorf("LocalTCP = %+v", settings.Mappings.LocalTCP)
+	}
+	if _, ok := settings.Profiles[DefaultProfile]; ok {
+		t.Error("active profile should not be parked")
+	}
+	if parked := settings.Profiles["lab"]; parked.Proxy.ListenAddress != "127.0.0.1:3080" {
+		t.Errorf("parked lab proxy = %+v", parked.Proxy)
+	}
+
+	// Settings survive a save and load
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded := NewStoreWithPath(s.path)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if parked := loaded.Get().Profiles["lab"]; parked.Proxy.ListenAddress != "127.0.0.1:3080" {
+		t.Errorf("loaded lab proxy = %+v", parked.Proxy)
+	}
+}
+
+func TestStore_CloneRenameDeleteProfile(t *testing.T) {
+	s := NewStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+	s.Update(func(settings *Settings) {
+		settings.Mappings.RemoteTCP = []PortMapping{{ID: "ssh", Source: ":22", Target: "127.0.0.1:22", Enabled: true}}
+	})
+
+	s.CloneProfile(DefaultProfile, "copy")
+	clone := s.Get().Profiles["copy"]
+	if len(clone.Mappings.RemoteTCP) != 1 {
+		t.Fatalf("cloned RemoteTCP = %+v", clone.Mappings.RemoteTCP)
+	}
+
+	// The clone does not share mapping slices with the source
+	s.Update(func(settings *Settings) {
+		settings.Mappings.RemoteTCP[0].Enabled = false
+	})
+	if !s.Get().Profiles["copy"].Mappings.RemoteTCP[0].Enabled {
+		t.Error("clone changed together with its source")
+	}
+
+	s.RenameProfile("copy", "prod")
+	profiles := s.Get().Profiles
+	if _, ok := profiles["copy"]; ok {
+		t.Error("old name still present after rename")
+	}
+	if _, ok := profiles["prod"]; !ok {
+		t.Error("new name missing after rename")
+	}
+
+	// Renaming the active profile only changes its name
+	s.RenameProfile(DefaultProfile, "main")
+	if got := s.ActiveProfile(); got != "main" {
+		t.Errorf("ActiveProfile() = %q, want main", got)
+	}
+
+	s.DeleteProfile("prod")
+	if _, ok := s.Get().Profiles["prod"]; ok {
+		t.Error("profile still present after delete")
+	}
+}
+
+func TestStore_ProfileSettings(t *testing.T) {
+	s := NewStoreWithPath(filepath.Join(t.TempDir(), "config.json"))
+
+	// The active profile is backed by the top level settings
+	s.UpdateProfile(DefaultProfile, func(p *ProfileSettings) {
+		p.Proxy.ListenAddress = "127.0.0.1:2080"
+	})
+	if got := s.Get().Proxy.ListenAddress; got != "127.0.0.1:2080" {
+		t.Errorf("Proxy.ListenAddress = %q, want 127.0.0.1:2080", got)
+	}
+
+	// An unknown profile starts from the defaults
+	if got := s.Profile("lab").Proxy; !equalProxy(got, DefaultSettings().Proxy) {
+		t.Errorf("Profile(lab).Proxy = %+v, want defaults", got)
+	}
+
+	s.UpdateProfile("lab", func(p *ProfileSettings) {
+		p.Proxy.ListenAddress = "127.0.0.1:3080"
+		p.Mappings.LocalTCP = append(p.Mappings.LocalTCP, PortMapping{ID: "web", Source: "127.0.0.1:8080", Target: "[200::1]:80"})
+	})
+	lab := s.Profile("lab")
+	if lab.Proxy.ListenAddress != "127.0.0.1:3080" || len(lab.Mappings.LocalTCP) != 1 {
+		t.Errorf("Profile(lab) = %+v", lab)
+	}
+	if s.Get().Proxy.ListenAddress != "127.0.0.1:2080" {
+		t.Error("updating an inactive profile changed the active one")
+	}
+
+	// Returned mappings do not alias the stored ones
+	lab.Mappings.LocalTCP[0].ID = "changed"
+	if s.Profile("lab").Mappings.LocalTCP[0].ID != "web" {
+		t.Error("Profile() returned shared mapping slices")
+	}
+}
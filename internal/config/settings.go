@@ -2,10 +2,18 @@ package config
 
 // Settings represents all application configuration
 type Settings struct {
-	App      AppSettings      `json:"app"`
-	Node     NodeSettings     `json:"node"`
-	Proxy    ProxySettings    `json:"proxy"`
-	Mappings MappingsSettings `json:"mappings"`
+	App       AppSettings       `json:"app"`
+	Node      NodeSettings      `json:"node"`
+	Proxy     ProxySettings     `json:"proxy"`
+	Mappings  MappingsSettings  `json:"mappings"`
+	Control   ControlSettings   `json:"control"`
+	Failover  FailoverSettings  `json:"failover"`
+	Directory DirectorySettings `json:"directory"`
+	Stats     StatsSettings     `json:"stats"`
+	Metrics   MetricsSettings   `json:"metrics"`
+
+	// Proxy and mapping settings of inactive node profiles
+	Profiles map[string]ProfileSettings `json:"profiles,omitempty"`
 }
 
 // AppSettings contains general application settings
@@ -20,8 +28,16 @@ type AppSettings struct {
 
 // NodeSettings contains Yggdrasil node settings
 type NodeSettings struct {
-	ConfigPath  string `json:"configPath"`
-	AutoConnect bool   `json:"autoConnect"`
+	ConfigPath  string   `json:"configPath"`
+	AutoConnect bool     `json:"autoConnect"`
+	Profile     string   `json:"profile"`         // Active node profile
+	Extra       []string `json:"extra,omitempty"` // Profiles run as additional nodes
+}
+
+// ProfileSettings contains the per-profile settings of an inactive profile
+type ProfileSettings struct {
+	Proxy    ProxySettings    `json:"proxy"`
+	Mappings MappingsSettings `json:"mappings"`
 }
 
 // ProxySettings contains SOCKS5 proxy settings
@@ -29,6 +45,70 @@ type ProxySettings struct {
 	Enabled       bool   `json:"enabled"`
 	ListenAddress string `json:"listenAddress"`
 	Nameserver    string `json:"nameserver"`
+	Auth          bool   `json:"auth"` // Credentials are kept in secure storage
+
+	// Client addresses or CIDRs allowed to use the proxy; empty allows all
+	AllowedClients []string `json:"allowedClients,omitempty"`
+
+	// HTTP proxy listener next to SOCKS5; empty disables it
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"`
+
+	// Split routing of destinations outside Yggdrasil
+	Routing ProxyRoutingSettings `json:"routing"`
+}
+
+// ProxyRoutingSettings contains the split routing settings of the proxy.
+// Yggdrasil addresses and .ygg names go over the mesh, other destinations
+// follow the rules and then Default.
+type ProxyRoutingSettings struct {
+	Enabled      bool               `json:"enabled"`
+	Default      string             `json:"default,omitempty"`      // "direct", "reject" or "upstream"
+	Upstream     string             `json:"upstream,omitempty"`     // socks5:// or http:// upstream proxy URL
+	UpstreamAuth bool               `json:"upstreamAuth,omitempty"` // Upstream credentials are kept in secure storage
+	Rules        []ProxyRoutingRule `json:"rules,omitempty"`
+}
+
+// ProxyRoutingRule routes a domain, address or CIDR
+type ProxyRoutingRule struct {
+	Match  string `json:"match"`
+	Action string `json:"action"` // "yggdrasil", "direct", "reject" or "upstream"
+}
+
+// ControlSettings contains local HTTP control API settings
+type ControlSettings struct {
+	Enabled       bool   `json:"enabled"`
+	ListenAddress string `json:"listenAddress"` // Must be a loopback address
+}
+
+// FailoverSettings contains peer health monitoring and failover settings.
+// They apply to every node.
+type FailoverSettings struct {
+	Enabled   bool     `json:"enabled"`
+	MinPeers  int      `json:"minPeers"`  // Connected peers below which standby peers are added
+	Interval  int      `json:"interval"`  // Seconds between peer probes
+	DeadAfter int      `json:"deadAfter"` // Failed probes after which a peer is removed
+	Standby   []string `json:"standby"`   // Peers added when connected peers drop
+}
+
+// DirectorySettings contains public peer directory settings
+type DirectorySettings struct {
+	Source  string   `json:"source"`  // URL or local file of a JSON or markdown peer list
+	Regions []string `json:"regions"` // Preferred regions, ranked first
+}
+
+// StatsSettings contains live traffic statistics settings
+type StatsSettings struct {
+	Interval int `json:"interval"` // Seconds between stats updates
+}
+
+// MetricsSettings contains Prometheus metrics settings
+type MetricsSettings struct {
+	Enabled       bool   `json:"enabled"`
+	ListenAddress string `json:"listenAddress"` // Must be a loopback address
+	YggdrasilPort int    `json:"yggdrasilPort"` // Also serve on the node address when set
+
+	// Public keys allowed to read metrics on the Yggdrasil port
+	AllowedPublicKeys []string `json:"allowedPublicKeys"`
 }
 
 // MappingsSettings contains port forwarding mappings
@@ -45,6 +125,17 @@ type PortMapping struct {
 	Source  string `json:"source"`
 	Target  string `json:"target"`
 	Enabled bool   `json:"enabled"`
+
+	// Optional limits, 0 means unlimited
+	UploadLimit    int64 `json:"uploadLimit,omitempty"`    // Bytes per second, client to target
+	DownloadLimit  int64 `json:"downloadLimit,omitempty"`  // Bytes per second, target to client
+	MaxConnections int   `json:"maxConnections,omitempty"` // Concurrent TCP connections
+	MaxSessions    int   `json:"maxSessions,omitempty"`    // Concurrent UDP sessions
+	UDPIdleTimeout int   `json:"udpIdleTimeout,omitempty"` // Seconds, 0 uses the default
+
+	// Access lists of remote mappings: public keys, addresses or subnets
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
 }
 
 // Validate checks if the settings are valid
@@ -65,5 +156,54 @@ func (s *Settings) Validate() error {
 		s.App.LogLevel = "info"
 	}
 
+	// Validate active profile
+	if s.Node.Profile == "" {
+		s.Node.Profile = DefaultProfile
+	}
+
+	// Validate control API address
+	if s.Control.ListenAddress == "" {
+		s.Control.ListenAddress = DefaultControlAddress
+	}
+
+	// Validate failover settings
+	defaults := DefaultSettings().Failover
+	if s.Failover.MinPeers < 1 {
+		s.Failover.MinPeers = defaults.MinPeers
+	}
+	if s.Failover.Interval < MinFailoverInterval {
+		s.Failover.Interval = defaults.Interval
+	}
+	if s.Failover.DeadAfter < 1 {
+		s.Failover.DeadAfter = defaults.DeadAfter
+	}
+	if s.Failover.Standby == nil {
+		s.Failover.Standby = []string{}
+	}
+
+	// Validate peer directory settings
+	if s.Directory.Source == "" {
+		s.Directory.Source = DefaultPeerDirectory
+	}
+	if s.Directory.Regions == nil {
+		s.Directory.Regions = []string{}
+	}
+
+	// Validate stats settings
+	if s.Stats.Interval < 1 {
+		s.Stats.Interval = DefaultStatsInterval
+	}
+
+	// Validate metrics settings
+	if s.Metrics.ListenAddress == "" {
+		s.Metrics.ListenAddress = DefaultMetricsAddress
+	}
+	if s.Metrics.YggdrasilPort < 0 || s.Metrics.YggdrasilPort > 65535 {
+		s.Metrics.YggdrasilPort = 0
+	}
+	if s.Metrics.AllowedPublicKeys == nil {
+		s.Metrics.AllowedPublicKeys = []string{}
+	}
+
 	return nil
 }
@@ -45,6 +45,48 @@ func TestDefaultSettings(t *testing.T) {
 	if s.Mappings.RemoteTCP == nil {
 		t.Error("expected RemoteTCP to be initialized")
 	}
+
+	// Check control API defaults
+	if s.Control.Enabled != false {
+		t.Error("expected default control API disabled")
+	}
+	if s.Control.ListenAddress != DefaultControlAddress {
+		t.Errorf("expected default control address %q, got %q", DefaultControlAddress, s.Control.ListenAddress)
+	}
+}
+
+func TestSettingsValidateControlAddress(t *testing.T) {
+	s := Settings{}
+	s.Validate()
+
+	if s.Control.ListenAddress != DefaultControlAddress {
+		t.Errorf("Control.ListenAddress = %q, want %q", s.Control.ListenAddress, DefaultControlAddress)
+	}
+
+	s.Control.ListenAddress = "127.0.0.1:9100"
+	s.Validate()
+
+	if s.Control.ListenAddress != "127.0.0.1:9100" {
+		t.Errorf("Control.ListenAddress = %q, want %q", s.Control.ListenAddress, "127.0.0.1:9100")
+	}
+}
+
+func TestSettingsValidateFailover(t *testing.T) {
+	s := Settings{}
+	s.Failover.Interval = 1
+	s.Validate()
+
+	want := DefaultSettings().Failover
+	if s.Failover.MinPeers != want.MinPeers || s.Failover.Interval != want.Interval || s.Failover.DeadAfter != want.DeadAfter {
+		t.Errorf("Failover = %+v, want defaults %+v", s.Failover, want)
+	}
+
+	s.Failover = FailoverSettings{MinPeers: 2, Interval: 60, DeadAfter: 5}
+	s.Validate()
+
+	if s.Failover.MinPeers != 2 || s.Failover.Interval != 60 || s.Failover.DeadAfter != 5 {
+		t.Errorf("valid failover settings changed: %+v", s.Failover)
+	}
 }
 
 func TestSettingsValidate(t *testing.T) {
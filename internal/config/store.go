@@ -71,7 +71,25 @@ func (s *Store) Save() error {
 		return err
 	}
 
-	return os.WriteFile(s.path, data, 0644)
+	// Write aside and rename into place, so a crash never leaves a partly
+	// written file. Only the user can read the settings.
+	tmp, err := os.CreateTemp(dir, ".config-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
 }
 
 // Get returns a copy of the current settings
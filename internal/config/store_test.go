@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"testing"
 )
@@ -288,6 +289,36 @@ func TestStoreSaveJSONFormat(t *testing.T) {
 	}
 }
 
+func TestStoreSaveReplacesFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewStoreWithPath(configPath)
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// No temporary file is left next to the config
+	entries, _ := os.ReadDir(filepath.Dir(configPath))
+	if len(entries) != 1 {
+		t.Errorf("unexpected files after Save(): %v", entries)
+	}
+}
+
 func TestDefaultPeers(t *testing.T) {
 	peers := DefaultPeers()
 
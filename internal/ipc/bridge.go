@@ -3,6 +3,7 @@ package ipc
 import (
 	gocontext "context"
 	"encoding/json"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,6 +22,7 @@ type Bridge struct {
 	subscribers map[string][]Subscriber
 	middleware  []Middleware
 	timeout     time.Duration
+	latency     *handlerLatency
 }
 
 // Handler is a function that handles an IPC event
@@ -35,6 +37,7 @@ type Middleware func(event string, req *Request, next func() *Response) *Respons
 // Request represents an IPC request from the frontend
 type Request struct {
 	RequestID string          `json:"requestId"`
+	NodeID    string          `json:"nodeId,omitempty"` // Target node, empty for the primary node
 	Payload   json.RawMessage `json:"payload"`
 	Timestamp int64           `json:"timestamp,omitempty"`
 }
@@ -91,6 +94,7 @@ func NewBridgeWithConfig(cfg BridgeConfig) *Bridge {
 		subscribers: make(map[string][]Subscriber),
 		middleware:  make([]Middleware, 0),
 		timeout:     cfg.Timeout,
+		latency:     newHandlerLatency(),
 	}
 }
 
@@ -129,6 +133,25 @@ func (b *Bridge) Subscribe(event string, subscriber Subscriber) {
 
 // handleEvent processes an incoming IPC event
 func (b *Bridge) handleEvent(event string, ctx ipccontext.IContext) {
+	// Parse request
+	var req Request
+	args := ctx.ArgumentList()
+	if args.Size() > 0 {
+		data := args.GetStringByIndex(0)
+		if err := json.Unmarshal([]byte(data), &req); err != nil {
+			b.logger.Error("Failed to parse request", "event", event, "error", err)
+			b.sendError(ctx, "", "PARSE_ERROR", "Failed to parse request: "+err.Error())
+			return
+		}
+	}
+
+	// Send response
+	b.sendResponse(ctx, b.Dispatch(event, &req))
+}
+
+// Dispatch runs a request through the middleware chain and the registered handler.
+// It is used by the Energy IPC transport and by other transports (e.g. the HTTP control API).
+func (b *Bridge) Dispatch(event string, req *Request) *Response {
 	startTime := time.Now()
 
 	b.mu.RLock()
@@ -139,19 +162,14 @@ func (b *Bridge) handleEvent(event string, ctx ipccontext.IContext) {
 
 	if !exists {
 		b.logger.Warn("No handler for event", "event", event)
-		b.sendError(ctx, "", "HANDLER_NOT_FOUND", "No handler registered for event: "+event)
-		return
-	}
-
-	// Parse request
-	var req Request
-	args := ctx.ArgumentList()
-	if args.Size() > 0 {
-		data := args.GetStringByIndex(0)
-		if err := json.Unmarshal([]byte(data), &req); err != nil {
-			b.logger.Error("Failed to parse request", "event", event, "error", err)
-			b.sendError(ctx, "", "PARSE_ERROR", "Failed to parse request: "+err.Error())
-			return
+		return &Response{
+			Success:   false,
+			RequestID: req.RequestID,
+			Timestamp: time.Now().UnixMilli(),
+			Error: &Error{
+				Code:    "HANDLER_NOT_FOUND",
+				Message: "No handler registered for event: " + event,
+			},
 		}
 	}
 
@@ -163,7 +181,7 @@ func (b *Bridge) handleEvent(event string, ctx ipccontext.IContext) {
 
 	// Build the handler chain
 	finalHandler := func() *Response {
-		return handler(&req)
+		return handler(req)
 	}
 
 	// Apply middleware in reverse order
@@ -172,7 +190,7 @@ func (b *Bridge) handleEvent(event string, ctx ipccontext.IContext) {
 		mw := middleware[i]
 		next := chain
 		chain = func() *Response {
-			return mw(event, &req, next)
+			return mw(event, req, next)
 		}
 	}
 
@@ -202,12 +220,25 @@ func (b *Bridge) handleEvent(event string, ctx ipccontext.IContext) {
 
 	// Log performance
 	duration := time.Since(startTime)
+	b.latency.observe(event, duration, response.Success)
 	if duration > 100*time.Millisecond {
 		b.logger.Warn("Slow IPC handler", "event", event, "duration", duration)
 	}
 
-	// Send response
-	b.sendResponse(ctx, response)
+	return response
+}
+
+// Events returns the names of all registered events
+func (b *Bridge) Events() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	events := make([]string, 0, len(b.handlers))
+	for event := range b.handlers {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
 }
 
 // sendResponse sends a response back to the frontend
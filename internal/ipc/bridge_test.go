@@ -11,7 +11,7 @@ import (
 func TestRequestJSON(t *testing.T) {
 	req := Request{
 		RequestID: "req-123",
-		Payload:   json.RawMessage(`{"key": "value"}`),
+		Payload:   json.RawMessage(`{"key":"value"}`), // Marshal compacts raw JSON
 		Timestamp: time.Now().UnixMilli(),
 	}
 
@@ -0,0 +1,330 @@
+package ipc
+
+import (
+	gocontext "context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+// ControlAPIPrefix is the URL prefix of the control API.
+// Every registered IPC event is reachable as POST <prefix><event>,
+// e.g. POST /api/v1/node:start
+const ControlAPIPrefix = "/api/v1/"
+
+// controlTokenSize is the size of a generated control token in bytes
+const controlTokenSize = 32
+
+// Control API errors
+var (
+	ErrControlNotLoopback = errors.New("control API must listen on a loopback address")
+	ErrControlNoToken     = errors.New("control API token is not set")
+)
+
+// ControlServer exposes the IPC handlers over a loopback HTTP/JSON API.
+// Requests go through Bridge.Dispatch, so they pass the same middleware
+// chain (security, validation) as requests from the GUI.
+type ControlServer struct {
+	mu          sync.Mutex
+	bridge      *Bridge
+	logger      *logger.Logger
+	auditLogger *logger.AuditLogger
+	token       []byte
+	server      *http.Server
+	listener    net.Listener
+}
+
+// NewControlServer creates a control server for the given bridge
+func NewControlServer(bridge *Bridge, log *logger.Logger, auditLog *logger.AuditLogger) *ControlServer {
+	return &ControlServer{
+		bridge:      bridge,
+		logger:      log,
+		auditLogger: auditLog,
+	}
+}
+
+// SetToken sets the bearer token required by the API
+func (cs *ControlServer) SetToken(token string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.token = []byte(token)
+}
+
+// Token returns the bearer token required by the API
+func (cs *ControlServer) Token() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return string(cs.token)
+}
+
+// Start starts listening on the given loopback address
+func (cs *ControlServer) Start(listenAddress string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.server != nil {
+		return errors.New("control API already running")
+	}
+	if len(cs.token) == 0 {
+		return ErrControlNoToken
+	}
+	if err := validateLoopbackAddress(listenAddress); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listenAddress, err)
+	}
+
+	server := &http.Server{
+		Handler:           cs,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	cs.server = server
+	cs.listener = listener
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			cs.logger.Error("Control API server error", "error", err)
+		}
+	}()
+
+	cs.logger.Info("Control API started", "address", listener.Addr().String())
+	return nil
+}
+
+// Stop stops the server
+func (cs *ControlServer) Stop() error {
+	cs.mu.Lock()
+	server := cs.server
+	cs.server = nil
+	cs.listener = nil
+	cs.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	cs.logger.Info("Control API stopped")
+	return nil
+}
+
+// IsRunning returns true if the server is listening
+func (cs *ControlServer) IsRunning() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.server != nil
+}
+
+// Address returns the actual listen address, or empty string if not running
+func (cs *ControlServer) Address() string {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.listener == nil {
+		return ""
+	}
+	return cs.listener.Addr().String()
+}
+
+// Apply starts, stops or restarts the server to match the settings
+func (cs *ControlServer) Apply(settings config.ControlSettings) error {
+	address := settings.ListenAddress
+	if address == "" {
+		address = config.DefaultControlAddress
+	}
+
+	if !settings.Enabled {
+		return cs.Stop()
+	}
+
+	if cs.IsRunning() {
+		if cs.Address() == address {
+			return nil
+		}
+		if err := cs.Stop(); err != nil {
+			return err
+		}
+	}
+
+	return cs.Start(address)
+}
+
+// ServeHTTP implements http.Handler
+func (cs *ControlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, ControlAPIPrefix) {
+		cs.writeError(w, http.StatusNotFound, "", "NOT_FOUND", "Unknown path: "+r.URL.Path)
+		return
+	}
+
+	if !cs.authorize(r) {
+		cs.logger.Warn("Control API request rejected", "path", r.URL.Path, "remote", r.RemoteAddr)
+		if cs.auditLogger != nil {
+			cs.auditLogger.LogFailure(logger.AuditEventSecurityError, "Control API authentication failed", nil, map[string]interface{}{
+				"path":   r.URL.Path,
+				"remote": r.RemoteAddr,
+			})
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		cs.writeError(w, http.StatusUnauthorized, "", "UNAUTHORIZED", "Missing or invalid bearer token")
+		return
+	}
+
+	event := strings.TrimPrefix(r.URL.Path, ControlAPIPrefix)
+	requestID := r.Header.Get("X-Request-ID")
+
+	// GET /api/v1/events lists the available events
+	if event == "events" {
+		if r.Method != http.MethodGet {
+			cs.writeError(w, http.StatusMethodNotAllowed, requestID, "METHOD_NOT_ALLOWED", "Use GET")
+			return
+		}
+		cs.writeJSON(w, http.StatusOK, &Response{
+			Success:   true,
+			Data:      cs.bridge.Events(),
+			RequestID: requestID,
+			Timestamp: time.Now().UnixMilli(),
+		})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		cs.writeError(w, http.StatusMethodNotAllowed, requestID, "METHOD_NOT_ALLOWED", "Use POST")
+		return
+	}
+
+	// The request body is the event payload, same as the GUI sends
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(DefaultSecurityConfig().MaxRequestSize)+1))
+	if err != nil {
+		cs.writeError(w, http.StatusBadRequest, requestID, "PARSE_ERROR", "Failed to read request body")
+		return
+	}
+
+	// The target node comes from a header or the query string
+	nodeID := r.Header.Get("X-Node-ID")
+	if nodeID == "" {
+		nodeID = r.URL.Query().Get("node")
+	}
+
+	req := &Request{RequestID: requestID, NodeID: nodeID}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		req.Payload = json.RawMessage(body)
+	}
+
+	resp := cs.bridge.Dispatch(event, req)
+
+	status := http.StatusOK
+	if resp.Error != nil && resp.Error.Code == "HANDLER_NOT_FOUND" {
+		status = http.StatusNotFound
+	}
+	cs.writeJSON(w, status, resp)
+}
+
+// authorize checks the Authorization header against the token
+func (cs *ControlServer) authorize(r *http.Request) bool {
+	cs.mu.Lock()
+	token := cs.token
+	cs.mu.Unlock()
+
+	if len(token) == 0 {
+		return false
+	}
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	return security.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), token)
+}
+
+// writeJSON writes a JSON response
+func (cs *ControlServer) writeJSON(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	if resp.RequestID != "" {
+		w.Header().Set("X-Request-ID", resp.RequestID)
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		cs.logger.Error("Failed to write control API response", "error", err)
+	}
+}
+
+// writeError writes an error response
+func (cs *ControlServer) writeError(w http.ResponseWriter, status int, requestID, code, message string) {
+	cs.writeJSON(w, status, &Response{
+		Success:   false,
+		RequestID: requestID,
+		Timestamp: time.Now().UnixMilli(),
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
+// validateLoopbackAddress ensures the address only binds to loopback
+func validateLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid listen address: %w", err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return ErrControlNotLoopback
+	}
+
+	return nil
+}
+
+// LoadControlToken returns the control token from secure storage,
+// generating and storing a new one on first use
+func LoadControlToken(store *security.SecureStore) (string, error) {
+	if store == nil {
+		return "", errors.New("secure storage is not available")
+	}
+
+	if data, err := store.Retrieve(security.ControlTokenAccount); err == nil && len(data) > 0 {
+		token := string(data)
+		security.ZeroBytes(data)
+		return token, nil
+	}
+
+	raw, err := security.GenerateRandomBytes(controlTokenSize)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	security.ZeroBytes(raw)
+
+	// Store zeros the value it is given, so pass a copy
+	if err := store.Store(security.ControlTokenAccount, []byte(token)); err != nil {
+		return "", fmt.Errorf("failed to store control token: %w", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,227 @@
+package ipc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+const testControlToken = "test-token"
+
+func newTestControlServer(t *testing.T) (*ControlServer, *Bridge) {
+	t.Helper()
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	bridge := NewBridge(log)
+	bridge.Register("test:echo", func(req *Request) *Response {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return &Response{
+				Success: false,
+				Error:   &Error{Code: "PARSE_ERROR", Message: err.Error()},
+			}
+		}
+		return &Response{Success: true, Data: payload}
+	})
+
+	cs := NewControlServer(bridge, log, nil)
+	cs.SetToken(testControlToken)
+	return cs, bridge
+}
+
+func doControlRequest(cs *ControlServer, method, path, token, body string) (*httptest.ResponseRecorder, *Response) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("X-Request-ID", "req-1")
+
+	rec := httptest.NewRecorder()
+	cs.ServeHTTP(rec, req)
+
+	var resp Response
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, &resp
+}
+
+func TestControlServer_Dispatch(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	rec, resp := doControlRequest(cs, http.MethodPost, "/api/v1/test:echo", testControlToken, `{"name":"value"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got error: %v", resp.Error)
+	}
+	if resp.RequestID != "req-1" {
+		t.Errorf("RequestID = %q, want %q", resp.RequestID, "req-1")
+	}
+
+	data, ok := resp.Data.(map[string]interface{})
+	if !ok || data["name"] != "value" {
+		t.Errorf("unexpected data: %v", resp.Data)
+	}
+}
+
+func TestControlServer_Unauthorized(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"missing token", ""},
+		{"wrong token", "wrong"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := doControlRequest(cs, http.MethodPost, "/api/v1/test:echo", tt.token, `{}`)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if resp.Error == nil || resp.Error.Code != "UNAUTHORIZED" {
+				t.Errorf("expected UNAUTHORIZED error, got %v", resp.Error)
+			}
+		})
+	}
+}
+
+func TestControlServer_UnknownEvent(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	rec, resp := doControlRequest(cs, http.MethodPost, "/api/v1/test:missing", testControlToken, "")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if resp.Error == nil || resp.Error.Code != "HANDLER_NOT_FOUND" {
+		t.Errorf("expected HANDLER_NOT_FOUND error, got %v", resp.Error)
+	}
+}
+
+func TestControlServer_MethodNotAllowed(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	rec, _ := doControlRequest(cs, http.MethodGet, "/api/v1/test:echo", testControlToken, "")
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestControlServer_Events(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	rec, resp := doControlRequest(cs, http.MethodGet, "/api/v1/events", testControlToken, "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	events, ok := resp.Data.([]interface{})
+	if !ok || len(events) != 1 || events[0] != "test:echo" {
+		t.Errorf("unexpected events: %v", resp.Data)
+	}
+}
+
+func TestControlServer_Middleware(t *testing.T) {
+	cs, bridge := newTestControlServer(t)
+
+	called := false
+	bridge.Use(func(event string, req *Request, next func() *Response) *Response {
+		called = true
+		return &Response{Success: false, Error: &Error{Code: "BLOCKED", Message: "blocked"}}
+	})
+
+	_, resp := doControlRequest(cs, http.MethodPost, "/api/v1/test:echo", testControlToken, `{}`)
+
+	if !called {
+		t.Error("middleware should be called for control API requests")
+	}
+	if resp.Error == nil || resp.Error.Code != "BLOCKED" {
+		t.Errorf("expected BLOCKED error, got %v", resp.Error)
+	}
+}
+
+func TestControlServer_StartStop(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	if err := cs.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !cs.IsRunning() {
+		t.Error("server should be running")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+cs.Address()+"/api/v1/test:echo", strings.NewReader(`{"a":1}`))
+	req.Header.Set("Authorization", "Bearer "+testControlToken)
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", httpResp.StatusCode, http.StatusOK)
+	}
+
+	if err := cs.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if cs.IsRunning() {
+		t.Error("server should not be running")
+	}
+}
+
+func TestControlServer_StartRequiresToken(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+	cs.SetToken("")
+
+	if err := cs.Start("127.0.0.1:0"); err != ErrControlNoToken {
+		t.Errorf("Start error = %v, want %v", err, ErrControlNoToken)
+	}
+}
+
+func TestControlServer_ApplyDisabled(t *testing.T) {
+	cs, _ := newTestControlServer(t)
+
+	if err := cs.Apply(config.ControlSettings{Enabled: true, ListenAddress: "127.0.0.1:0"}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if err := cs.Apply(config.ControlSettings{Enabled: false}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if cs.IsRunning() {
+		t.Error("server should be stopped after disabling")
+	}
+}
+
+func TestValidateLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"127.0.0.1:9001", false},
+		{"[::1]:9001", false},
+		{"localhost:9001", false},
+		{"0.0.0.0:9001", true},
+		{"192.168.1.1:9001", true},
+		{":9001", true},
+		{"invalid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := validateLoopbackAddress(tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateLoopbackAddress(%q) error = %v, wantErr %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package ipc
 
-import "time"
+import (
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
+)
 
 // Event names for IPC communication
 const (
@@ -29,13 +33,35 @@ const (
 	EventPeerConnected    = "peer:connected"
 	EventPeerDisconnected = "peer:disconnected"
 
+	// Peer health events
+	EventPeersHealth   = "peers:health"
+	EventPeersFailover = "peers:failover"
+
+	// Public peer directory event
+	EventPeersDiscover = "peers:discover"
+
 	// Session events
-	EventSessionsList = "sessions:list"
+	EventSessionsList  = "sessions:list"
 	EventSessionsStats = "sessions:stats"
 
 	// Configuration events
-	EventConfigLoad = "config:load"
-	EventConfigSave = "config:save"
+	EventConfigLoad   = "config:load"
+	EventConfigSave   = "config:save"
+	EventConfigImport = "config:import"
+	EventConfigExport = "config:export"
+
+	// Identity events
+	EventIdentityExport = "identity:export"
+	EventIdentityImport = "identity:import"
+
+	// Key generation events
+	EventKeygenStart  = "keygen:start"
+	EventKeygenStop   = "keygen:stop"
+	EventKeygenStatus = "keygen:status"
+	EventKeygenAdopt  = "keygen:adopt"
+
+	// Push event for key search progress
+	EventKeygenProgress = "keygen:progress"
 
 	// Settings events
 	EventSettingsGet = "settings:get"
@@ -47,13 +73,35 @@ const (
 	EventProxyStart  = "proxy:start"
 	EventProxyStop   = "proxy:stop"
 
+	EventProxyClients = "proxy:clients"
+	EventProxyRouting = "proxy:routing"
+
 	// Mapping events
-	EventMappingList   = "mapping:list"
-	EventMappingAdd    = "mapping:add"
-	EventMappingRemove = "mapping:remove"
-	EventMappingEnable = "mapping:enable"
+	EventMappingList    = "mapping:list"
+	EventMappingAdd     = "mapping:add"
+	EventMappingRemove  = "mapping:remove"
+	EventMappingEnable  = "mapping:enable"
 	EventMappingDisable = "mapping:disable"
 
+	// Profile events
+	EventProfileList   = "profile:list"
+	EventProfileCreate = "profile:create"
+	EventProfileClone  = "profile:clone"
+	EventProfileRename = "profile:rename"
+	EventProfileDelete = "profile:delete"
+	EventProfileSwitch = "profile:switch"
+
+	// Push event for profiles
+	EventProfileChanged = "profile:changed"
+
+	// Node registry events
+	EventNodesList   = "nodes:list"
+	EventNodesAdd    = "nodes:add"
+	EventNodesRemove = "nodes:remove"
+
+	// Push event for the node registry
+	EventNodesChanged = "nodes:changed"
+
 	// State synchronization events
 	EventStateChanged = "state:changed"
 	EventStateSync    = "state:sync"
@@ -66,7 +114,11 @@ const (
 	EventLogClear = "log:clear"
 
 	// Stats events
-	EventStatsUpdate = "stats:update"
+	EventStatsUpdate  = "stats:update"
+	EventStatsHistory = "stats:history"
+
+	// Control API events
+	EventControlStatus = "control:status"
 )
 
 // NodeStatus represents the current node status
@@ -82,6 +134,7 @@ type NodeStatus struct {
 
 // NodeStateChange represents a node state change event
 type NodeStateChange struct {
+	NodeID        string      `json:"nodeId,omitempty"` // Empty for the primary node
 	PreviousState string      `json:"previousState"`
 	CurrentState  string      `json:"currentState"`
 	NodeInfo      *NodeStatus `json:"nodeInfo,omitempty"`
@@ -104,11 +157,18 @@ type PeerInfo struct {
 
 // PeerEvent represents a peer connection/disconnection event
 type PeerEvent struct {
-	Type      string    `json:"type"` // "connected", "disconnected"
+	NodeID    string    `json:"nodeId,omitempty"` // Empty for the primary node
+	Type      string    `json:"type"`             // "connected", "disconnected"
 	Peer      *PeerInfo `json:"peer"`
 	Timestamp int64     `json:"timestamp"`
 }
 
+// PeersUpdate carries the connected peers of a node after a peer change
+type PeersUpdate struct {
+	NodeID string      `json:"nodeId,omitempty"` // Empty for the primary node
+	Peers  []*PeerInfo `json:"peers"`
+}
+
 // SessionInfo represents information about a session
 type SessionInfo struct {
 	Address   string `json:"address"`
@@ -133,6 +193,19 @@ type ProxyConfig struct {
 	Enabled       bool   `json:"enabled"`
 	ListenAddress string `json:"listenAddress"`
 	Nameserver    string `json:"nameserver,omitempty"`
+
+	// Client addresses or CIDRs allowed to connect. Omitted keeps the saved
+	// list, an empty list allows all clients.
+	AllowedClients []string `json:"allowedClients"`
+
+	// HTTP proxy listener sharing the SOCKS dialer; empty disables it
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"`
+
+	// Auth false removes the saved credentials. A username and password
+	// replace them; both are kept in secure storage.
+	Auth     *bool  `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
 }
 
 // ProxyStatus represents SOCKS5 proxy status
@@ -143,6 +216,10 @@ type ProxyStatus struct {
 	TotalConnections  int64  `json:"totalConnections"`
 	BytesIn           int64  `json:"bytesIn"`
 	BytesOut          int64  `json:"bytesOut"`
+	AuthEnabled       bool   `json:"authEnabled"`
+	AuthFailures      int64  `json:"authFailures"`
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"`
+	UDPAssociations   int64  `json:"udpAssociations"`
 }
 
 // PortMapping represents a port forwarding mapping
@@ -155,6 +232,22 @@ type PortMapping struct {
 	Active   bool   `json:"active"`
 	BytesIn  int64  `json:"bytesIn,omitempty"`
 	BytesOut int64  `json:"bytesOut,omitempty"`
+
+	UploadLimit    int64 `json:"uploadLimit,omitempty"`   // Bytes per second, 0 = unlimited
+	DownloadLimit  int64 `json:"downloadLimit,omitempty"` // Bytes per second, 0 = unlimited
+	MaxConnections int   `json:"maxConnections,omitempty"`
+	MaxSessions    int   `json:"maxSessions,omitempty"`
+	UDPIdleTimeout int   `json:"udpIdleTimeout,omitempty"` // Seconds
+
+	Allow []string `json:"allow,omitempty"` // Remote mappings only
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// SessionStats represents aggregate session statistics
+type SessionStats struct {
+	Total        int   `json:"total"`
+	TotalRxBytes int64 `json:"totalRxBytes"`
+	TotalTxBytes int64 `json:"totalTxBytes"`
 }
 
 // LogEntry represents a log entry
@@ -167,6 +260,7 @@ type LogEntry struct {
 
 // NetworkStats represents network statistics
 type NetworkStats struct {
+	NodeID         string  `json:"nodeId,omitempty"` // Empty for the primary node
 	TotalRxBytes   int64   `json:"totalRxBytes"`
 	TotalTxBytes   int64   `json:"totalTxBytes"`
 	RxBytesPerSec  float64 `json:"rxBytesPerSec"`
@@ -211,6 +305,16 @@ type AddMappingRequest struct {
 	Source  string `json:"source"`
 	Target  string `json:"target"`
 	Enabled bool   `json:"enabled"`
+
+	UploadLimit    int64 `json:"uploadLimit,omitempty"`    // Bytes per second, client to target
+	DownloadLimit  int64 `json:"downloadLimit,omitempty"`  // Bytes per second, target to client
+	MaxConnections int   `json:"maxConnections,omitempty"` // TCP mappings
+	MaxSessions    int   `json:"maxSessions,omitempty"`    // UDP mappings
+	UDPIdleTimeout int   `json:"udpIdleTimeout,omitempty"` // Seconds, UDP mappings
+
+	// Remote mappings only: public keys, addresses or subnets such as 300::/64
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
 }
 
 // RemoveMappingRequest is the payload for removing a port mapping
@@ -218,6 +322,171 @@ type RemoveMappingRequest struct {
 	ID string `json:"id"`
 }
 
+// ToggleMappingRequest is the payload for enabling or disabling a port mapping
+type ToggleMappingRequest struct {
+	ID string `json:"id"`
+}
+
+// StartProxyRequest is the payload for starting the proxy.
+// Empty fields are taken from saved settings.
+type StartProxyRequest struct {
+	ListenAddress     string `json:"listenAddress,omitempty"`
+	Nameserver        string `json:"nameserver,omitempty"`
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"`
+}
+
+// ImportConfigRequest is the payload for importing a yggdrasil-go or yggstack config.
+// Either Path or Content must be set; Args holds yggstack command-line flags.
+type ImportConfigRequest struct {
+	Path    string `json:"path,omitempty"`
+	Content string `json:"content,omitempty"`
+	Args    string `json:"args,omitempty"`
+}
+
+// ImportConfigResult describes an imported config
+type ImportConfigResult struct {
+	PublicKey       string `json:"publicKey"`
+	IPv6Address     string `json:"ipv6Address,omitempty"`
+	Peers           int    `json:"peers"`
+	Mappings        int    `json:"mappings"`
+	Proxy           bool   `json:"proxy"`
+	RestartRequired bool   `json:"restartRequired"`
+}
+
+// ExportConfigRequest is the payload for exporting the config.
+// The file is written to Path if set, otherwise only returned.
+type ExportConfigRequest struct {
+	Path   string `json:"path,omitempty"`
+	Format string `json:"format,omitempty"` // "hjson" (default) or "json"
+}
+
+// ExportConfigResult contains an exported config
+type ExportConfigResult struct {
+	Content string `json:"content"`
+	Args    string `json:"args"` // yggstack flags for proxy and mappings
+	Format  string `json:"format"`
+	Path    string `json:"path,omitempty"`
+}
+
+// DiscoverPeersRequest is the payload for ranking peers from a public peer
+// list. Empty fields use the directory settings.
+type DiscoverPeersRequest struct {
+	Source  string   `json:"source,omitempty"`  // URL or local file
+	Regions []string `json:"regions,omitempty"` // Preferred regions
+	Limit   int      `json:"limit,omitempty"`   // Maximum number of peers returned
+}
+
+// DiscoverPeersResult contains ranked peers from a public peer list
+type DiscoverPeersResult struct {
+	Source string                    `json:"source"`
+	Total  int                       `json:"total"`
+	Peers  []yggdrasil.DirectoryPeer `json:"peers"`
+}
+
+// StatsHistoryRequest is the payload for querying the traffic history
+type StatsHistoryRequest struct {
+	Series     []string `json:"series,omitempty"`     // e.g. "peers", "proxy", "mapping/web"; all if empty
+	Resolution string   `json:"resolution,omitempty"` // "minute" (24h) or "hour" (30d)
+	From       int64    `json:"from,omitempty"`       // unix milliseconds
+	To         int64    `json:"to,omitempty"`         // unix milliseconds
+	Format     string   `json:"format,omitempty"`     // "json" or "csv"
+}
+
+// StatsHistoryResult is the traffic history of a node
+type StatsHistoryResult struct {
+	Node       string                    `json:"node"`
+	Resolution string                    `json:"resolution"`
+	Series     []yggdrasil.TrafficSeries `json:"series,omitempty"`
+	Content    string                    `json:"content,omitempty"` // CSV when requested
+}
+
+// PeerFailoverEvent reports a peer added or removed by the peer supervisor
+// of a node. The primary node has an empty node ID.
+type PeerFailoverEvent struct {
+	NodeID string `json:"nodeId,omitempty"`
+	yggdrasil.FailoverEvent
+}
+
+// ExportIdentityRequest is the payload for exporting a passphrase-protected
+// identity bundle. Peers and mappings are only included when requested.
+type ExportIdentityRequest struct {
+	Passphrase string `json:"passphrase"`
+	Path       string `json:"path,omitempty"`
+	Peers      bool   `json:"peers,omitempty"`
+	Mappings   bool   `json:"mappings,omitempty"`
+}
+
+// ExportIdentityResult contains an exported identity bundle
+type ExportIdentityResult struct {
+	Content     string `json:"content"`
+	PublicKey   string `json:"publicKey"`
+	IPv6Address string `json:"ipv6Address"`
+	Path        string `json:"path,omitempty"`
+}
+
+// ImportIdentityRequest is the payload for importing an identity bundle.
+// Either Path or Content must be set.
+type ImportIdentityRequest struct {
+	Passphrase string `json:"passphrase"`
+	Path       string `json:"path,omitempty"`
+	Content    string `json:"content,omitempty"`
+}
+
+// ImportIdentityResult describes an imported identity bundle
+type ImportIdentityResult struct {
+	PublicKey       string `json:"publicKey"`
+	IPv6Address     string `json:"ipv6Address"`
+	Peers           int    `json:"peers"`
+	Mappings        int    `json:"mappings"`
+	RestartRequired bool   `json:"restartRequired"`
+}
+
+// ProfileRequest is the payload for creating, deleting or switching a profile
+type ProfileRequest struct {
+	Name string `json:"name"`
+}
+
+// CloneProfileRequest is the payload for cloning a profile.
+// Source defaults to the active profile.
+type CloneProfileRequest struct {
+	Source      string `json:"source,omitempty"`
+	Name        string `json:"name"`
+	NewIdentity bool   `json:"newIdentity"` // Generate new keys instead of copying them
+}
+
+// RenameProfileRequest is the payload for renaming a profile
+type RenameProfileRequest struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+}
+
+// ProfileList describes the node profiles
+type ProfileList struct {
+	Active   string                  `json:"active"`
+	Profiles []yggdrasil.ProfileInfo `json:"profiles"`
+}
+
+// NodeRequest is the payload for adding or removing a node.
+// The ID is the name of the profile the node runs.
+type NodeRequest struct {
+	ID string `json:"id"`
+}
+
+// NodeEntry describes a node in the registry
+type NodeEntry struct {
+	ID          string `json:"id"`
+	Primary     bool   `json:"primary"` // The node that runs the active profile
+	State       string `json:"state"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	Proxy       string `json:"proxy,omitempty"` // SOCKS listen address while running
+}
+
+// NodeList describes all nodes, the primary node first
+type NodeList struct {
+	Nodes []NodeEntry `json:"nodes"`
+}
+
 // SetSettingsRequest is the payload for updating settings
 type SetSettingsRequest struct {
 	Settings AppSettings `json:"settings"`
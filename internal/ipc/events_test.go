@@ -28,6 +28,8 @@ func TestEventConstants(t *testing.T) {
 		EventSessionsStats,
 		EventConfigLoad,
 		EventConfigSave,
+		EventConfigImport,
+		EventConfigExport,
 		EventSettingsGet,
 		EventSettingsSet,
 		EventProxyConfig,
@@ -45,6 +47,7 @@ func TestEventConstants(t *testing.T) {
 		EventLogEntry,
 		EventLogLevel,
 		EventStatsUpdate,
+		EventControlStatus,
 	}
 
 	seen := make(map[string]bool)
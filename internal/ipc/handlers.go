@@ -1,15 +1,28 @@
 package ipc
 
 import (
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
 	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
 	"github.com/JB-SelfCompany/yggstack-gui/internal/platform"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
 	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
 )
 
+// peerDiscoverTimeout bounds peers:discover so it finishes before the
+// bridge gives up on the handler (30s by default)
+const peerDiscoverTimeout = 25 * time.Second
+
 // Handlers contains all IPC handlers with Yggdrasil integration
 type Handlers struct {
 	service        *yggdrasil.Service
@@ -17,23 +30,43 @@ type Handlers struct {
 	sessionManager *yggdrasil.SessionManager
 	socksProxy     *yggdrasil.SOCKSProxy
 	mappingManager *yggdrasil.MappingManager
+	primary        *yggdrasil.Node         // Runs the active profile, shares the fields above
+	nodes          *yggdrasil.NodeRegistry // Additional nodes keyed by profile name
+	profiles       *yggdrasil.ProfileManager
+	profileMu      sync.Mutex // Serializes profile changes
+	keySearch      *yggdrasil.KeySearch
+	history        *yggdrasil.TrafficHistory
 	configStore    *config.Store
+	controlServer  *ControlServer
+	metricsServer  *MetricsServer
+	migration      *config.MigrationReport
 	logger         *logger.Logger
+	auditLogger    *logger.AuditLogger
+	secrets        yggdrasil.KeyStore
 	bridge         *Bridge
 }
 
 // NewHandlers creates handlers with Yggdrasil service integration
 func NewHandlers(log *logger.Logger) *Handlers {
 	service := yggdrasil.NewService(log)
+	primary := yggdrasil.NewNode(service, log)
 
-	return &Handlers{
+	h := &Handlers{
 		service:        service,
-		peerManager:    yggdrasil.NewPeerManager(service),
-		sessionManager: yggdrasil.NewSessionManager(service),
-		socksProxy:     yggdrasil.NewSOCKSProxy(service, log),
-		mappingManager: yggdrasil.NewMappingManager(service, log),
+		peerManager:    primary.Peers,
+		sessionManager: primary.Sessions,
+		socksProxy:     primary.SOCKS,
+		mappingManager: primary.Mappings,
+		primary:        primary,
+		nodes:          yggdrasil.NewNodeRegistry(),
+		profiles:       yggdrasil.NewProfileManager(service.ConfigManager(), log),
+		keySearch:      yggdrasil.NewKeySearch(log),
 		logger:         log,
 	}
+	h.configureMappingAudit("", primary)
+	h.configureProxyAudit("", primary)
+
+	return h
 }
 
 // configManager returns the config manager from service
@@ -41,6 +74,28 @@ func (h *Handlers) configManager() *yggdrasil.ConfigManager {
 	return h.service.ConfigManager()
 }
 
+// nodeFor returns the node a request is addressed to and the profile it
+// runs. An empty node ID or the active profile selects the primary node.
+func (h *Handlers) nodeFor(req *Request) (*yggdrasil.Node, string, *Response) {
+	active := h.profiles.Active()
+	if req == nil || req.NodeID == "" || req.NodeID == active {
+		return h.primary, active, nil
+	}
+
+	n, ok := h.nodes.Get(req.NodeID)
+	if !ok {
+		return nil, "", &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "NODE_NOT_FOUND",
+				Message: "Node not found: " + req.NodeID,
+			},
+		}
+	}
+
+	return n, req.NodeID, nil
+}
+
 // GetService returns the Yggdrasil service
 func (h *Handlers) GetService() *yggdrasil.Service {
 	return h.service
@@ -51,11 +106,290 @@ func (h *Handlers) GetSOCKSProxy() *yggdrasil.SOCKSProxy {
 	return h.socksProxy
 }
 
+// GetMappingManager returns the port mapping manager
+func (h *Handlers) GetMappingManager() *yggdrasil.MappingManager {
+	return h.mappingManager
+}
+
+// GetProfileManager returns the node profile manager
+func (h *Handlers) GetProfileManager() *yggdrasil.ProfileManager {
+	return h.profiles
+}
+
 // SetConfigStore sets the config store for settings persistence
 func (h *Handlers) SetConfigStore(store *config.Store) {
 	h.configStore = store
 }
 
+// SetAuditLogger records peer connections, denied mapping connections and
+// failed SOCKS authentications in the audit log
+func (h *Handlers) SetAuditLogger(al *logger.AuditLogger) {
+	h.auditLogger = al
+}
+
+// SetTrafficHistory records the traffic of all nodes into history
+func (h *Handlers) SetTrafficHistory(history *yggdrasil.TrafficHistory) {
+	h.history = history
+	history.Start(h.sampleTraffic)
+}
+
+// StopTrafficHistory records the last traffic sample and saves the history
+func (h *Handlers) StopTrafficHistory() {
+	if h.history == nil {
+		return
+	}
+	h.sampleTraffic(time.Now())
+	h.history.Stop()
+}
+
+// sampleTraffic records the traffic of all nodes
+func (h *Handlers) sampleTraffic(now time.Time) {
+	h.history.SampleNodes(h.allNodes(), now)
+}
+
+// allNodes returns the primary and the additional nodes keyed by the
+// profile they run
+func (h *Handlers) allNodes() map[string]*yggdrasil.Node {
+	nodes := map[string]*yggdrasil.Node{h.profiles.Active(): h.primary}
+	for _, id := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(id); ok {
+			nodes[id] = n
+		}
+	}
+	return nodes
+}
+
+// SetKeyStore keeps node private keys and proxy credentials in secure
+// storage instead of the config files. Plaintext keys and upstream proxy
+// credentials of all profiles are moved there.
+func (h *Handlers) SetKeyStore(store *security.SecureStore) {
+	if store == nil {
+		h.logger.Warn("Secure storage unavailable, private keys stay in the config file")
+		return
+	}
+	h.secrets = store
+
+	if err := h.configManager().SetKeyStore(store); err != nil {
+		h.logger.Warn("Failed to load config from secure storage", "error", err)
+	}
+	h.profiles.MigrateKeys()
+	h.migrateUpstreamCredentials()
+}
+
+// LoadProfile activates the node profile saved in the config store.
+// A missing profile falls back to the default one.
+func (h *Handlers) LoadProfile() {
+	if h.configStore == nil {
+		return
+	}
+
+	name := h.configStore.ActiveProfile()
+	if name == config.DefaultProfile {
+		return
+	}
+
+	if err := h.profiles.Activate(name); err != nil {
+		h.logger.Warn("Failed to load profile, using default", "profile", name, "error", err)
+		h.configStore.SwitchProfile(config.DefaultProfile)
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save settings", "error", err)
+		}
+	}
+}
+
+// MigrateLegacyConfig moves SOCKS and mapping settings left in the
+// Yggdrasil config file into the config store. Values already in the
+// store win; conflicts are logged and reported by config:load.
+func (h *Handlers) MigrateLegacyConfig() {
+	if h.configStore == nil {
+		return
+	}
+
+	cm := h.configManager()
+	legacy := cm.LegacySettings()
+	if legacy == nil {
+		return
+	}
+
+	report := h.configStore.MergeLegacy(legacy)
+	h.migration = report
+
+	for _, c := range report.Conflicts {
+		h.logger.Warn("Conflicting setting in Yggdrasil config ignored",
+			"field", c.Field, "kept", c.Kept, "discarded", c.Discarded)
+	}
+
+	if report.Changed() {
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save migrated settings", "error", err)
+			return
+		}
+	}
+
+	// Drop the legacy sections only after settings are safely saved
+	cm.ClearLegacySettings()
+	if err := cm.Save(); err != nil {
+		h.logger.Warn("Failed to save Yggdrasil config after migration", "error", err)
+		return
+	}
+
+	h.logger.Info("Migrated legacy proxy and mapping settings",
+		"proxy", report.ProxyMigrated,
+		"mappings", report.MappingsAdded,
+		"conflicts", len(report.Conflicts))
+}
+
+// saveProxySettings writes the SOCKS proxy settings of a profile to the
+// config store. A config without address only updates the enabled flag.
+func (h *Handlers) saveProxySettings(profile string, cfg yggdrasil.SOCKSConfig) {
+	if h.configStore == nil {
+		return
+	}
+
+	h.configStore.UpdateProfile(profile, func(p *config.ProfileSettings) {
+		p.Proxy.Enabled = cfg.Enabled
+		if cfg.ListenAddress != "" {
+			p.Proxy.ListenAddress = cfg.ListenAddress
+			p.Proxy.Nameserver = cfg.Nameserver
+			p.Proxy.AllowedClients = cfg.AllowedClients
+			p.Proxy.HTTPListenAddress = cfg.HTTPListenAddress
+		}
+	})
+
+	if err := h.configStore.Save(); err != nil {
+		h.logger.Warn("Failed to save proxy settings", "error", err)
+	}
+}
+
+// LoadMappings registers port mappings saved in the config store.
+// Enabled mappings are started once the node is running.
+func (h *Handlers) LoadMappings() {
+	h.loadMappings(h.primary, h.profiles.Active())
+}
+
+// loadMappings registers the saved port mappings of a profile with a node
+func (h *Handlers) loadMappings(n *yggdrasil.Node, profile string) {
+	if h.configStore == nil {
+		return
+	}
+
+	h.addMappings(n.Mappings, h.configStore.Profile(profile).Mappings)
+}
+
+// addMappings registers mappings with a mapping manager and returns the
+// number added. Mappings without ID get a new one.
+func (h *Handlers) addMappings(mm *yggdrasil.MappingManager, mappings config.MappingsSettings) int {
+	groups := []struct {
+		mappingType yggdrasil.MappingType
+		mappings    []config.PortMapping
+	}{
+		{yggdrasil.MappingLocalTCP, mappings.LocalTCP},
+		{yggdrasil.MappingRemoteTCP, mappings.RemoteTCP},
+		{yggdrasil.MappingLocalUDP, mappings.LocalUDP},
+		{yggdrasil.MappingRemoteUDP, mappings.RemoteUDP},
+	}
+
+	added := 0
+	for _, group := range groups {
+		for _, m := range group.mappings {
+			if m.ID == "" {
+				m.ID = newMappingID()
+			}
+			err := mm.AddMapping(yggdrasil.PortMapping{
+				ID:             m.ID,
+				Type:           group.mappingType,
+				Source:         m.Source,
+				Target:         m.Target,
+				Enabled:        m.Enabled,
+				UploadLimit:    m.UploadLimit,
+				DownloadLimit:  m.DownloadLimit,
+				MaxConnections: m.MaxConnections,
+				MaxSessions:    m.MaxSessions,
+				UDPIdleTimeout: m.UDPIdleTimeout,
+				Allow:          m.Allow,
+				Deny:           m.Deny,
+			})
+			if err != nil {
+				h.logger.Warn("Failed to add mapping", "id", m.ID, "error", err)
+				continue
+			}
+			added++
+		}
+	}
+
+	return added
+}
+
+// saveMappings writes the current port mappings of a node to the config
+// store, under the profile the node runs
+func (h *Handlers) saveMappings(n *yggdrasil.Node, profile string) {
+	if h.configStore == nil {
+		return
+	}
+
+	mappings := n.Mappings.GetMappings()
+
+	saved := config.MappingsSettings{
+		LocalTCP:  []config.PortMapping{},
+		RemoteTCP: []config.PortMapping{},
+		LocalUDP:  []config.PortMapping{},
+		RemoteUDP: []config.PortMapping{},
+	}
+
+	for _, m := range mappings {
+		pm := config.PortMapping{
+			ID:             m.ID,
+			Source:         m.Source,
+			Target:         m.Target,
+			Enabled:        m.Enabled,
+			UploadLimit:    m.UploadLimit,
+			DownloadLimit:  m.DownloadLimit,
+			MaxConnections: m.MaxConnections,
+			MaxSessions:    m.MaxSessions,
+			UDPIdleTimeout: m.UDPIdleTimeout,
+			Allow:          m.Allow,
+			Deny:           m.Deny,
+		}
+		switch m.Type {
+		case yggdrasil.MappingLocalTCP:
+			saved.LocalTCP = append(saved.LocalTCP, pm)
+		case yggdrasil.MappingRemoteTCP:
+			saved.RemoteTCP = append(saved.RemoteTCP, pm)
+		case yggdrasil.MappingLocalUDP:
+			saved.LocalUDP = append(saved.LocalUDP, pm)
+		case yggdrasil.MappingRemoteUDP:
+			saved.RemoteUDP = append(saved.RemoteUDP, pm)
+		}
+	}
+
+	h.configStore.UpdateProfile(profile, func(p *config.ProfileSettings) {
+		p.Mappings = saved
+	})
+
+	if err := h.configStore.Save(); err != nil {
+		h.logger.Warn("Failed to save mappings", "error", err)
+	}
+}
+
+// newMappingID generates a mapping ID that stays unique across restarts
+func newMappingID() string {
+	b, err := security.GenerateRandomBytes(4)
+	if err != nil {
+		return fmt.Sprintf("mapping-%d", time.Now().UnixNano())
+	}
+	return "mapping-" + hex.EncodeToString(b)
+}
+
+// SetControlServer sets the control API server managed by settings
+func (h *Handlers) SetControlServer(server *ControlServer) {
+	h.controlServer = server
+}
+
+// SetMetricsServer sets the Prometheus metrics server managed by settings
+func (h *Handlers) SetMetricsServer(server *MetricsServer) {
+	h.metricsServer = server
+}
+
 // RegisterAll registers all handlers with the bridge
 func (h *Handlers) RegisterAll(bridge *Bridge) {
 	h.bridge = bridge
@@ -67,12 +401,26 @@ func (h *Handlers) RegisterAll(bridge *Bridge) {
 
 	// Peer management
 	bridge.Register(EventPeersList, h.handlePeersList)
+	bridge.Register(EventPeersHealth, h.handlePeersHealth)
+	bridge.Register(EventPeersDiscover, h.handlePeersDiscover)
 	bridge.Register(EventPeersAdd, h.handlePeersAdd)
 	bridge.Register(EventPeersRemove, h.handlePeersRemove)
 
 	// Configuration
 	bridge.Register(EventConfigLoad, h.handleConfigLoad)
 	bridge.Register(EventConfigSave, h.handleConfigSave)
+	bridge.Register(EventConfigImport, h.handleConfigImport)
+	bridge.Register(EventConfigExport, h.handleConfigExport)
+
+	// Identity handlers
+	bridge.Register(EventIdentityExport, h.handleIdentityExport)
+	bridge.Register(EventIdentityImport, h.handleIdentityImport)
+
+	// Key generation handlers
+	bridge.Register(EventKeygenStart, h.handleKeygenStart)
+	bridge.Register(EventKeygenStop, h.handleKeygenStop)
+	bridge.Register(EventKeygenStatus, h.handleKeygenStatus)
+	bridge.Register(EventKeygenAdopt, h.handleKeygenAdopt)
 
 	// Settings
 	bridge.Register(EventSettingsGet, h.handleSettingsGet)
@@ -81,15 +429,45 @@ func (h *Handlers) RegisterAll(bridge *Bridge) {
 	// Proxy
 	bridge.Register(EventProxyConfig, h.handleProxyConfig)
 	bridge.Register(EventProxyStatus, h.handleProxyStatus)
+	bridge.Register(EventProxyStart, h.handleProxyStart)
+	bridge.Register(EventProxyStop, h.handleProxyStop)
+	bridge.Register(EventProxyClients, h.handleProxyClients)
+	bridge.Register(EventProxyRouting, h.handleProxyRouting)
 
 	// Mappings
 	bridge.Register(EventMappingAdd, h.handleMappingAdd)
 	bridge.Register(EventMappingRemove, h.handleMappingRemove)
+	bridge.Register(EventMappingList, h.handleMappingList)
+	bridge.Register(EventMappingEnable, h.handleMappingEnable)
+	bridge.Register(EventMappingDisable, h.handleMappingDisable)
+
+	// Profiles
+	bridge.Register(EventProfileList, h.handleProfileList)
+	bridge.Register(EventProfileCreate, h.handleProfileCreate)
+	bridge.Register(EventProfileClone, h.handleProfileClone)
+	bridge.Register(EventProfileRename, h.handleProfileRename)
+	bridge.Register(EventProfileDelete, h.handleProfileDelete)
+	bridge.Register(EventProfileSwitch, h.handleProfileSwitch)
+
+	// Node registry
+	bridge.Register(EventNodesList, h.handleNodesList)
+	bridge.Register(EventNodesAdd, h.handleNodesAdd)
+	bridge.Register(EventNodesRemove, h.handleNodesRemove)
+
+	// Sessions
+	bridge.Register(EventSessionsList, h.handleSessionsList)
+	bridge.Register(EventSessionsStats, h.handleSessionsStats)
+
+	// Traffic history
+	bridge.Register(EventStatsHistory, h.handleStatsHistory)
 
 	// Logs
 	bridge.Register(EventLogList, h.handleLogList)
 	bridge.Register(EventLogClear, h.handleLogClear)
 
+	// Control API
+	bridge.Register(EventControlStatus, h.handleControlStatus)
+
 	// Subscribe to service state changes to notify frontend
 	h.setupStateChangeNotifier()
 
@@ -98,11 +476,22 @@ func (h *Handlers) RegisterAll(bridge *Bridge) {
 
 // setupStateChangeNotifier subscribes to service state changes and emits events to frontend
 func (h *Handlers) setupStateChangeNotifier() {
-	h.service.AddStateListener(func(state yggdrasil.ServiceState, info *yggdrasil.NodeInfo) {
-		h.logger.Debug("Service state changed, notifying frontend", "state", state.String())
+	h.notifyStateChanges("", h.service)
+}
+
+// notifyStateChanges emits state changes of a node service to the frontend.
+// The primary node has an empty node ID.
+func (h *Handlers) notifyStateChanges(nodeID string, service *yggdrasil.Service) {
+	service.AddStateListener(func(state yggdrasil.ServiceState, info *yggdrasil.NodeInfo) {
+		if h.bridge == nil {
+			return
+		}
+
+		h.logger.Debug("Service state changed, notifying frontend", "node", nodeID, "state", state.String())
 
 		// Build state change event
 		data := &NodeStateChange{
+			NodeID:       nodeID,
 			CurrentState: state.String(),
 			Timestamp:    time.Now().UnixMilli(),
 		}
@@ -124,10 +513,15 @@ func (h *Handlers) setupStateChangeNotifier() {
 // Node handlers
 
 func (h *Handlers) handleNodeStart(req *Request) *Response {
-	h.logger.Info("Starting Yggdrasil node")
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	h.logger.Info("Starting Yggdrasil node", "profile", profile)
 
 	// Load or generate configuration
-	if err := h.configManager().Load(); err != nil {
+	if err := n.Config().Load(); err != nil {
 		h.logger.Error("Failed to load configuration", "error", err)
 		return &Response{
 			Success: false,
@@ -138,10 +532,20 @@ func (h *Handlers) handleNodeStart(req *Request) *Response {
 		}
 	}
 
-	cfg := h.configManager().GetConfig()
+	cfg := n.Config().GetConfig()
+
+	if err := h.checkIdentity(profile, cfg.PublicKey); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "IDENTITY_CONFLICT",
+				Message: err.Error(),
+			},
+		}
+	}
 
 	// Start the service
-	if err := h.service.Start(cfg); err != nil {
+	if err := n.Service.Start(cfg); err != nil {
 		h.logger.Error("Failed to start node", "error", err)
 		return &Response{
 			Success: false,
@@ -152,26 +556,12 @@ func (h *Handlers) handleNodeStart(req *Request) *Response {
 		}
 	}
 
-	// Auto-start SOCKS proxy with settings from config store
-	if h.configStore != nil {
-		appSettings := h.configStore.Get()
-		socksConfig := yggdrasil.SOCKSConfig{
-			Enabled:       true, // Always start proxy when node starts
-			ListenAddress: appSettings.Proxy.ListenAddress,
-			Nameserver:    appSettings.Proxy.Nameserver,
-		}
-		h.logger.Info("Starting SOCKS proxy", "address", socksConfig.ListenAddress)
-		if err := h.socksProxy.Start(socksConfig); err != nil {
-			h.logger.Warn("Failed to start SOCKS proxy", "error", err)
-		} else {
-			h.logger.Info("SOCKS proxy started", "address", socksConfig.ListenAddress)
-		}
-	}
+	h.startNodeProxy(n, profile)
 
 	// Return node info
-	info := h.service.GetNodeInfo()
+	info := n.Service.GetNodeInfo()
 	data := map[string]interface{}{
-		"state": h.service.GetState().String(),
+		"state": n.Service.GetState().String(),
 	}
 
 	if info != nil {
@@ -187,17 +577,22 @@ func (h *Handlers) handleNodeStart(req *Request) *Response {
 }
 
 func (h *Handlers) handleNodeStop(req *Request) *Response {
-	h.logger.Info("Stopping Yggdrasil node")
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	h.logger.Info("Stopping Yggdrasil node", "profile", profile)
 
 	// Stop SOCKS proxy first if running
-	if h.socksProxy.IsRunning() {
+	if n.SOCKS.IsRunning() {
 		h.logger.Info("Stopping SOCKS proxy")
-		if err := h.socksProxy.Stop(); err != nil {
+		if err := n.SOCKS.Stop(); err != nil {
 			h.logger.Warn("Failed to stop SOCKS proxy", "error", err)
 		}
 	}
 
-	if err := h.service.Stop(); err != nil {
+	if err := n.Service.Stop(); err != nil {
 		h.logger.Error("Failed to stop node", "error", err)
 		return &Response{
 			Success: false,
@@ -211,14 +606,19 @@ func (h *Handlers) handleNodeStop(req *Request) *Response {
 	return &Response{
 		Success: true,
 		Data: map[string]interface{}{
-			"state": h.service.GetState().String(),
+			"state": n.Service.GetState().String(),
 		},
 	}
 }
 
 func (h *Handlers) handleNodeStatus(req *Request) *Response {
-	state := h.service.GetState()
-	info := h.service.GetNodeInfo()
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	state := n.Service.GetState()
+	info := n.Service.GetNodeInfo()
 
 	data := map[string]interface{}{
 		"state": state.String(),
@@ -231,8 +631,8 @@ func (h *Handlers) handleNodeStatus(req *Request) *Response {
 		data["uptime"] = info.Uptime.Seconds()
 
 		// Add stats: peerCount, sessionCount, traffic
-		peerStats := h.peerManager.GetPeerStats()
-		sessionStats := h.sessionManager.GetSessionStats()
+		peerStats := n.Peers.GetPeerStats()
+		sessionStats := n.Sessions.GetSessionStats()
 
 		data["stats"] = map[string]interface{}{
 			"peerCount":    peerStats.Total,
@@ -251,10 +651,15 @@ func (h *Handlers) handleNodeStatus(req *Request) *Response {
 // Peer handlers
 
 func (h *Handlers) handlePeersList(req *Request) *Response {
-	peers, err := h.peerManager.GetPeers()
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	peers, err := n.Peers.GetPeers()
 	if err != nil {
 		// If not running, return configured peers instead
-		configPeers := h.configManager().GetPeers()
+		configPeers := n.Config().GetPeers()
 		peerList := make([]map[string]interface{}, len(configPeers))
 		for i, uri := range configPeers {
 			peerList[i] = map[string]interface{}{
@@ -274,7 +679,107 @@ func (h *Handlers) handlePeersList(req *Request) *Response {
 	}
 }
 
+func (h *Handlers) handlePeersHealth(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	return &Response{
+		Success: true,
+		Data:    n.Supervisor.Health(),
+	}
+}
+
+func (h *Handlers) handlePeersDiscover(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload DiscoverPeersRequest
+	if len(req.Payload) > 0 {
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PARSE_ERROR",
+					Message: "Failed to parse discover request",
+				},
+			}
+		}
+	}
+
+	settings := config.DefaultSettings().Directory
+	if h.configStore != nil {
+		settings = h.configStore.Get().Directory
+	}
+	if payload.Source == "" {
+		payload.Source = settings.Source
+	}
+	if payload.Regions == nil {
+		payload.Regions = settings.Regions
+	}
+	// Only the configured source may name a local file, the control API
+	// must not be able to read arbitrary paths
+	if payload.Source != settings.Source {
+		u, err := url.Parse(payload.Source)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "VALIDATION_ERROR",
+					Message: "Peer list source must be an http(s) URL",
+				},
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), peerDiscoverTimeout)
+	defer cancel()
+
+	peers, err := yggdrasil.FetchPeerList(ctx, payload.Source)
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "DISCOVER_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, uri := range n.Config().GetPeers() {
+		configured[uri] = true
+	}
+
+	ranked := yggdrasil.RankPeers(yggdrasil.ProbePeers(ctx, peers), payload.Regions)
+	for i := range ranked {
+		ranked[i].Configured = configured[ranked[i].URI]
+	}
+	if payload.Limit > 0 && len(ranked) > payload.Limit {
+		ranked = ranked[:payload.Limit]
+	}
+
+	h.logger.Info("Public peers ranked", "source", payload.Source, "peers", len(peers))
+
+	return &Response{
+		Success: true,
+		Data: &DiscoverPeersResult{
+			Source: payload.Source,
+			Total:  len(peers),
+			Peers:  ranked,
+		},
+	}
+}
+
 func (h *Handlers) handlePeersAdd(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
 	var payload struct {
 		URI string `json:"uri"`
 	}
@@ -301,7 +806,7 @@ func (h *Handlers) handlePeersAdd(req *Request) *Response {
 	}
 
 	// Add to config
-	if err := h.configManager().AddPeer(payload.URI); err != nil {
+	if err := n.Config().AddPeer(payload.URI); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
@@ -312,14 +817,14 @@ func (h *Handlers) handlePeersAdd(req *Request) *Response {
 	}
 
 	// If running, add to live node
-	if h.service.IsRunning() {
-		if err := h.peerManager.AddPeer(payload.URI); err != nil {
+	if n.Service.IsRunning() {
+		if err := n.Peers.AddPeer(payload.URI); err != nil {
 			h.logger.Warn("Failed to add peer to running node", "uri", payload.URI, "error", err)
 		}
 	}
 
 	// Save config
-	if err := h.configManager().Save(); err != nil {
+	if err := n.Config().Save(); err != nil {
 		h.logger.Warn("Failed to save config", "error", err)
 	}
 
@@ -333,6 +838,11 @@ func (h *Handlers) handlePeersAdd(req *Request) *Response {
 }
 
 func (h *Handlers) handlePeersRemove(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
 	var payload struct {
 		URI string `json:"uri"`
 	}
@@ -348,7 +858,7 @@ func (h *Handlers) handlePeersRemove(req *Request) *Response {
 	}
 
 	// Remove from config
-	if err := h.configManager().RemovePeer(payload.URI); err != nil {
+	if err := n.Config().RemovePeer(payload.URI); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
@@ -359,14 +869,14 @@ func (h *Handlers) handlePeersRemove(req *Request) *Response {
 	}
 
 	// If running, remove from live node
-	if h.service.IsRunning() {
-		if err := h.peerManager.RemovePeer(payload.URI); err != nil {
+	if n.Service.IsRunning() {
+		if err := n.Peers.RemovePeer(payload.URI); err != nil {
 			h.logger.Warn("Failed to remove peer from running node", "uri", payload.URI, "error", err)
 		}
 	}
 
 	// Save config
-	if err := h.configManager().Save(); err != nil {
+	if err := n.Config().Save(); err != nil {
 		h.logger.Warn("Failed to save config", "error", err)
 	}
 
@@ -382,7 +892,12 @@ func (h *Handlers) handlePeersRemove(req *Request) *Response {
 // Config handlers
 
 func (h *Handlers) handleConfigLoad(req *Request) *Response {
-	if err := h.configManager().Load(); err != nil {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	if err := n.Config().Load(); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
@@ -392,20 +907,33 @@ func (h *Handlers) handleConfigLoad(req *Request) *Response {
 		}
 	}
 
-	cfg := h.configManager().GetConfig()
+	cfg := n.Config().GetConfig()
+
+	data := map[string]interface{}{
+		"path":             n.Config().GetPath(),
+		"profile":          profile,
+		"peers":            cfg.Peers,
+		"multicastEnabled": len(cfg.MulticastInterfaces) > 0,
+	}
+
+	// Report settings that could not be migrated from the Yggdrasil config
+	if n == h.primary && h.migration != nil && len(h.migration.Conflicts) > 0 {
+		data["conflicts"] = h.migration.Conflicts
+	}
 
 	return &Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"path":             h.configManager().GetPath(),
-			"peers":            cfg.Peers,
-			"multicastEnabled": len(cfg.MulticastInterfaces) > 0,
-		},
+		Data:    data,
 	}
 }
 
 func (h *Handlers) handleConfigSave(req *Request) *Response {
-	if err := h.configManager().Save(); err != nil {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	if err := n.Config().Save(); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
@@ -419,269 +947,2299 @@ func (h *Handlers) handleConfigSave(req *Request) *Response {
 		Success: true,
 		Data: map[string]interface{}{
 			"saved": true,
-			"path":  h.configManager().GetPath(),
+			"path":  n.Config().GetPath(),
 		},
 	}
 }
 
-// Settings handlers
+func (h *Handlers) handleConfigImport(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
 
-func (h *Handlers) handleSettingsGet(req *Request) *Response {
-	// Return default settings if config store not set
-	if h.configStore == nil {
+	var payload ImportConfigRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
 		return &Response{
-			Success: true,
-			Data: map[string]interface{}{
-				"language":       "en",
-				"theme":          "dark",
-				"minimizeToTray": true,
-				"startMinimized": false,
-				"autostart":      false,
-				"logLevel":       "info",
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse import request",
 			},
 		}
 	}
 
-	settings := h.configStore.Get()
+	if payload.Path == "" && payload.Content == "" && payload.Args == "" {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "VALIDATION_ERROR",
+				Message: "Config path, content or arguments are required",
+			},
+		}
+	}
 
-	// Get actual autostart state from system (registry/desktop file)
-	// This is important because user may have changed autostart outside the app
-	autostartEnabled, err := platform.IsAutoStartEnabled()
+	// Only a config file may point to a private key file next to it
+	var imported *yggdrasil.ImportedConfig
+	var err error
+	if payload.Path != "" {
+		imported, err = yggdrasil.ParseNodeConfigFile(payload.Path, payload.Args)
+	} else {
+		imported, err = yggdrasil.ParseNodeConfig([]byte(payload.Content), payload.Args)
+	}
 	if err != nil {
-		h.logger.Warn("Failed to check autostart status", "error", err)
-		autostartEnabled = settings.App.Autostart // Fallback to saved value
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "IMPORT_ERROR",
+				Message: err.Error(),
+			},
+		}
 	}
 
-	return &Response{
-		Success: true,
-		Data: map[string]interface{}{
-			"language":       settings.App.Language,
-			"theme":          settings.App.Theme,
-			"minimizeToTray": settings.App.MinimizeToTray,
-			"startMinimized": settings.App.StartMinimized,
-			"autostart":      autostartEnabled,
-			"logLevel":       settings.App.LogLevel,
-			"proxy": map[string]interface{}{
-				"enabled":       settings.Proxy.Enabled,
-				"listenAddress": settings.Proxy.ListenAddress,
+	// Node settings are only replaced when a config file was given
+	if payload.Path != "" || payload.Content != "" {
+		n.Config().Import(imported.Config)
+		if err := n.Config().Save(); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "SAVE_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	}
+
+	if imported.Proxy != nil {
+		h.saveProxySettings(profile, yggdrasil.SOCKSConfig{
+			Enabled:       imported.Proxy.Enabled,
+			ListenAddress: imported.Proxy.ListenAddress,
+			Nameserver:    imported.Proxy.Nameserver,
+		})
+	}
+
+	added := h.addMappings(n.Mappings, imported.Mappings)
+	if added > 0 {
+		h.saveMappings(n, profile)
+	}
+
+	info := n.Config().GetConfigInfo()
+	h.logger.Info("Configuration imported", "peers", len(info.Peers), "mappings", added)
+
+	return &Response{
+		Success: true,
+		Data: &ImportConfigResult{
+			PublicKey:       info.PublicKey,
+			IPv6Address:     info.IPv6Address,
+			Peers:           len(info.Peers),
+			Mappings:        added,
+			Proxy:           imported.Proxy != nil,
+			RestartRequired: n.Service.IsRunning(),
+		},
+	}
+}
+
+func (h *Handlers) handleConfigExport(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload ExportConfigRequest
+	if len(req.Payload) > 0 {
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PARSE_ERROR",
+					Message: "Failed to parse export request",
+				},
+			}
+		}
+	}
+	if payload.Format == "" {
+		payload.Format = yggdrasil.FormatHJSON
+	}
+
+	content, err := n.Config().Export(payload.Format)
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "EXPORT_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	result := &ExportConfigResult{
+		Content: string(content),
+		Format:  payload.Format,
+	}
+
+	if h.configStore != nil {
+		settings := h.configStore.Profile(profile)
+		if result.Args, err = yggdrasil.YggstackArgs(settings.Proxy, settings.Mappings); err != nil {
+			h.logger.Warn("Failed to export yggstack arguments", "error", err)
+		}
+	}
+
+	if payload.Path != "" {
+		// The file contains the private key
+		if err := os.WriteFile(payload.Path, content, 0600); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "EXPORT_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+		result.Path = payload.Path
+		h.logger.Info("Configuration exported", "path", payload.Path)
+	}
+
+	return &Response{
+		Success: true,
+		Data:    result,
+	}
+}
+
+// Identity handlers
+
+func (h *Handlers) handleIdentityExport(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload ExportIdentityRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse identity export request",
+			},
+		}
+	}
+
+	bundle, err := n.Config().Identity(payload.Peers)
+	if err != nil {
+		return identityError(err)
+	}
+	if payload.Mappings && h.configStore != nil {
+		mappings := h.configStore.Profile(profile).Mappings
+		bundle.Mappings = &mappings
+	}
+
+	content, err := yggdrasil.SealIdentity(bundle, payload.Passphrase)
+	if err != nil {
+		return identityError(err)
+	}
+
+	result := &ExportIdentityResult{
+		Content:     string(content),
+		PublicKey:   bundle.PublicKey,
+		IPv6Address: bundle.IPv6Address,
+	}
+
+	if payload.Path != "" {
+		if err := os.WriteFile(payload.Path, content, 0600); err != nil {
+			return identityError(err)
+		}
+		result.Path = payload.Path
+		h.logger.Info("Identity exported", "path", payload.Path)
+	}
+
+	return &Response{
+		Success: true,
+		Data:    result,
+	}
+}
+
+func (h *Handlers) handleIdentityImport(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload ImportIdentityRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse identity import request",
+			},
+		}
+	}
+
+	data := []byte(payload.Content)
+	if payload.Path != "" {
+		var err error
+		if data, err = os.ReadFile(payload.Path); err != nil {
+			return identityError(err)
+		}
+	}
+	if len(data) == 0 {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "VALIDATION_ERROR",
+				Message: "Identity path or content is required",
+			},
+		}
+	}
+
+	bundle, err := yggdrasil.OpenIdentity(data, payload.Passphrase)
+	if err != nil {
+		return identityError(err)
+	}
+
+	if err := n.Config().SetIdentity(bundle); err != nil {
+		return identityError(err)
+	}
+	if err := n.Config().Save(); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "SAVE_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	// The node must now answer to the imported key and address
+	info := n.Config().GetConfigInfo()
+	if info.PublicKey != bundle.PublicKey || info.IPv6Address != bundle.IPv6Address {
+		return identityError(fmt.Errorf("imported identity does not match the bundle"))
+	}
+
+	added := 0
+	if bundle.Mappings != nil {
+		if added = h.addMappings(n.Mappings, *bundle.Mappings); added > 0 {
+			h.saveMappings(n, profile)
+		}
+	}
+
+	h.logger.Info("Identity imported", "address", info.IPv6Address, "peers", len(bundle.Peers), "mappings", added)
+
+	return &Response{
+		Success: true,
+		Data: &ImportIdentityResult{
+			PublicKey:       info.PublicKey,
+			IPv6Address:     info.IPv6Address,
+			Peers:           len(bundle.Peers),
+			Mappings:        added,
+			RestartRequired: n.Service.IsRunning(),
+		},
+	}
+}
+
+// identityError wraps an identity export or import error
+func identityError(err error) *Response {
+	return &Response{
+		Success: false,
+		Error: &Error{
+			Code:    "IDENTITY_ERROR",
+			Message: err.Error(),
+		},
+	}
+}
+
+// Key generation handlers
+
+func (h *Handlers) handleKeygenStart(req *Request) *Response {
+	var opts yggdrasil.KeySearchOptions
+	if len(req.Payload) > 0 {
+		if err := json.Unmarshal(req.Payload, &opts); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PARSE_ERROR",
+					Message: "Failed to parse key search options",
+				},
+			}
+		}
+	}
+
+	err := h.keySearch.Start(opts, func(status yggdrasil.KeySearchStatus) {
+		if h.bridge != nil {
+			h.bridge.Emit(EventKeygenProgress, status)
+		}
+	})
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "KEYGEN_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    h.keySearch.Status(),
+	}
+}
+
+func (h *Handlers) handleKeygenStop(req *Request) *Response {
+	h.keySearch.Stop()
+	return &Response{
+		Success: true,
+		Data:    h.keySearch.Status(),
+	}
+}
+
+func (h *Handlers) handleKeygenStatus(req *Request) *Response {
+	return &Response{
+		Success: true,
+		Data:    h.keySearch.Status(),
+	}
+}
+
+func (h *Handlers) handleKeygenAdopt(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	key := h.keySearch.Best()
+	if key == nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "KEYGEN_ERROR",
+				Message: "No key has been found yet",
+			},
+		}
+	}
+	defer security.ZeroBytes(key)
+
+	if err := n.Config().SetKeys(key); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "KEYGEN_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+	if err := n.Config().Save(); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "SAVE_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	info := n.Config().GetConfigInfo()
+	return &Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"publicKey":       info.PublicKey,
+			"ipv6Address":     info.IPv6Address,
+			"restartRequired": n.Service.IsRunning(),
+		},
+	}
+}
+
+// StopKeySearch cancels a running key search
+func (h *Handlers) StopKeySearch() {
+	h.keySearch.Stop()
+}
+
+// Settings handlers
+
+func (h *Handlers) handleSettingsGet(req *Request) *Response {
+	// Return default settings if config store not set
+	if h.configStore == nil {
+		return &Response{
+			Success: true,
+			Data: map[string]interface{}{
+				"language":       "en",
+				"theme":          "dark",
+				"minimizeToTray": true,
+				"startMinimized": false,
+				"autostart":      false,
+				"logLevel":       "info",
+			},
+		}
+	}
+
+	settings := h.configStore.Get()
+
+	// Get actual autostart state from system (registry/desktop file)
+	// This is important because user may have changed autostart outside the app
+	autostartEnabled, err := platform.IsAutoStartEnabled()
+	if err != nil {
+		h.logger.Warn("Failed to check autostart status", "error", err)
+		autostartEnabled = settings.App.Autostart // Fallback to saved value
+	}
+
+	return &Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"language":       settings.App.Language,
+			"theme":          settings.App.Theme,
+			"minimizeToTray": settings.App.MinimizeToTray,
+			"startMinimized": settings.App.StartMinimized,
+			"autostart":      autostartEnabled,
+			"logLevel":       settings.App.LogLevel,
+			"proxy": map[string]interface{}{
+				"enabled":       settings.Proxy.Enabled,
+				"listenAddress": settings.Proxy.ListenAddress,
 				"nameserver":    settings.Proxy.Nameserver,
+				"auth":          settings.Proxy.Auth,
+
+				"allowedClients":    settings.Proxy.AllowedClients,
+				"httpListenAddress": settings.Proxy.HTTPListenAddress,
+				"routing":           redactRouting(h.SOCKSConfig(h.configStore.ActiveProfile()).Routing),
 			},
 			"node": map[string]interface{}{
 				"autoConnect": settings.Node.AutoConnect,
 			},
+			"control": map[string]interface{}{
+				"enabled":       settings.Control.Enabled,
+				"listenAddress": settings.Control.ListenAddress,
+			},
+			"failover": map[string]interface{}{
+				"enabled":   settings.Failover.Enabled,
+				"minPeers":  settings.Failover.MinPeers,
+				"interval":  settings.Failover.Interval,
+				"deadAfter": settings.Failover.DeadAfter,
+				"standby":   settings.Failover.Standby,
+			},
+			"directory": map[string]interface{}{
+				"source":  settings.Directory.Source,
+				"regions": settings.Directory.Regions,
+			},
+			"stats": map[string]interface{}{
+				"interval": settings.Stats.Interval,
+			},
+			"metrics": map[string]interface{}{
+				"enabled":           settings.Metrics.Enabled,
+				"listenAddress":     settings.Metrics.ListenAddress,
+				"yggdrasilPort":     settings.Metrics.YggdrasilPort,
+				"allowedPublicKeys": settings.Metrics.AllowedPublicKeys,
+			},
 		},
 	}
 }
 
-func (h *Handlers) handleSettingsSet(req *Request) *Response {
-	var payload struct {
-		Language       *string `json:"language,omitempty"`
-		Theme          *string `json:"theme,omitempty"`
-		MinimizeToTray *bool   `json:"minimizeToTray,omitempty"`
-		StartMinimized *bool   `json:"startMinimized,omitempty"`
-		Autostart      *bool   `json:"autostart,omitempty"`
-		LogLevel       *string `json:"logLevel,omitempty"`
-		Proxy          *struct {
-			Enabled       *bool   `json:"enabled,omitempty"`
-			ListenAddress *string `json:"listenAddress,omitempty"`
-			Nameserver    *string `json:"nameserver,omitempty"`
-		} `json:"proxy,omitempty"`
-		Node *struct {
-			AutoConnect *bool `json:"autoConnect,omitempty"`
-		} `json:"node,omitempty"`
+func (h *Handlers) handleSettingsSet(req *Request) *Response {
+	var payload struct {
+		Language       *string `json:"language,omitempty"`
+		Theme          *string `json:"theme,omitempty"`
+		MinimizeToTray *bool   `json:"minimizeToTray,omitempty"`
+		StartMinimized *bool   `json:"startMinimized,omitempty"`
+		Autostart      *bool   `json:"autostart,omitempty"`
+		LogLevel       *string `json:"logLevel,omitempty"`
+		Proxy          *struct {
+			Enabled       *bool   `json:"enabled,omitempty"`
+			ListenAddress *string `json:"listenAddress,omitempty"`
+			Nameserver    *string `json:"nameserver,omitempty"`
+
+			AllowedClients    *[]string                `json:"allowedClients,omitempty"`
+			HTTPListenAddress *string                  `json:"httpListenAddress,omitempty"`
+			Routing           *yggdrasil.RoutingConfig `json:"routing,omitempty"`
+		} `json:"proxy,omitempty"`
+		Node *struct {
+			AutoConnect *bool `json:"autoConnect,omitempty"`
+		} `json:"node,omitempty"`
+		Control *struct {
+			Enabled       *bool   `json:"enabled,omitempty"`
+			ListenAddress *string `json:"listenAddress,omitempty"`
+		} `json:"control,omitempty"`
+		Failover *struct {
+			Enabled   *bool    `json:"enabled,omitempty"`
+			MinPeers  *int     `json:"minPeers,omitempty"`
+			Interval  *int     `json:"interval,omitempty"`
+			DeadAfter *int     `json:"deadAfter,omitempty"`
+			Standby   []string `json:"standby,omitempty"`
+		} `json:"failover,omitempty"`
+		Directory *struct {
+			Source  *string  `json:"source,omitempty"`
+			Regions []string `json:"regions,omitempty"`
+		} `json:"directory,omitempty"`
+		Stats *struct {
+			Interval *int `json:"interval,omitempty"`
+		} `json:"stats,omitempty"`
+		Metrics *struct {
+			Enabled           *bool     `json:"enabled,omitempty"`
+			ListenAddress     *string   `json:"listenAddress,omitempty"`
+			YggdrasilPort     *int      `json:"yggdrasilPort,omitempty"`
+			AllowedPublicKeys *[]string `json:"allowedPublicKeys,omitempty"`
+		} `json:"metrics,omitempty"`
+	}
+
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse settings",
+			},
+		}
+	}
+
+	if payload.Proxy != nil && payload.Proxy.AllowedClients != nil {
+		if err := yggdrasil.ValidateClientAllowlist(*payload.Proxy.AllowedClients); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "VALIDATION_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	}
+
+	var routing config.ProxyRoutingSettings
+	if payload.Proxy != nil && payload.Proxy.Routing != nil {
+		if h.configStore != nil {
+			keepUpstream(payload.Proxy.Routing, h.SOCKSConfig(h.configStore.ActiveProfile()).Routing.Upstream)
+		}
+		if err := yggdrasil.ValidateRouting(*payload.Proxy.Routing); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "VALIDATION_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+		if h.configStore != nil {
+			var err error
+			if routing, err = h.upstreamSettings(h.configStore.ActiveProfile(), *payload.Proxy.Routing); err != nil {
+				return &Response{
+					Success: false,
+					Error: &Error{
+						Code:    "AUTH_ERROR",
+						Message: err.Error(),
+					},
+				}
+			}
+		}
+	}
+
+	// Update settings in config store
+	if h.configStore != nil {
+		h.configStore.Update(func(s *config.Settings) {
+			if payload.Language != nil {
+				s.App.Language = *payload.Language
+			}
+			if payload.Theme != nil {
+				s.App.Theme = *payload.Theme
+			}
+			if payload.MinimizeToTray != nil {
+				s.App.MinimizeToTray = *payload.MinimizeToTray
+			}
+			if payload.StartMinimized != nil {
+				s.App.StartMinimized = *payload.StartMinimized
+			}
+			if payload.Autostart != nil {
+				s.App.Autostart = *payload.Autostart
+				// Apply autostart setting to OS
+				if *payload.Autostart {
+					if err := platform.EnableAutoStart(); err != nil {
+						h.logger.Warn("Failed to enable autostart", "error", err)
+					} else {
+						h.logger.Info("Autostart enabled")
+					}
+				} else {
+					if err := platform.DisableAutoStart(); err != nil {
+						h.logger.Warn("Failed to disable autostart", "error", err)
+					} else {
+						h.logger.Info("Autostart disabled")
+					}
+				}
+			}
+			if payload.LogLevel != nil {
+				s.App.LogLevel = *payload.LogLevel
+			}
+			if payload.Proxy != nil {
+				if payload.Proxy.Enabled != nil {
+					s.Proxy.Enabled = *payload.Proxy.Enabled
+				}
+				if payload.Proxy.ListenAddress != nil {
+					s.Proxy.ListenAddress = *payload.Proxy.ListenAddress
+				}
+				if payload.Proxy.Nameserver != nil {
+					s.Proxy.Nameserver = *payload.Proxy.Nameserver
+				}
+				if payload.Proxy.AllowedClients != nil {
+					s.Proxy.AllowedClients = *payload.Proxy.AllowedClients
+				}
+				if payload.Proxy.HTTPListenAddress != nil {
+					s.Proxy.HTTPListenAddress = *payload.Proxy.HTTPListenAddress
+				}
+				if payload.Proxy.Routing != nil {
+					s.Proxy.Routing = routing
+				}
+			}
+			if payload.Node != nil {
+				if payload.Node.AutoConnect != nil {
+					s.Node.AutoConnect = *payload.Node.AutoConnect
+				}
+			}
+			if payload.Control != nil {
+				if payload.Control.Enabled != nil {
+					s.Control.Enabled = *payload.Control.Enabled
+				}
+				if payload.Control.ListenAddress != nil {
+					s.Control.ListenAddress = *payload.Control.ListenAddress
+				}
+			}
+			if payload.Failover != nil {
+				if payload.Failover.Enabled != nil {
+					s.Failover.Enabled = *payload.Failover.Enabled
+				}
+				if payload.Failover.MinPeers != nil {
+					s.Failover.MinPeers = *payload.Failover.MinPeers
+				}
+				if payload.Failover.Interval != nil {
+					s.Failover.Interval = *payload.Failover.Interval
+				}
+				if payload.Failover.DeadAfter != nil {
+					s.Failover.DeadAfter = *payload.Failover.DeadAfter
+				}
+				if payload.Failover.Standby != nil {
+					s.Failover.Standby = payload.Failover.Standby
+				}
+			}
+			if payload.Directory != nil {
+				if payload.Directory.Source != nil {
+					s.Directory.Source = *payload.Directory.Source
+				}
+				if payload.Directory.Regions != nil {
+					s.Directory.Regions = payload.Directory.Regions
+				}
+			}
+			if payload.Stats != nil && payload.Stats.Interval != nil {
+				s.Stats.Interval = *payload.Stats.Interval
+			}
+			if payload.Metrics != nil {
+				if payload.Metrics.Enabled != nil {
+					s.Metrics.Enabled = *payload.Metrics.Enabled
+				}
+				if payload.Metrics.ListenAddress != nil {
+					s.Metrics.ListenAddress = *payload.Metrics.ListenAddress
+				}
+				if payload.Metrics.YggdrasilPort != nil {
+					s.Metrics.YggdrasilPort = *payload.Metrics.YggdrasilPort
+				}
+				if payload.Metrics.AllowedPublicKeys != nil {
+					s.Metrics.AllowedPublicKeys = *payload.Metrics.AllowedPublicKeys
+				}
+			}
+		})
+
+		// Validate and save
+		settings := h.configStore.Get()
+		settings.Validate()
+		h.configStore.Set(&settings)
+
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save settings", "error", err)
+		}
+
+		// Start or stop the control API to match new settings.
+		// Done asynchronously so a request made through the control API
+		// itself can complete before its server is shut down.
+		if payload.Control != nil && h.controlServer != nil {
+			go func(cfg config.ControlSettings) {
+				if err := h.controlServer.Apply(cfg); err != nil {
+					h.logger.Warn("Failed to apply control API settings", "error", err)
+				}
+			}(settings.Control)
+		}
+
+		if payload.Failover != nil {
+			h.ApplyFailover()
+		}
+		if payload.Stats != nil {
+			h.ApplyStats()
+		}
+		if payload.Metrics != nil && h.metricsServer != nil {
+			if err := h.metricsServer.Apply(settings.Metrics); err != nil {
+				h.logger.Warn("Failed to apply metrics settings", "error", err)
+			}
+		}
+	}
+
+	h.logger.Info("Settings updated")
+
+	// Return updated settings
+	return h.handleSettingsGet(req)
+}
+
+// SOCKSConfig returns the SOCKS proxy config of a profile from the saved
+// settings, with the credentials from secure storage
+func (h *Handlers) SOCKSConfig(profile string) yggdrasil.SOCKSConfig {
+	if h.configStore == nil {
+		return yggdrasil.SOCKSConfig{}
+	}
+
+	proxy := h.configStore.Profile(profile).Proxy
+	cfg := yggdrasil.SOCKSConfig{
+		Enabled:        proxy.Enabled,
+		ListenAddress:  proxy.ListenAddress,
+		Nameserver:     proxy.Nameserver,
+		AllowedClients: proxy.AllowedClients,
+
+		HTTPListenAddress: proxy.HTTPListenAddress,
+		Routing:           routingConfig(proxy.Routing),
+	}
+
+	if proxy.Auth {
+		creds, err := h.loadProxyCredentials(profile)
+		if err != nil {
+			// Leaving auth off would open the proxy, so use credentials
+			// no client can present
+			h.logger.Warn("SOCKS credentials unavailable, refusing all clients", "profile", profile, "error", err)
+			creds = lockedSOCKSCredentials()
+		}
+		cfg.Username = creds.Username
+		cfg.Password = creds.Password
+	}
+
+	if proxy.Routing.UpstreamAuth {
+		user, err := h.loadUpstreamCredentials(profile)
+		if err != nil {
+			// The upstream refuses the proxy, nothing is opened
+			h.logger.Warn("Upstream proxy credentials unavailable", "profile", profile, "error", err)
+		} else {
+			cfg.Routing.Upstream = yggdrasil.WithUpstreamCredentials(cfg.Routing.Upstream, user)
+		}
+	}
+
+	return cfg
+}
+
+// loadProxyCredentials reads the SOCKS credentials of a profile
+func (h *Handlers) loadProxyCredentials(profile string) (*yggdrasil.SOCKSCredentials, error) {
+	if h.secrets == nil {
+		return nil, errors.New("secure storage is not available")
+	}
+	return yggdrasil.LoadSOCKSCredentials(h.secrets, profile)
+}
+
+// lockedSOCKSCredentials returns random credentials, used when the saved
+// ones cannot be read. Without randomness the password stays empty and the
+// proxy refuses to start.
+func lockedSOCKSCredentials() *yggdrasil.SOCKSCredentials {
+	creds := &yggdrasil.SOCKSCredentials{Username: "locked"}
+	if raw, err := security.GenerateRandomBytes(32); err == nil {
+		creds.Password = hex.EncodeToString(raw)
+	}
+	return creds
+}
+
+// setProxyCredentials saves or, with nil, removes the SOCKS credentials of
+// a profile. The settings only record whether auth is on.
+func (h *Handlers) setProxyCredentials(profile string, creds *yggdrasil.SOCKSCredentials) error {
+	if h.secrets == nil {
+		return errors.New("secure storage is not available")
+	}
+
+	if creds != nil {
+		if err := yggdrasil.StoreSOCKSCredentials(h.secrets, profile, *creds); err != nil {
+			return err
+		}
+	} else if err := yggdrasil.DeleteSOCKSCredentials(h.secrets, profile); err != nil {
+		h.logger.Debug("No SOCKS credentials to delete", "profile", profile, "error", err)
+	}
+
+	if h.configStore != nil {
+		h.configStore.UpdateProfile(profile, func(p *config.ProfileSettings) {
+			p.Proxy.Auth = creds != nil
+		})
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save proxy settings", "error", err)
+		}
+	}
+	return nil
+}
+
+// loadUpstreamCredentials reads the upstream proxy credentials of a profile
+func (h *Handlers) loadUpstreamCredentials(profile string) (*url.Userinfo, error) {
+	if h.secrets == nil {
+		return nil, errors.New("secure storage is not available")
+	}
+	return yggdrasil.LoadUpstreamCredentials(h.secrets, profile)
+}
+
+// upstreamSettings moves the credentials of the routing upstream of a
+// profile to secure storage and returns the routing settings to save
+// without them
+func (h *Handlers) upstreamSettings(profile string, cfg yggdrasil.RoutingConfig) (config.ProxyRoutingSettings, error) {
+	upstream, user := yggdrasil.SplitUpstreamCredentials(cfg.Upstream)
+	cfg.Upstream = upstream
+	settings := routingSettings(cfg)
+
+	if user == nil {
+		if h.secrets != nil {
+			if err := yggdrasil.DeleteUpstreamCredentials(h.secrets, profile); err != nil {
+				h.logger.Debug("No upstream proxy credentials to delete", "profile", profile, "error", err)
+			}
+		}
+		return settings, nil
+	}
+
+	if h.secrets == nil {
+		return settings, errors.New("secure storage is not available, upstream proxy credentials cannot be saved")
+	}
+	if err := yggdrasil.StoreUpstreamCredentials(h.secrets, profile, user); err != nil {
+		return settings, err
+	}
+	settings.UpstreamAuth = true
+	return settings, nil
+}
+
+// migrateUpstreamCredentials moves upstream proxy credentials left in the
+// settings of any profile to secure storage
+func (h *Handlers) migrateUpstreamCredentials() {
+	if h.configStore == nil {
+		return
+	}
+
+	names := []string{h.configStore.ActiveProfile()}
+	for name := range h.configStore.Get().Profiles {
+		names = append(names, name)
+	}
+
+	migrated := false
+	for _, name := range names {
+		routing := h.configStore.Profile(name).Proxy.Routing
+		if _, user := yggdrasil.SplitUpstreamCredentials(routing.Upstream); user == nil {
+			continue
+		}
+		saved, err := h.upstreamSettings(name, routingConfig(routing))
+		if err != nil {
+			h.logger.Warn("Failed to move upstream proxy credentials to secure storage", "profile", name, "error", err)
+			continue
+		}
+		h.configStore.UpdateProfile(name, func(p *config.ProfileSettings) {
+			p.Proxy.Routing = saved
+		})
+		migrated = true
+	}
+
+	if migrated {
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save settings", "error", err)
+		}
+	}
+}
+
+// configureProxyAudit records failed SOCKS authentications of a node in
+// the audit log. The primary node has an empty node ID.
+func (h *Handlers) configureProxyAudit(nodeID string, n *yggdrasil.Node) {
+	n.SOCKS.SetAuthFailureHandler(func(remote, username string) {
+		if h.auditLogger == nil {
+			return
+		}
+		h.auditLogger.LogFailure(logger.AuditEventProxyAuthFail, "SOCKS authentication failed", nil, map[string]interface{}{
+			"node":     nodeID,
+			"remote":   remote,
+			"username": username,
+		})
+	})
+}
+
+// Proxy handlers
+
+func (h *Handlers) handleProxyConfig(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload ProxyConfig
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse proxy config",
+			},
+		}
+	}
+
+	// Update the saved credentials before the proxy is restarted with them
+	var authErr error
+	switch {
+	case payload.Username != "" || payload.Password != "":
+		authErr = h.setProxyCredentials(profile, &yggdrasil.SOCKSCredentials{
+			Username: payload.Username,
+			Password: payload.Password,
+		})
+	case payload.Auth != nil && !*payload.Auth:
+		authErr = h.setProxyCredentials(profile, nil)
+	case payload.Auth != nil && *payload.Auth:
+		if _, err := h.loadProxyCredentials(profile); err != nil {
+			authErr = fmt.Errorf("no saved SOCKS credentials, set a username and password")
+		}
+	}
+	if authErr != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "AUTH_ERROR",
+				Message: authErr.Error(),
+			},
+		}
+	}
+
+	if err := yggdrasil.ValidateClientAllowlist(payload.AllowedClients); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	config := yggdrasil.SOCKSConfig{
+		Enabled:        payload.Enabled,
+		ListenAddress:  payload.ListenAddress,
+		Nameserver:     payload.Nameserver,
+		AllowedClients: payload.AllowedClients,
+
+		HTTPListenAddress: payload.HTTPListenAddress,
+	}
+	saved := h.SOCKSConfig(profile)
+	if saved.Username != "" {
+		config.Username = saved.Username
+		config.Password = saved.Password
+	}
+	// Without a list in the payload the saved allowlist is kept
+	if payload.AllowedClients == nil {
+		config.AllowedClients = saved.AllowedClients
+	}
+	// Routing is changed through proxy:routing
+	config.Routing = saved.Routing
+
+	if config.Enabled {
+		if err := n.SOCKS.Start(config); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PROXY_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	} else {
+		if err := n.SOCKS.Stop(); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PROXY_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	}
+
+	// Proxy settings live in the config store only, same as settings:set
+	h.saveProxySettings(profile, config)
+
+	return &Response{
+		Success: true,
+		Data:    n.SOCKS.GetStats(),
+	}
+}
+
+func (h *Handlers) handleProxyStatus(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	return &Response{
+		Success: true,
+		Data:    n.SOCKS.GetStats(),
+	}
+}
+
+func (h *Handlers) handleProxyStart(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload StartProxyRequest
+	if len(req.Payload) > 0 {
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PARSE_ERROR",
+					Message: "Failed to parse proxy config",
+				},
+			}
+		}
+	}
+
+	// Fill missing values from saved settings, then from the last used config
+	socksConfig := n.SOCKS.GetConfig()
+	if h.configStore != nil {
+		saved := h.SOCKSConfig(profile)
+		socksConfig.ListenAddress = saved.ListenAddress
+		socksConfig.Nameserver = saved.Nameserver
+		socksConfig.Username = saved.Username
+		socksConfig.Password = saved.Password
+		socksConfig.AllowedClients = saved.AllowedClients
+		socksConfig.HTTPListenAddress = saved.HTTPListenAddress
+		socksConfig.Routing = saved.Routing
+	}
+	if payload.ListenAddress != "" {
+		socksConfig.ListenAddress = payload.ListenAddress
+	}
+	if payload.Nameserver != "" {
+		socksConfig.Nameserver = payload.Nameserver
+	}
+	if payload.HTTPListenAddress != "" {
+		socksConfig.HTTPListenAddress = payload.HTTPListenAddress
+	}
+	socksConfig.Enabled = true
+
+	if err := n.SOCKS.Start(socksConfig); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PROXY_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    toProxyStatus(n.SOCKS.GetStats()),
+	}
+}
+
+func (h *Handlers) handleProxyStop(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	if err := n.SOCKS.Stop(); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PROXY_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    toProxyStatus(n.SOCKS.GetStats()),
+	}
+}
+
+func (h *Handlers) handleProxyClients(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	return &Response{
+		Success: true,
+		Data:    n.SOCKS.GetClients(),
+	}
+}
+
+// handleProxyRouting returns the split routing settings of the proxy. With
+// a payload it replaces them and restarts a running proxy.
+func (h *Handlers) handleProxyRouting(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	if len(req.Payload) == 0 || string(req.Payload) == "null" {
+		return &Response{
+			Success: true,
+			Data:    redactRouting(h.SOCKSConfig(profile).Routing),
+		}
+	}
+
+	var routing yggdrasil.RoutingConfig
+	if err := json.Unmarshal(req.Payload, &routing); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse proxy routing",
+			},
+		}
+	}
+	keepUpstream(&routing, h.SOCKSConfig(profile).Routing.Upstream)
+	if err := yggdrasil.ValidateRouting(routing); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	// Credentials are saved first, a running proxy only changes once they are
+	var saved config.ProxyRoutingSettings
+	if h.configStore != nil {
+		var err error
+		if saved, err = h.upstreamSettings(profile, routing); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "AUTH_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	}
+
+	if n.SOCKS.IsRunning() {
+		socksConfig := n.SOCKS.GetConfig()
+		socksConfig.Routing = routing
+		if err := n.SOCKS.SetConfig(socksConfig); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PROXY_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+	}
+
+	if h.configStore != nil {
+		h.configStore.UpdateProfile(profile, func(p *config.ProfileSettings) {
+			p.Proxy.Routing = saved
+		})
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save proxy routing", "error", err)
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    redactRouting(routing),
+	}
+}
+
+// routingConfig converts saved routing settings to the proxy type
+func routingConfig(s config.ProxyRoutingSettings) yggdrasil.RoutingConfig {
+	cfg := yggdrasil.RoutingConfig{
+		Enabled:  s.Enabled,
+		Default:  yggdrasil.RouteAction(s.Default),
+		Upstream: s.Upstream,
+	}
+	for _, rule := range s.Rules {
+		cfg.Rules = append(cfg.Rules, yggdrasil.RoutingRule{Match: rule.Match, Action: yggdrasil.RouteAction(rule.Action)})
+	}
+	return cfg
+}
+
+// routingSettings converts proxy routing to the saved settings type
+func routingSettings(cfg yggdrasil.RoutingConfig) config.ProxyRoutingSettings {
+	s := config.ProxyRoutingSettings{
+		Enabled:  cfg.Enabled,
+		Default:  string(cfg.Default),
+		Upstream: cfg.Upstream,
+	}
+	for _, rule := range cfg.Rules {
+		s.Rules = append(s.Rules, config.ProxyRoutingRule{Match: rule.Match, Action: string(rule.Action)})
+	}
+	return s
+}
+
+// redactRouting hides the upstream proxy password before routing is sent
+// to a client
+func redactRouting(cfg yggdrasil.RoutingConfig) yggdrasil.RoutingConfig {
+	if u, err := url.Parse(cfg.Upstream); err == nil && u.User != nil {
+		cfg.Upstream = u.Redacted()
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = []yggdrasil.RoutingRule{}
+	}
+	return cfg
+}
+
+// keepUpstream restores the saved upstream when a client sends back the
+// redacted address it was given
+func keepUpstream(cfg *yggdrasil.RoutingConfig, saved string) {
+	if saved != "" && cfg.Upstream != saved && cfg.Upstream == redactRouting(yggdrasil.RoutingConfig{Upstream: saved}).Upstream {
+		cfg.Upstream = saved
+	}
+}
+
+// toProxyStatus converts proxy stats to the IPC type
+func toProxyStatus(stats *yggdrasil.SOCKSStats) *ProxyStatus {
+	return &ProxyStatus{
+		Enabled:           stats.Enabled,
+		ListenAddress:     stats.ListenAddress,
+		ActiveConnections: stats.ActiveConnections,
+		TotalConnections:  int64(stats.TotalConnections),
+		BytesIn:           int64(stats.BytesIn),
+		BytesOut:          int64(stats.BytesOut),
+		AuthEnabled:       stats.AuthEnabled,
+		AuthFailures:      int64(stats.AuthFailures),
+		HTTPListenAddress: stats.HTTPListenAddress,
+		UDPAssociations:   stats.UDPAssociations,
+	}
+}
+
+// Mapping handlers
+
+func (h *Handlers) handleMappingAdd(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var mapping yggdrasil.PortMapping
+	if err := json.Unmarshal(req.Payload, &mapping); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse mapping",
+			},
+		}
+	}
+
+	// Assign the ID here so it can be returned and saved
+	if mapping.ID == "" {
+		mapping.ID = newMappingID()
+	}
+
+	if err := n.Mappings.AddMapping(mapping); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "MAPPING_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	h.saveMappings(n, profile)
+
+	return &Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"id":    mapping.ID,
+			"added": true,
+		},
+	}
+}
+
+func (h *Handlers) handleMappingRemove(req *Request) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse mapping ID",
+			},
+		}
+	}
+
+	if err := n.Mappings.RemoveMapping(payload.ID); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "MAPPING_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	h.saveMappings(n, profile)
+
+	return &Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"id":      payload.ID,
+			"removed": true,
+		},
+	}
+}
+
+func (h *Handlers) handleMappingList(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	mappings := n.Mappings.GetMappings()
+
+	list := make([]PortMapping, len(mappings))
+	for i, m := range mappings {
+		list[i] = toPortMapping(m)
+	}
+
+	return &Response{
+		Success: true,
+		Data:    list,
+	}
+}
+
+func (h *Handlers) handleMappingEnable(req *Request) *Response {
+	return h.toggleMapping(req, true)
+}
+
+func (h *Handlers) handleMappingDisable(req *Request) *Response {
+	return h.toggleMapping(req, false)
+}
+
+// toggleMapping enables or disables the mapping given in the request
+func (h *Handlers) toggleMapping(req *Request, enable bool) *Response {
+	n, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	var payload ToggleMappingRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse mapping ID",
+			},
+		}
+	}
+
+	if payload.ID == "" {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "VALIDATION_ERROR",
+				Message: "Mapping ID is required",
+			},
+		}
+	}
+
+	var err error
+	if enable {
+		err = n.Mappings.EnableMapping(payload.ID)
+	} else {
+		err = n.Mappings.DisableMapping(payload.ID)
+	}
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "MAPPING_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	h.saveMappings(n, profile)
+
+	mapping, err := n.Mappings.GetMapping(payload.ID)
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "MAPPING_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    toPortMapping(*mapping),
+	}
+}
+
+// toPortMapping converts a mapping to the IPC type
+func toPortMapping(m yggdrasil.PortMapping) PortMapping {
+	return PortMapping{
+		ID:       m.ID,
+		Type:     string(m.Type),
+		Source:   m.Source,
+		Target:   m.Target,
+		Enabled:  m.Enabled,
+		Active:   m.Active,
+		BytesIn:  int64(m.BytesIn),
+		BytesOut: int64(m.BytesOut),
+
+		UploadLimit:    m.UploadLimit,
+		DownloadLimit:  m.DownloadLimit,
+		MaxConnections: m.MaxConnections,
+		MaxSessions:    m.MaxSessions,
+		UDPIdleTimeout: m.UDPIdleTimeout,
+
+		Allow: m.Allow,
+		Deny:  m.Deny,
+	}
+}
+
+// Profile handlers
+
+// ListProfiles returns the node profiles
+func (h *Handlers) ListProfiles() (*ProfileList, error) {
+	profiles, err := h.profiles.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProfileList{
+		Active:   h.profiles.Active(),
+		Profiles: profiles,
+	}, nil
+}
+
+// CreateProfile creates a profile with a new identity
+func (h *Handlers) CreateProfile(name string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if err := h.profiles.Create(name); err != nil {
+		return err
+	}
+
+	h.emitProfileChanged()
+	return nil
+}
+
+// CloneProfile creates a profile from the config and settings of src
+func (h *Handlers) CloneProfile(src, dst string, newIdentity bool) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if src == "" {
+		src = h.profiles.Active()
+	}
+
+	if err := h.profiles.Clone(src, dst, newIdentity); err != nil {
+		return err
+	}
+
+	if h.configStore != nil {
+		h.configStore.CloneProfile(src, dst)
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save profile settings", "error", err)
+		}
+	}
+
+	h.emitProfileChanged()
+	return nil
+}
+
+// RenameProfile renames a profile
+func (h *Handlers) RenameProfile(oldName, newName string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if err := h.checkNotHosted(oldName); err != nil {
+		return err
+	}
+	if err := h.profiles.Rename(oldName, newName); err != nil {
+		return err
+	}
+
+	if h.configStore != nil {
+		h.configStore.RenameProfile(oldName, newName)
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save profile settings", "error", err)
+		}
+	}
+
+	h.emitProfileChanged()
+	return nil
+}
+
+// DeleteProfile deletes an inactive profile
+func (h *Handlers) DeleteProfile(name string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if err := h.checkNotHosted(name); err != nil {
+		return err
+	}
+	if err := h.profiles.Delete(name); err != nil {
+		return err
+	}
+
+	if h.configStore != nil {
+		h.configStore.DeleteProfile(name)
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save profile settings", "error", err)
+		}
+	}
+
+	h.emitProfileChanged()
+	return nil
+}
+
+// SwitchProfile makes another profile active. The proxy and mappings of
+// the old profile are stopped, and a running node is restarted with the
+// identity, peers, proxy and mappings of the new one.
+func (h *Handlers) SwitchProfile(name string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if name == h.profiles.Active() {
+		return nil
+	}
+	if !h.profiles.Exists(name) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	if err := h.checkNotHosted(name); err != nil {
+		return err
+	}
+
+	h.logger.Info("Switching profile", "from", h.profiles.Active(), "to", name)
+
+	// The proxy keeps the netstack of the node it was started on
+	proxyWasRunning := h.socksProxy.IsRunning()
+	if proxyWasRunning {
+		if err := h.socksProxy.Stop(); err != nil {
+			h.logger.Warn("Failed to stop SOCKS proxy", "error", err)
+		}
+	}
+
+	// Mappings belong to the profile, they are saved in the config store
+	for _, m := range h.mappingManager.GetMappings() {
+		if err := h.mappingManager.RemoveMapping(m.ID); err != nil {
+			h.logger.Warn("Failed to remove mapping", "id", m.ID, "error", err)
+		}
+	}
+
+	if err := h.profiles.Activate(name); err != nil {
+		// Keep running with the old profile
+		h.LoadMappings()
+		return err
+	}
+
+	if h.configStore != nil {
+		h.configStore.SwitchProfile(name)
+		if err := h.configStore.Save(); err != nil {
+			h.logger.Warn("Failed to save profile settings", "error", err)
+		}
+	}
+
+	var restartErr error
+	if h.service.IsRunning() {
+		restartErr = h.service.Restart()
+		if restartErr != nil {
+			h.logger.Error("Failed to restart node with new profile", "error", restartErr)
+		}
+	}
+
+	h.LoadMappings()
+
+	if proxyWasRunning && h.service.IsRunning() {
+		h.startNodeProxy(h.primary, name)
+	}
+
+	h.emitProfileChanged()
+	return restartErr
+}
+
+// checkNotHosted fails if a profile runs as an additional node
+func (h *Handlers) checkNotHosted(name string) error {
+	if _, ok := h.nodes.Get(name); ok {
+		return fmt.Errorf("profile %s runs as an additional node, remove the node first", name)
+	}
+	return nil
+}
+
+// emitProfileChanged notifies the frontend that profiles changed
+func (h *Handlers) emitProfileChanged() {
+	if h.bridge == nil {
+		return
+	}
+
+	list, err := h.ListProfiles()
+	if err != nil {
+		h.logger.Warn("Failed to list profiles", "error", err)
+		return
+	}
+	h.bridge.Emit(EventProfileChanged, list)
+}
+
+func (h *Handlers) handleProfileList(req *Request) *Response {
+	list, err := h.ListProfiles()
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PROFILE_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data:    list,
+	}
+}
+
+func (h *Handlers) handleProfileCreate(req *Request) *Response {
+	var payload ProfileRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse profile request",
+			},
+		}
+	}
+
+	return h.profileResponse(h.CreateProfile(payload.Name))
+}
+
+func (h *Handlers) handleProfileClone(req *Request) *Response {
+	var payload CloneProfileRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse profile request",
+			},
+		}
+	}
+
+	return h.profileResponse(h.CloneProfile(payload.Source, payload.Name, payload.NewIdentity))
+}
+
+func (h *Handlers) handleProfileRename(req *Request) *Response {
+	var payload RenameProfileRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse profile request",
+			},
+		}
+	}
+
+	return h.profileResponse(h.RenameProfile(payload.Name, payload.NewName))
+}
+
+func (h *Handlers) handleProfileDelete(req *Request) *Response {
+	var payload ProfileRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse profile request",
+			},
+		}
+	}
+
+	return h.profileResponse(h.DeleteProfile(payload.Name))
+}
+
+func (h *Handlers) handleProfileSwitch(req *Request) *Response {
+	var payload ProfileRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PARSE_ERROR",
+				Message: "Failed to parse profile request",
+			},
+		}
+	}
+
+	return h.profileResponse(h.SwitchProfile(payload.Name))
+}
+
+// profileResponse returns the profile list, or err if a profile change failed
+func (h *Handlers) profileResponse(err error) *Response {
+	if err != nil {
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "PROFILE_ERROR",
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return h.handleProfileList(nil)
+}
+
+// Node registry handlers
+
+// LoadNodes registers the additional nodes saved in the config store.
+// Profiles that no longer exist are dropped.
+func (h *Handlers) LoadNodes() {
+	if h.configStore == nil {
+		return
+	}
+
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	saved := h.configStore.Get().Node.Extra
+	for _, id := range saved {
+		if err := h.addNode(id); err != nil {
+			h.logger.Warn("Failed to load node", "id", id, "error", err)
+		}
+	}
+
+	if len(h.nodes.IDs()) != len(saved) {
+		h.saveNodes()
+	}
+}
+
+// ListNodes returns the primary node and the additional nodes
+func (h *Handlers) ListNodes() *NodeList {
+	list := &NodeList{
+		Nodes: []NodeEntry{nodeEntry(h.profiles.Active(), h.primary, true)},
+	}
+
+	for _, id := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(id); ok {
+			list.Nodes = append(list.Nodes, nodeEntry(id, n, false))
+		}
+	}
+
+	return list
+}
+
+// AddNode runs a profile as an additional node next to the primary one.
+// The node is stopped until it is started with node:start.
+func (h *Handlers) AddNode(id string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	if err := h.addNode(id); err != nil {
+		return err
+	}
+
+	h.saveNodes()
+	h.emitNodesChanged()
+	return nil
+}
+
+// RemoveNode stops an additional node and removes it from the registry
+func (h *Handlers) RemoveNode(id string) error {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	n, ok := h.nodes.Remove(id)
+	if !ok {
+		return fmt.Errorf("node not found: %s", id)
+	}
+
+	if err := n.Stop(); err != nil {
+		h.logger.Warn("Failed to stop node", "id", id, "error", err)
+	}
+
+	h.saveNodes()
+	h.emitNodesChanged()
+	h.logger.Info("Node removed", "id", id)
+	return nil
+}
+
+// StartAdditionalNodes starts the additional nodes that are stopped
+func (h *Handlers) StartAdditionalNodes() {
+	for _, id := range h.nodes.IDs() {
+		n, ok := h.nodes.Get(id)
+		if !ok || n.Service.GetState() != yggdrasil.StateStopped {
+			continue
+		}
+
+		if err := n.Config().Load(); err != nil {
+			h.logger.Warn("Failed to load node configuration", "id", id, "error", err)
+			continue
+		}
+		if err := h.checkIdentity(id, n.Config().GetPublicKey()); err != nil {
+			h.logger.Warn("Not starting node", "id", id, "error", err)
+			continue
+		}
+		if err := n.Service.Start(n.Config().GetConfig()); err != nil {
+			h.logger.Warn("Failed to start node", "id", id, "error", err)
+			continue
+		}
+		h.startNodeProxy(n, id)
+	}
+}
+
+// StopAdditionalNodes stops all additional nodes
+func (h *Handlers) StopAdditionalNodes() {
+	for _, id := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(id); ok {
+			if err := n.Stop(); err != nil {
+				h.logger.Warn("Failed to stop node", "id", id, "error", err)
+			}
+		}
+	}
+}
+
+// addNode creates a node for a profile and registers it (must be called
+// with profileMu held)
+func (h *Handlers) addNode(id string) error {
+	if _, ok := h.nodes.Get(id); ok {
+		return fmt.Errorf("node already exists: %s", id)
+	}
+
+	cm, err := h.profiles.OpenConfig(id)
+	if err != nil {
+		return err
+	}
+	if err := h.checkIdentity(id, cm.IdentityKey()); err != nil {
+		return err
+	}
+
+	n := yggdrasil.NewNode(yggdrasil.NewServiceWithConfig(cm, h.logger), h.logger)
+	if err := h.nodes.Add(id, n); err != nil {
+		return err
+	}
+
+	h.notifyStateChanges(id, n.Service)
+	h.loadMappings(n, id)
+	h.configureFailover(id, n)
+	h.configureStats(id, n)
+	h.configureMappingAudit(id, n)
+	h.configureProxyAudit(id, n)
+
+	h.logger.Info("Node added", "id", id)
+	return nil
+}
+
+// checkIdentity refuses a node whose public key is already used by the
+// primary or another registered node. Profiles cloned without a new
+// identity share keys, and two nodes would announce the same address.
+func (h *Handlers) checkIdentity(id, publicKey string) error {
+	if publicKey == "" {
+		return nil
+	}
+
+	others := map[string]*yggdrasil.Node{h.profiles.Active(): h.primary}
+	for _, other := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(other); ok {
+			others[other] = n
+		}
+	}
+	for other, n := range others {
+		if other != id && n.Config().IdentityKey() == publicKey {
+			return fmt.Errorf("profile %s has the same identity as node %s, clone it with a new identity", id, other)
+		}
+	}
+	return nil
+}
+
+// failoverConfig returns the peer supervisor config from the settings
+func (h *Handlers) failoverConfig() yggdrasil.FailoverConfig {
+	if h.configStore == nil {
+		return yggdrasil.FailoverConfig{}
 	}
 
-	if err := json.Unmarshal(req.Payload, &payload); err != nil {
-		return &Response{
-			Success: false,
-			Error: &Error{
-				Code:    "PARSE_ERROR",
-				Message: "Failed to parse settings",
-			},
+	s := h.configStore.Get().Failover
+	return yggdrasil.FailoverConfig{
+		Enabled:   s.Enabled,
+		MinPeers:  s.MinPeers,
+		Interval:  time.Duration(s.Interval) * time.Second,
+		DeadAfter: s.DeadAfter,
+		Standby:   append([]string{}, s.Standby...),
+	}
+}
+
+// configureFailover applies the failover settings to the peer supervisor
+// of a node. The primary node has an empty node ID.
+func (h *Handlers) configureFailover(nodeID string, n *yggdrasil.Node) {
+	n.Supervisor.SetChangeHandler(func(e yggdrasil.FailoverEvent) {
+		if h.bridge != nil {
+			h.bridge.Emit(EventPeersFailover, &PeerFailoverEvent{NodeID: nodeID, FailoverEvent: e})
+		}
+	})
+	n.Supervisor.Configure(h.failoverConfig())
+}
+
+// configureMappingAudit records connections refused by the access lists
+// of a node's remote mappings in the audit log. The primary node has an
+// empty node ID.
+func (h *Handlers) configureMappingAudit(nodeID string, n *yggdrasil.Node) {
+	n.Mappings.SetDenyHandler(func(d yggdrasil.MappingDenial) {
+		if h.auditLogger == nil {
+			return
+		}
+		h.auditLogger.LogFailure(logger.AuditEventMappingDeny, "Mapping connection denied", nil, map[string]interface{}{
+			"node":    nodeID,
+			"mapping": d.MappingID,
+			"remote":  d.Remote,
+			"reason":  d.Reason,
+		})
+	})
+}
+
+// ApplyFailover applies the failover settings to all nodes
+func (h *Handlers) ApplyFailover() {
+	h.configureFailover("", h.primary)
+	for _, id := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(id); ok {
+			h.configureFailover(id, n)
 		}
 	}
+}
 
-	// Update settings in config store
-	if h.configStore != nil {
-		h.configStore.Update(func(s *config.Settings) {
-			if payload.Language != nil {
-				s.App.Language = *payload.Language
-			}
-			if payload.Theme != nil {
-				s.App.Theme = *payload.Theme
-			}
-			if payload.MinimizeToTray != nil {
-				s.App.MinimizeToTray = *payload.MinimizeToTray
-			}
-			if payload.StartMinimized != nil {
-				s.App.StartMinimized = *payload.StartMinimized
-			}
-			if payload.Autostart != nil {
-				s.App.Autostart = *payload.Autostart
-				// Apply autostart setting to OS
-				if *payload.Autostart {
-					if err := platform.EnableAutoStart(); err != nil {
-						h.logger.Warn("Failed to enable autostart", "error", err)
-					} else {
-						h.logger.Info("Autostart enabled")
-					}
-				} else {
-					if err := platform.DisableAutoStart(); err != nil {
-						h.logger.Warn("Failed to disable autostart", "error", err)
-					} else {
-						h.logger.Info("Autostart disabled")
-					}
-				}
-			}
-			if payload.LogLevel != nil {
-				s.App.LogLevel = *payload.LogLevel
+// statsInterval returns the time between stats updates from the settings
+func (h *Handlers) statsInterval() time.Duration {
+	if h.configStore == nil {
+		return yggdrasil.DefaultStatsInterval
+	}
+	return time.Duration(h.configStore.Get().Stats.Interval) * time.Second
+}
+
+// configureStats pushes the peer changes and traffic stats of a node to
+// the frontend and records peer connections in the audit log. The primary
+// node has an empty node ID.
+func (h *Handlers) configureStats(nodeID string, n *yggdrasil.Node) {
+	n.Monitor.SetHandlers(func(changes []yggdrasil.PeerChange, peers []yggdrasil.PeerInfo) {
+		now := time.Now().UnixMilli()
+		for _, c := range changes {
+			event, eventType, audit := EventPeerDisconnected, "disconnected", logger.AuditEventPeerDisconnect
+			if c.Connected {
+				event, eventType, audit = EventPeerConnected, "connected", logger.AuditEventPeerConnect
 			}
-			if payload.Proxy != nil {
-				if payload.Proxy.Enabled != nil {
-					s.Proxy.Enabled = *payload.Proxy.Enabled
-				}
-				if payload.Proxy.ListenAddress != nil {
-					s.Proxy.ListenAddress = *payload.Proxy.ListenAddress
-				}
-				if payload.Proxy.Nameserver != nil {
-					s.Proxy.Nameserver = *payload.Proxy.Nameserver
-				}
+
+			if h.auditLogger != nil {
+				h.auditLogger.LogSuccess(audit, "Peer "+eventType, map[string]interface{}{
+					"node":    nodeID,
+					"uri":     c.Peer.URI,
+					"address": c.Peer.Address,
+					"inbound": c.Peer.Inbound,
+				})
 			}
-			if payload.Node != nil {
-				if payload.Node.AutoConnect != nil {
-					s.Node.AutoConnect = *payload.Node.AutoConnect
-				}
+			if h.bridge != nil {
+				h.bridge.Emit(event, &PeerEvent{NodeID: nodeID, Type: eventType, Peer: toPeerInfo(c.Peer), Timestamp: now})
 			}
-		})
+		}
 
-		// Validate and save
-		settings := h.configStore.Get()
-		settings.Validate()
-		h.configStore.Set(&settings)
+		if h.bridge != nil {
+			update := &PeersUpdate{NodeID: nodeID, Peers: make([]*PeerInfo, len(peers))}
+			for i, p := range peers {
+				update.Peers[i] = toPeerInfo(p)
+			}
+			h.bridge.Emit(EventPeersUpdate, update)
+		}
+	}, func(s yggdrasil.TrafficStats) {
+		if h.bridge != nil {
+			h.bridge.Emit(EventStatsUpdate, toNetworkStats(nodeID, s))
+		}
+	})
+	n.Monitor.SetInterval(h.statsInterval())
+}
 
-		if err := h.configStore.Save(); err != nil {
-			h.logger.Warn("Failed to save settings", "error", err)
+// ApplyStats applies the stats settings to all nodes
+func (h *Handlers) ApplyStats() {
+	h.configureStats("", h.primary)
+	for _, id := range h.nodes.IDs() {
+		if n, ok := h.nodes.Get(id); ok {
+			h.configureStats(id, n)
 		}
 	}
+}
 
-	h.logger.Info("Settings updated")
+// toPeerInfo converts a peer to the IPC type
+func toPeerInfo(p yggdrasil.PeerInfo) *PeerInfo {
+	return &PeerInfo{
+		URI:       p.URI,
+		Address:   p.Address,
+		PublicKey: p.PublicKey,
+		Connected: p.Connected,
+		Inbound:   p.Inbound,
+		Latency:   p.Latency,
+		RxBytes:   int64(p.RxBytes),
+		TxBytes:   int64(p.TxBytes),
+		Uptime:    p.Uptime,
+	}
+}
 
-	// Return updated settings
-	return h.handleSettingsGet(req)
+// toNetworkStats converts traffic stats to the IPC type
+func toNetworkStats(nodeID string, s yggdrasil.TrafficStats) *NetworkStats {
+	return &NetworkStats{
+		NodeID:        nodeID,
+		TotalRxBytes:  int64(s.TotalRxBytes),
+		TotalTxBytes:  int64(s.TotalTxBytes),
+		RxBytesPerSec: s.RxRate,
+		TxBytesPerSec: s.TxRate,
+		PeerCount:     s.PeerCount,
+		SessionCount:  s.SessionCount,
+		Uptime:        s.Uptime,
+		Timestamp:     s.Time.UnixMilli(),
+	}
 }
 
-// Proxy handlers
+// saveNodes writes the IDs of the additional nodes to the config store
+func (h *Handlers) saveNodes() {
+	if h.configStore == nil {
+		return
+	}
 
-func (h *Handlers) handleProxyConfig(req *Request) *Response {
-	var config yggdrasil.SOCKSConfig
-	if err := json.Unmarshal(req.Payload, &config); err != nil {
-		return &Response{
-			Success: false,
-			Error: &Error{
-				Code:    "PARSE_ERROR",
-				Message: "Failed to parse proxy config",
-			},
-		}
+	ids := h.nodes.IDs()
+	h.configStore.Update(func(s *config.Settings) {
+		s.Node.Extra = ids
+	})
+
+	if err := h.configStore.Save(); err != nil {
+		h.logger.Warn("Failed to save nodes", "error", err)
 	}
+}
 
-	if config.Enabled {
-		if err := h.socksProxy.Start(config); err != nil {
-			return &Response{
-				Success: false,
-				Error: &Error{
-					Code:    "PROXY_ERROR",
-					Message: err.Error(),
-				},
-			}
-		}
+// startNodeProxy starts the SOCKS proxy of a node with the settings of its
+// profile. The primary node always runs its proxy; additional nodes only
+// when the proxy is enabled, so they don't clash on the default address.
+func (h *Handlers) startNodeProxy(n *yggdrasil.Node, profile string) {
+	if h.configStore == nil {
+		return
+	}
+
+	socksConfig := h.SOCKSConfig(profile)
+	if n != h.primary && !socksConfig.Enabled {
+		return
+	}
+	socksConfig.Enabled = true
+
+	h.logger.Info("Starting SOCKS proxy", "profile", profile, "address", socksConfig.ListenAddress)
+	if err := n.SOCKS.Start(socksConfig); err != nil {
+		h.logger.Warn("Failed to start SOCKS proxy", "profile", profile, "error", err)
 	} else {
-		if err := h.socksProxy.Stop(); err != nil {
-			return &Response{
-				Success: false,
-				Error: &Error{
-					Code:    "PROXY_ERROR",
-					Message: err.Error(),
-				},
-			}
-		}
+		h.logger.Info("SOCKS proxy started", "profile", profile, "address", socksConfig.ListenAddress)
 	}
+}
 
-	return &Response{
-		Success: true,
-		Data:    h.socksProxy.GetStats(),
+// nodeEntry describes a node for the node list
+func nodeEntry(id string, n *yggdrasil.Node, primary bool) NodeEntry {
+	entry := NodeEntry{
+		ID:        id,
+		Primary:   primary,
+		State:     n.Service.GetState().String(),
+		PublicKey: n.Config().GetPublicKey(),
+	}
+
+	if info := n.Service.GetNodeInfo(); info != nil {
+		entry.IPv6Address = info.IPv6Address
+		entry.PublicKey = info.PublicKey
+	}
+	if n.SOCKS.IsRunning() {
+		entry.Proxy = n.SOCKS.GetConfig().ListenAddress
 	}
+
+	return entry
 }
 
-func (h *Handlers) handleProxyStatus(req *Request) *Response {
+// emitNodesChanged notifies the frontend that the node registry changed
+func (h *Handlers) emitNodesChanged() {
+	if h.bridge == nil {
+		return
+	}
+	h.bridge.Emit(EventNodesChanged, h.ListNodes())
+}
+
+func (h *Handlers) handleNodesList(req *Request) *Response {
 	return &Response{
 		Success: true,
-		Data:    h.socksProxy.GetStats(),
+		Data:    h.ListNodes(),
 	}
 }
 
-// Mapping handlers
+func (h *Handlers) handleNodesAdd(req *Request) *Response {
+	return h.nodesResponse(req, h.AddNode)
+}
 
-func (h *Handlers) handleMappingAdd(req *Request) *Response {
-	var mapping yggdrasil.PortMapping
-	if err := json.Unmarshal(req.Payload, &mapping); err != nil {
+func (h *Handlers) handleNodesRemove(req *Request) *Response {
+	return h.nodesResponse(req, h.RemoveNode)
+}
+
+// nodesResponse applies change to the node ID in the request and returns
+// the node list
+func (h *Handlers) nodesResponse(req *Request, change func(id string) error) *Response {
+	var payload NodeRequest
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
 				Code:    "PARSE_ERROR",
-				Message: "Failed to parse mapping",
+				Message: "Failed to parse node request",
 			},
 		}
 	}
 
-	if err := h.mappingManager.AddMapping(mapping); err != nil {
+	if err := change(payload.ID); err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
-				Code:    "MAPPING_ERROR",
+				Code:    "NODE_ERROR",
 				Message: err.Error(),
 			},
 		}
 	}
 
+	return h.handleNodesList(req)
+}
+
+// Session handlers
+
+func (h *Handlers) handleSessionsList(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	sessions, err := n.Sessions.GetSessions()
+	if err != nil {
+		// Node is not running, there are no sessions
+		return &Response{
+			Success: true,
+			Data:    []SessionInfo{},
+		}
+	}
+
+	list := make([]SessionInfo, len(sessions))
+	for i, s := range sessions {
+		list[i] = SessionInfo{
+			Address:   s.Address,
+			PublicKey: s.PublicKey,
+			RxBytes:   int64(s.RxBytes),
+			TxBytes:   int64(s.TxBytes),
+			Uptime:    s.Uptime,
+		}
+	}
+
 	return &Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"id":    mapping.ID,
-			"added": true,
-		},
+		Data:    list,
 	}
 }
 
-func (h *Handlers) handleMappingRemove(req *Request) *Response {
-	var payload struct {
-		ID string `json:"id"`
+func (h *Handlers) handleStatsHistory(req *Request) *Response {
+	_, profile, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
 	}
 
-	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+	if h.history == nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
-				Code:    "PARSE_ERROR",
-				Message: "Failed to parse mapping ID",
+				Code:    "HISTORY_UNAVAILABLE",
+				Message: "Traffic history is not recorded",
 			},
 		}
 	}
 
-	if err := h.mappingManager.RemoveMapping(payload.ID); err != nil {
+	var payload StatsHistoryRequest
+	if len(req.Payload) > 0 {
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "PARSE_ERROR",
+					Message: "Failed to parse history request",
+				},
+			}
+		}
+	}
+
+	var from, to time.Time
+	if payload.From > 0 {
+		from = time.UnixMilli(payload.From)
+	}
+	if payload.To > 0 {
+		to = time.UnixMilli(payload.To)
+	}
+
+	series, err := h.history.Query(profile, payload.Series, payload.Resolution, from, to)
+	if err != nil {
 		return &Response{
 			Success: false,
 			Error: &Error{
-				Code:    "MAPPING_ERROR",
+				Code:    "HISTORY_ERROR",
 				Message: err.Error(),
 			},
 		}
 	}
 
+	result := &StatsHistoryResult{Node: profile, Resolution: payload.Resolution, Series: series}
+	if result.Resolution == "" {
+		result.Resolution = yggdrasil.HistoryHour
+	}
+
+	switch payload.Format {
+	case "", "json":
+	case "csv":
+		var buf strings.Builder
+		if err := yggdrasil.WriteTrafficCSV(&buf, series); err != nil {
+			return &Response{
+				Success: false,
+				Error: &Error{
+					Code:    "HISTORY_ERROR",
+					Message: err.Error(),
+				},
+			}
+		}
+		result.Series = nil
+		result.Content = buf.String()
+	default:
+		return &Response{
+			Success: false,
+			Error: &Error{
+				Code:    "INVALID_FORMAT",
+				Message: "Format must be json or csv",
+			},
+		}
+	}
+
 	return &Response{
 		Success: true,
-		Data: map[string]interface{}{
-			"id":      payload.ID,
-			"removed": true,
+		Data:    result,
+	}
+}
+
+func (h *Handlers) handleSessionsStats(req *Request) *Response {
+	n, _, errResp := h.nodeFor(req)
+	if errResp != nil {
+		return errResp
+	}
+
+	stats := n.Sessions.GetSessionStats()
+
+	return &Response{
+		Success: true,
+		Data: &SessionStats{
+			Total:        stats.Total,
+			TotalRxBytes: int64(stats.TotalRxBytes),
+			TotalTxBytes: int64(stats.TotalTxBytes),
 		},
 	}
 }
@@ -749,3 +3307,25 @@ func (h *Handlers) handleLogClear(req *Request) *Response {
 		},
 	}
 }
+
+// Control API handlers
+
+func (h *Handlers) handleControlStatus(req *Request) *Response {
+	if h.controlServer == nil {
+		return &Response{
+			Success: true,
+			Data: map[string]interface{}{
+				"running": false,
+			},
+		}
+	}
+
+	return &Response{
+		Success: true,
+		Data: map[string]interface{}{
+			"running": h.controlServer.IsRunning(),
+			"address": h.controlServer.Address(),
+			"token":   h.controlServer.Token(),
+		},
+	}
+}
@@ -0,0 +1,551 @@
+package ipc
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil"
+)
+
+// MetricsPath is the URL path of the Prometheus metrics
+const MetricsPath = "/metrics"
+
+// latencyBuckets are the upper bounds of the IPC handler latency
+// histogram in seconds
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 30}
+
+// HandlerLatency is the latency histogram of one IPC event
+type HandlerLatency struct {
+	Event   string
+	Count   uint64
+	Errors  uint64
+	Sum     float64  // seconds
+	Buckets []uint64 // Requests per bucket of latencyBuckets, not cumulative
+}
+
+// handlerLatency records IPC handler latency per event
+type handlerLatency struct {
+	mu     sync.Mutex
+	events map[string]*HandlerLatency
+}
+
+func newHandlerLatency() *handlerLatency {
+	return &handlerLatency{events: make(map[string]*HandlerLatency)}
+}
+
+// observe records a handled request
+func (hl *handlerLatency) observe(event string, d time.Duration, success bool) {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	l, ok := hl.events[event]
+	if !ok {
+		l = &HandlerLatency{Event: event, Buckets: make([]uint64, len(latencyBuckets))}
+		hl.events[event] = l
+	}
+
+	seconds := d.Seconds()
+	l.Count++
+	l.Sum += seconds
+	if !success {
+		l.Errors++
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			l.Buckets[i]++
+			break
+		}
+	}
+}
+
+// HandlerLatency returns the handler latency of every event handled so
+// far, sorted by event
+func (b *Bridge) HandlerLatency() []HandlerLatency {
+	b.latency.mu.Lock()
+	defer b.latency.mu.Unlock()
+
+	result := make([]HandlerLatency, 0, len(b.latency.events))
+	for _, l := range b.latency.events {
+		c := *l
+		c.Buckets = append([]uint64{}, l.Buckets...)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Event < result[j].Event
+	})
+	return result
+}
+
+// MetricsServer exposes node, peer, proxy, mapping, session and IPC
+// metrics in the Prometheus text format. It listens on a loopback address
+// and, when a Yggdrasil port is set, on the primary node while it runs.
+// The Yggdrasil listener only accepts the allowed public keys.
+type MetricsServer struct {
+	mu        sync.Mutex
+	handlers  *Handlers
+	bridge    *Bridge
+	logger    *logger.Logger
+	settings  config.MetricsSettings
+	listening bool // State listener is registered
+	local     *http.Server
+	localAddr string
+	ygg       *http.Server
+}
+
+// NewMetricsServer creates a stopped metrics server
+func NewMetricsServer(handlers *Handlers, bridge *Bridge, log *logger.Logger) *MetricsServer {
+	return &MetricsServer{
+		handlers: handlers,
+		bridge:   bridge,
+		logger:   log,
+	}
+}
+
+// Apply starts, stops or restarts the listeners to match the settings
+func (ms *MetricsServer) Apply(settings config.MetricsSettings) error {
+	if settings.ListenAddress == "" {
+		settings.ListenAddress = config.DefaultMetricsAddress
+	}
+	if settings.Enabled {
+		if err := validateLoopbackAddress(settings.ListenAddress); err != nil {
+			return err
+		}
+		if settings.YggdrasilPort < 0 || settings.YggdrasilPort > 65535 {
+			return fmt.Errorf("invalid Yggdrasil port %d", settings.YggdrasilPort)
+		}
+		if settings.YggdrasilPort > 0 {
+			if len(settings.AllowedPublicKeys) == 0 {
+				return fmt.Errorf("metrics on the Yggdrasil port need allowed public keys")
+			}
+			if _, err := yggdrasil.NewAccessList(settings.AllowedPublicKeys); err != nil {
+				return err
+			}
+		}
+	}
+
+	ms.mu.Lock()
+	ms.settings = settings
+	if !ms.listening {
+		ms.listening = true
+		ms.handlers.GetService().AddStateListener(ms.onState)
+	}
+	ms.mu.Unlock()
+
+	ms.stopYggdrasil()
+	if !settings.Enabled {
+		return ms.stopLocal()
+	}
+
+	if ms.LocalAddress() != settings.ListenAddress {
+		if err := ms.stopLocal(); err != nil {
+			return err
+		}
+		if err := ms.startLocal(settings.ListenAddress); err != nil {
+			return err
+		}
+	}
+
+	if settings.YggdrasilPort > 0 && ms.handlers.GetService().IsRunning() {
+		ms.startYggdrasil()
+	}
+	return nil
+}
+
+// Stop stops both listeners
+func (ms *MetricsServer) Stop() error {
+	ms.stopYggdrasil()
+	return ms.stopLocal()
+}
+
+// LocalAddress returns the loopback listen address, or empty string if
+// not running
+func (ms *MetricsServer) LocalAddress() string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.localAddr
+}
+
+// onState serves metrics on the primary node while it is running
+func (ms *MetricsServer) onState(state yggdrasil.ServiceState, info *yggdrasil.NodeInfo) {
+	ms.mu.Lock()
+	settings := ms.settings
+	ms.mu.Unlock()
+
+	switch state {
+	case yggdrasil.StateRunning:
+		if settings.Enabled && settings.YggdrasilPort > 0 {
+			ms.startYggdrasil()
+		}
+	case yggdrasil.StateStopping:
+		ms.stopYggdrasil()
+	}
+}
+
+// serve runs an HTTP server for the metrics on listener
+func (ms *MetricsServer) serve(listener net.Listener) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, ms)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ms.logger.Error("Metrics server error", "error", err)
+		}
+	}()
+	return server
+}
+
+func (ms *MetricsServer) startLocal(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	ms.mu.Lock()
+	ms.local = ms.serve(listener)
+	ms.localAddr = address
+	ms.mu.Unlock()
+
+	ms.logger.Info("Metrics server started", "address", listener.Addr().String())
+	return nil
+}
+
+func (ms *MetricsServer) stopLocal() error {
+	ms.mu.Lock()
+	server := ms.local
+	ms.local, ms.localAddr = nil, ""
+	ms.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return err
+	}
+	ms.logger.Info("Metrics server stopped")
+	return nil
+}
+
+// startYggdrasil listens on the Yggdrasil port of the primary node
+func (ms *MetricsServer) startYggdrasil() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.ygg != nil {
+		return
+	}
+	ns := ms.handlers.GetService().GetNetstack()
+	if ns == nil {
+		return
+	}
+	acl, err := yggdrasil.NewAccessList(ms.settings.AllowedPublicKeys)
+	if err != nil || len(ms.settings.AllowedPublicKeys) == 0 {
+		ms.logger.Warn("Not serving metrics on Yggdrasil without allowed public keys", "error", err)
+		return
+	}
+
+	listener, err := ns.ListenTCP(&net.TCPAddr{Port: ms.settings.YggdrasilPort})
+	if err != nil {
+		ms.logger.Warn("Failed to serve metrics on Yggdrasil", "port", ms.settings.YggdrasilPort, "error", err)
+		return
+	}
+	ms.ygg = ms.serve(&allowListener{Listener: listener, acl: acl})
+	ms.logger.Info("Metrics served on Yggdrasil", "port", ms.settings.YggdrasilPort)
+}
+
+func (ms *MetricsServer) stopYggdrasil() {
+	ms.mu.Lock()
+	server := ms.ygg
+	ms.ygg = nil
+	ms.mu.Unlock()
+
+	if server != nil {
+		server.Close()
+	}
+}
+
+// allowListener accepts only connections from remotes on its access list
+type allowListener struct {
+	net.Listener
+	acl *yggdrasil.AccessList
+}
+
+// Accept implements net.Listener. Other remotes are disconnected.
+func (l *allowListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.acl.Allows(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (ms *MetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ms.WriteMetrics(w)
+}
+
+// WriteMetrics writes all metrics in the Prometheus text format
+func (ms *MetricsServer) WriteMetrics(w io.Writer) {
+	nodes := ms.handlers.allNodes()
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mw := &metricsWriter{w: w}
+	writeNodeMetrics(mw, names, nodes)
+	writePeerMetrics(mw, names, nodes)
+	writeProxyMetrics(mw, names, nodes)
+	writeMappingMetrics(mw, names, nodes)
+	writeHandlerMetrics(mw, ms.bridge.HandlerLatency())
+}
+
+func writeNodeMetrics(mw *metricsWriter, names []string, nodes map[string]*yggdrasil.Node) {
+	mw.family("yggstack_node_up", "Whether the node is running.", "gauge")
+	for _, name := range names {
+		mw.sample("yggstack_node_up", boolValue(nodes[name].Service.IsRunning()), "node", name)
+	}
+
+	mw.family("yggstack_node_uptime_seconds", "Time since the node started.", "gauge")
+	for _, name := range names {
+		mw.sample("yggstack_node_uptime_seconds", nodes[name].Service.GetUptime().Seconds(), "node", name)
+	}
+
+	mw.family("yggstack_sessions", "Active sessions with other nodes.", "gauge")
+	for _, name := range names {
+		mw.sample("yggstack_sessions", float64(nodes[name].Sessions.GetSessionStats().Total), "node", name)
+	}
+}
+
+func writePeerMetrics(mw *metricsWriter, names []string, nodes map[string]*yggdrasil.Node) {
+	type nodePeer struct {
+		node string
+		peer yggdrasil.PeerInfo
+	}
+	var peers []nodePeer
+	connected := make(map[string]int, len(names))
+	for _, name := range names {
+		list, _ := nodes[name].Peers.GetPeers()
+		for _, p := range list {
+			peers = append(peers, nodePeer{name, p})
+			if p.Connected {
+				connected[name]++
+			}
+		}
+	}
+
+	mw.family("yggstack_peers_connected", "Connected peers.", "gauge")
+	for _, name := range names {
+		mw.sample("yggstack_peers_connected", float64(connected[name]), "node", name)
+	}
+
+	metrics := []struct {
+		name, help, typ string
+		value           func(p yggdrasil.PeerInfo) float64
+	}{
+		{"yggstack_peer_up", "Whether the peer is connected.", "gauge",
+			func(p yggdrasil.PeerInfo) float64 { return boolValue(p.Connected) }},
+		{"yggstack_peer_receive_bytes_total", "Bytes received from the peer on its current connection.", "counter",
+			func(p yggdrasil.PeerInfo) float64 { return float64(p.RxBytes) }},
+		{"yggstack_peer_transmit_bytes_total", "Bytes sent to the peer on its current connection.", "counter",
+			func(p yggdrasil.PeerInfo) float64 { return float64(p.TxBytes) }},
+		{"yggstack_peer_latency_seconds", "Round trip time to the peer.", "gauge",
+			func(p yggdrasil.PeerInfo) float64 { return p.Latency / 1000 }},
+		{"yggstack_peer_uptime_seconds", "Time since the peer connected.", "gauge",
+			func(p yggdrasil.PeerInfo) float64 { return float64(p.Uptime) }},
+	}
+	for _, m := range metrics {
+		mw.family(m.name, m.help, m.typ)
+		for _, np := range peers {
+			mw.sample(m.name, m.value(np.peer), "node", np.node, "uri", peerLabel(np.peer.URI),
+				"address", np.peer.Address, "inbound", strconv.FormatBool(np.peer.Inbound))
+		}
+	}
+}
+
+// peerLabel returns a peer URI without the credentials and options, such
+// as passwords or pinned keys, that may be part of it
+func peerLabel(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery, u.ForceQuery, u.Fragment = "", false, ""
+	return u.String()
+}
+
+func writeProxyMetrics(mw *metricsWriter, names []string, nodes map[string]*yggdrasil.Node) {
+	stats := make([]*yggdrasil.SOCKSStats, len(names))
+	for i, name := range names {
+		stats[i] = nodes[name].SOCKS.GetStats()
+	}
+
+	metrics := []struct {
+		name, help, typ string
+		value           func(s *yggdrasil.SOCKSStats) float64
+	}{
+		{"yggstack_proxy_up", "Whether the SOCKS proxy is running.", "gauge",
+			func(s *yggdrasil.SOCKSStats) float64 { return boolValue(s.Enabled) }},
+		{"yggstack_proxy_active_connections", "Open SOCKS proxy connections.", "gauge",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.ActiveConnections) }},
+		{"yggstack_proxy_connections_total", "SOCKS proxy connections since the proxy started.", "counter",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.TotalConnections) }},
+		{"yggstack_proxy_receive_bytes_total", "Bytes received through the SOCKS proxy.", "counter",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.BytesIn) }},
+		{"yggstack_proxy_transmit_bytes_total", "Bytes sent through the SOCKS proxy.", "counter",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.BytesOut) }},
+		{"yggstack_proxy_auth_failures_total", "Failed SOCKS proxy authentications.", "counter",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.AuthFailures) }},
+		{"yggstack_proxy_udp_associations", "Open SOCKS UDP ASSOCIATE relays.", "gauge",
+			func(s *yggdrasil.SOCKSStats) float64 { return float64(s.UDPAssociations) }},
+	}
+	for _, m := range metrics {
+		mw.family(m.name, m.help, m.typ)
+		for i, name := range names {
+			mw.sample(m.name, m.value(stats[i]), "node", name)
+		}
+	}
+}
+
+func writeMappingMetrics(mw *metricsWriter, names []string, nodes map[string]*yggdrasil.Node) {
+	type nodeMapping struct {
+		node    string
+		mapping yggdrasil.PortMapping
+		stats   *yggdrasil.MappingStats
+	}
+	var mappings []nodeMapping
+	for _, name := range names {
+		list := nodes[name].Mappings.GetMappings()
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		for _, m := range list {
+			stats, err := nodes[name].Mappings.GetMappingStats(m.ID)
+			if err != nil {
+				continue
+			}
+			mappings = append(mappings, nodeMapping{name, m, stats})
+		}
+	}
+
+	metrics := []struct {
+		name, help, typ string
+		value           func(m nodeMapping) float64
+	}{
+		{"yggstack_mapping_up", "Whether the mapping is forwarding.", "gauge",
+			func(m nodeMapping) float64 { return boolValue(m.mapping.Active) }},
+		{"yggstack_mapping_active_connections", "Open connections of the mapping.", "gauge",
+			func(m nodeMapping) float64 { return float64(m.stats.ActiveConnections) }},
+		{"yggstack_mapping_connections_total", "Connections of the mapping since it was added.", "counter",
+			func(m nodeMapping) float64 { return float64(m.stats.TotalConnections) }},
+		{"yggstack_mapping_rejected_connections_total", "Connections rejected by the mapping limits.", "counter",
+			func(m nodeMapping) float64 { return float64(m.stats.RejectedConnections) }},
+		{"yggstack_mapping_udp_sessions", "Open UDP sessions of the mapping.", "gauge",
+			func(m nodeMapping) float64 { return float64(m.stats.ActiveSessions) }},
+		{"yggstack_mapping_udp_evicted_sessions_total", "UDP sessions closed for being idle.", "counter",
+			func(m nodeMapping) float64 { return float64(m.stats.EvictedSessions) }},
+		{"yggstack_mapping_receive_bytes_total", "Bytes received by the mapping.", "counter",
+			func(m nodeMapping) float64 { return float64(m.stats.BytesIn) }},
+		{"yggstack_mapping_transmit_bytes_total", "Bytes sent by the mapping.", "counter",
+			func(m nodeMapping) float64 { return float64(m.stats.BytesOut) }},
+	}
+	for _, metric := range metrics {
+		mw.family(metric.name, metric.help, metric.typ)
+		for _, m := range mappings {
+			mw.sample(metric.name, metric.value(m), "node", m.node, "mapping", m.mapping.ID,
+				"type", string(m.mapping.Type))
+		}
+	}
+}
+
+func writeHandlerMetrics(mw *metricsWriter, latency []HandlerLatency) {
+	mw.family("yggstack_ipc_request_errors_total", "IPC requests that returned an error.", "counter")
+	for _, l := range latency {
+		mw.sample("yggstack_ipc_request_errors_total", float64(l.Errors), "event", l.Event)
+	}
+
+	mw.family("yggstack_ipc_request_duration_seconds", "Time taken by IPC handlers.", "histogram")
+	for _, l := range latency {
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += l.Buckets[i]
+			mw.sample("yggstack_ipc_request_duration_seconds_bucket", float64(cumulative),
+				"event", l.Event, "le", strconv.FormatFloat(bound, 'g', -1, 64))
+		}
+		mw.sample("yggstack_ipc_request_duration_seconds_bucket", float64(l.Count), "event", l.Event, "le", "+Inf")
+		mw.sample("yggstack_ipc_request_duration_seconds_sum", l.Sum, "event", l.Event)
+		mw.sample("yggstack_ipc_request_duration_seconds_count", float64(l.Count), "event", l.Event)
+	}
+}
+
+// metricsWriter writes the Prometheus text format
+type metricsWriter struct {
+	w io.Writer
+}
+
+// family writes the HELP and TYPE lines of a metric
+func (mw *metricsWriter) family(name, help, typ string) {
+	fmt.Fprintf(mw.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one sample. labels are name and value pairs.
+func (mw *metricsWriter) sample(name string, value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(labelEscaper.Replace(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	b.WriteByte('\n')
+	io.WriteString(mw.w, b.String())
+}
+
+// labelEscaper escapes label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
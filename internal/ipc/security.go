@@ -262,15 +262,32 @@ func (sm *SecurityMiddleware) logSecurityEvent(eventType logger.AuditEventType,
 
 // SensitiveEvents lists events that should be treated with extra care
 var SensitiveEvents = map[string]bool{
-	"config:save":    true,
-	"node:start":     true,
-	"node:stop":      true,
-	"peers:add":      true,
-	"peers:remove":   true,
-	"settings:set":   true,
-	"proxy:config":   true,
-	"mapping:add":    true,
-	"mapping:remove": true,
+	"config:save":     true,
+	"config:import":   true,
+	"config:export":   true,
+	"identity:export": true,
+	"identity:import": true,
+	"keygen:adopt":    true,
+	"node:start":      true,
+	"node:stop":       true,
+	"peers:add":       true,
+	"peers:remove":    true,
+	"settings:set":    true,
+	"proxy:config":    true,
+	"proxy:start":     true,
+	"proxy:stop":      true,
+	"mapping:add":     true,
+	"mapping:remove":  true,
+	"mapping:enable":  true,
+	"mapping:disable": true,
+	"control:status":  true,
+	"profile:create":  true,
+	"profile:clone":   true,
+	"profile:rename":  true,
+	"profile:delete":  true,
+	"profile:switch":  true,
+	"nodes:add":       true,
+	"nodes:remove":    true,
 }
 
 // IsSensitiveEvent checks if an event is sensitive
@@ -300,7 +317,7 @@ func ValidationMiddleware(validator *security.Validator, log *logger.Logger) Mid
 				}
 			}
 
-		case "proxy:config":
+		case "proxy:config", "proxy:start":
 			var payload struct {
 				ListenAddress string `json:"listenAddress"`
 			}
@@ -356,6 +373,36 @@ func ValidationMiddleware(validator *security.Validator, log *logger.Logger) Mid
 						}
 					}
 				}
+				if control, ok := payload["control"].(map[string]interface{}); ok {
+					if addr, ok := control["listenAddress"].(string); ok && addr != "" {
+						if err := validateLoopbackAddress(addr); err != nil {
+							log.Warn("Invalid control API address", "event", event, "error", err)
+							return &Response{
+								Success: false,
+								Error: &Error{
+									Code:    "VALIDATION_ERROR",
+									Message: err.Error(),
+								},
+							}
+						}
+					}
+				}
+				if failover, ok := payload["failover"].(map[string]interface{}); ok {
+					standby, _ := failover["standby"].([]interface{})
+					for _, peer := range standby {
+						uri, _ := peer.(string)
+						if err := validator.ValidatePeerURI(uri); err != nil {
+							log.Warn("Invalid standby peer URI", "event", event, "error", err)
+							return &Response{
+								Success: false,
+								Error: &Error{
+									Code:    "VALIDATION_ERROR",
+									Message: err.Error(),
+								},
+							}
+						}
+					}
+				}
 			}
 		}
 
@@ -43,8 +43,10 @@ const (
 	// Proxy/Mapping events
 	AuditEventProxyStart    AuditEventType = "PROXY_START"
 	AuditEventProxyStop     AuditEventType = "PROXY_STOP"
+	AuditEventProxyAuthFail AuditEventType = "PROXY_AUTH_FAIL"
 	AuditEventMappingAdd    AuditEventType = "MAPPING_ADD"
 	AuditEventMappingRemove AuditEventType = "MAPPING_REMOVE"
+	AuditEventMappingDeny   AuditEventType = "MAPPING_DENY"
 
 	// Application events
 	AuditEventAppStart      AuditEventType = "APP_START"
@@ -145,3 +145,24 @@ func GetConfigPath() string {
 func GetYggdrasilConfigPath() string {
 	return filepath.Join(getDataDir(GetOS()), "yggdrasil.conf")
 }
+
+// GetProfilesDir returns the directory holding named node profiles
+func GetProfilesDir() string {
+	return filepath.Join(getDataDir(GetOS()), "profiles")
+}
+
+// GetTrafficHistoryPath returns the path of the traffic history file
+func GetTrafficHistoryPath() string {
+	return filepath.Join(getDataDir(GetOS()), "traffic.json")
+}
+
+// GetSecureStorePath returns the path of the encrypted fallback secret store
+func GetSecureStorePath() string {
+	return filepath.Join(getDataDir(GetOS()), "secure.dat")
+}
+
+// GetSecureStoreKeyPath returns the path of the key file of the encrypted
+// fallback secret store
+func GetSecureStoreKeyPath() string {
+	return filepath.Join(getDataDir(GetOS()), "secure.key")
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -154,6 +155,20 @@ func (s *EncryptedStore) save() error {
 	return os.Rename(tmpPath, s.path)
 }
 
+// rekey encrypts the store with a new key and salt and saves it
+func (s *EncryptedStore) rekey(machineKey []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	salt, err := GenerateRandomBytes(saltSize)
+	if err != nil {
+		return err
+	}
+	s.salt = salt
+	s.key = deriveKey(machineKey, salt)
+	return s.save()
+}
+
 // Store saves a value to the encrypted store
 func (s *EncryptedStore) Store(key string, value []byte) error {
 	s.mu.Lock()
@@ -299,6 +314,87 @@ func GenerateMachineKey() ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// legacyMachineKey returns the key older versions derived from machine
+// identifiers. Those are readable by every user, so it is only used to
+// migrate existing stores to a key from LoadStoreKey.
+func legacyMachineKey() []byte {
+	h := sha256.Sum256([]byte(ServiceName + ":" + getMachineID()))
+	return h[:]
+}
+
+// LoadStoreKey returns the key of the encrypted fallback store. The key is
+// random and kept in a file only the owner can read; a missing file is
+// created, also when another process races to create it.
+func LoadStoreKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err == nil {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("invalid secure store key file: %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err = GenerateRandomBytes(keySize)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	// Write the key aside and link it into place, so a reader never sees a
+	// partly written file and the first process to create it wins
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".secure-key-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if _, err := tmp.Write(key); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return LoadStoreKey(path)
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// openFallbackStore opens the encrypted store at path with the key from
+// keyPath. A store written by an older version is re-encrypted with it.
+func openFallbackStore(path, keyPath string) (*EncryptedStore, error) {
+	key, err := LoadStoreKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secure store key: %w", err)
+	}
+
+	store, err := NewEncryptedStore(path, key)
+	if !errors.Is(err, ErrDecryptionFailed) {
+		return store, err
+	}
+
+	legacy, legacyErr := NewEncryptedStore(path, legacyMachineKey())
+	if legacyErr != nil {
+		return nil, err
+	}
+	if err := legacy.rekey(key); err != nil {
+		return nil, fmt.Errorf("failed to re-encrypt secure store: %w", err)
+	}
+	return legacy, nil
+}
+
 // getMachineID returns a platform-specific machine identifier
 func getMachineID() string {
 	// Try common locations for machine ID
@@ -329,6 +425,38 @@ func DeriveKeyFromPassword(password string, salt []byte) []byte {
 	return pbkdf2.Key([]byte(password), salt, pbkdf2Iterations, keySize, sha256.New)
 }
 
+// EncryptWithPassword encrypts data with a key derived from password.
+// A random salt is prepended to the result so it can be decrypted with
+// DecryptWithPassword.
+func EncryptWithPassword(plaintext []byte, password string) ([]byte, error) {
+	salt, err := GenerateRandomBytes(saltSize)
+	if err != nil {
+		return nil, err
+	}
+
+	key := DeriveKeyFromPassword(password, salt)
+	defer ZeroBytes(key)
+
+	ciphertext, err := encrypt(plaintext, key)
+	if err != nil {
+		return nil, ErrEncryptionFailed
+	}
+
+	return append(salt, ciphertext...), nil
+}
+
+// DecryptWithPassword decrypts data produced by EncryptWithPassword
+func DecryptWithPassword(data []byte, password string) ([]byte, error) {
+	if len(data) < saltSize+nonceSize {
+		return nil, ErrDecryptionFailed
+	}
+
+	key := DeriveKeyFromPassword(password, data[:saltSize])
+	defer ZeroBytes(key)
+
+	return decrypt(data[saltSize:], key)
+}
+
 // HashPassword hashes a password using SHA-256
 // For password storage, use bcrypt or argon2 instead
 func HashPassword(password string) []byte {
@@ -0,0 +1,74 @@
+package security
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLoadStoreKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "secure.key")
+
+	key, err := LoadStoreKey(path)
+	if err != nil {
+		t.Fatalf("LoadStoreKey() error = %v", err)
+	}
+	if len(key) != keySize || bytes.Equal(key, legacyMachineKey()) {
+		t.Fatalf("LoadStoreKey() key length = %d", len(key))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	again, err := LoadStoreKey(path)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Errorf("second LoadStoreKey() = %x, %v, want the saved key", again, err)
+	}
+
+	os.WriteFile(path, []byte("short"), 0600)
+	if _, err := LoadStoreKey(path); err == nil {
+		t.Error("a truncated key file should be rejected")
+	}
+}
+
+func TestOpenFallbackStore_MigratesLegacyKey(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "secure.dat")
+	keyPath := filepath.Join(dir, "secure.key")
+
+	// Store written by an older version with the machine ID key
+	legacy, err := NewEncryptedStore(storePath, legacyMachineKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.Store("control-token", []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := openFallbackStore(storePath, keyPath)
+	if err != nil {
+		t.Fatalf("openFallbackStore() error = %v", err)
+	}
+	if value, err := store.Retrieve("control-token"); err != nil || string(value) != "secret" {
+		t.Fatalf("Retrieve() = %q, %v", value, err)
+	}
+
+	// The file no longer opens with the machine ID key, only with the new one
+	if _, err := NewEncryptedStore(storePath, legacyMachineKey()); err != ErrDecryptionFailed {
+		t.Errorf("legacy key still opens the store, error = %v", err)
+	}
+	reopened, err := openFallbackStore(storePath, keyPath)
+	if err != nil {
+		t.Fatalf("reopening error = %v", err)
+	}
+	if value, err := reopened.Retrieve("control-token"); err != nil || string(value) != "secret" {
+		t.Errorf("Retrieve() after reopening = %q, %v", value, err)
+	}
+}
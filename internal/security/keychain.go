@@ -9,6 +9,15 @@ import (
 const (
 	ServiceName = "yggstack-gui"
 	AccountName = "yggdrasil-private-key"
+
+	// ControlTokenAccount stores the bearer token of the local control API
+	ControlTokenAccount = "control-api-token"
+
+	// SOCKSCredentialsAccount prefixes the SOCKS proxy credentials of a profile
+	SOCKSCredentialsAccount = "socks-credentials"
+
+	// UpstreamCredentialsAccount prefixes the upstream proxy credentials of a profile
+	UpstreamCredentialsAccount = "upstream-credentials"
 )
 
 // Common errors
@@ -47,8 +56,9 @@ type SecureStore struct {
 }
 
 // NewSecureStore creates a new secure storage with platform keychain
-// and fallback to encrypted file storage
-func NewSecureStore(fallbackPath string, machineKey []byte) (*SecureStore, error) {
+// and fallback to encrypted file storage. The key of the fallback file is
+// read from keyPath, see LoadStoreKey.
+func NewSecureStore(fallbackPath, keyPath string) (*SecureStore, error) {
 	store := &SecureStore{}
 
 	// Try to initialize platform keychain
@@ -59,7 +69,7 @@ func NewSecureStore(fallbackPath string, machineKey []byte) (*SecureStore, error
 	} else {
 		// Fall back to encrypted file storage
 		var err error
-		store.fallback, err = NewEncryptedStore(fallbackPath, machineKey)
+		store.fallback, err = openFallbackStore(fallbackPath, keyPath)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,165 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+)
+
+// maxDenialTracked bounds the set of remotes whose denials are throttled
+const maxDenialTracked = 1024
+
+// MappingDenial describes a connection refused by a mapping's access list
+type MappingDenial struct {
+	MappingID string `json:"mappingId"`
+	Remote    string `json:"remote"`
+	Reason    string `json:"reason"`
+}
+
+// mappingACL holds the parsed allow and deny lists of a mapping. Entries
+// are Yggdrasil public keys (hex), addresses or subnets such as 300::/64.
+// A public key matches both the node address and the /64 subnet of the key.
+type mappingACL struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+
+	mu       sync.Mutex
+	deniedAt map[string]time.Time
+}
+
+// parseACLEntry parses an access list entry into the networks it covers
+func parseACLEntry(entry string) ([]*net.IPNet, error) {
+	entry = strings.TrimSpace(entry)
+
+	if len(entry) == hex.EncodedLen(ed25519.PublicKeySize) {
+		if key, err := hex.DecodeString(entry); err == nil {
+			addr := address.AddrForKey(ed25519.PublicKey(key))
+			subnet := address.SubnetForKey(ed25519.PublicKey(key))
+			if addr == nil || subnet == nil {
+				return nil, fmt.Errorf("invalid public key: %s", entry)
+			}
+			subnetIP := make(net.IP, net.IPv6len)
+			copy(subnetIP, subnet[:])
+			return []*net.IPNet{
+				{IP: net.IP(addr[:]), Mask: net.CIDRMask(128, 128)},
+				{IP: subnetIP, Mask: net.CIDRMask(64, 128)},
+			}, nil
+		}
+	}
+
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil || network.IP.To4() != nil {
+			return nil, fmt.Errorf("invalid subnet: %s", entry)
+		}
+		return []*net.IPNet{network}, nil
+	}
+
+	ip := net.ParseIP(strings.Trim(entry, "[]"))
+	if ip == nil || ip.To4() != nil {
+		return nil, fmt.Errorf("invalid ACL entry %q: expected a public key, IPv6 address or subnet", entry)
+	}
+	return []*net.IPNet{{IP: ip, Mask: net.CIDRMask(128, 128)}}, nil
+}
+
+// newMappingACL parses the access lists of a mapping
+func newMappingACL(allow, deny []string) (*mappingACL, error) {
+	acl := &mappingACL{}
+	for _, entry := range allow {
+		networks, err := parseACLEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		acl.allow = append(acl.allow, networks...)
+	}
+	for _, entry := range deny {
+		networks, err := parseACLEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		acl.deny = append(acl.deny, networks...)
+	}
+	return acl, nil
+}
+
+// AccessList admits remotes by public key, address or subnet, with the
+// entries of a mapping allow list. Unlike a mapping, an empty list admits
+// nobody.
+type AccessList struct {
+	acl *mappingACL
+}
+
+// NewAccessList parses an allow list
+func NewAccessList(allow []string) (*AccessList, error) {
+	acl, err := newMappingACL(allow, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessList{acl: acl}, nil
+}
+
+// Allows reports whether remote is on the list
+func (l *AccessList) Allows(remote net.Addr) bool {
+	if len(l.acl.allow) == 0 {
+		return false
+	}
+	ok, _ := l.acl.check(remote)
+	return ok
+}
+
+// check reports whether a remote address may connect. Deny entries take
+// precedence; a non-empty allow list admits only the listed remotes.
+func (acl *mappingACL) check(remote net.Addr) (bool, string) {
+	if acl == nil || (len(acl.allow) == 0 && len(acl.deny) == 0) {
+		return true, ""
+	}
+
+	var ip net.IP
+	switch addr := remote.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	}
+	if ip == nil {
+		return false, "unknown remote address"
+	}
+
+	for _, network := range acl.deny {
+		if network.Contains(ip) {
+			return false, "denied by " + network.String()
+		}
+	}
+	if len(acl.allow) == 0 {
+		return true, ""
+	}
+	for _, network := range acl.allow {
+		if network.Contains(ip) {
+			return true, ""
+		}
+	}
+	return false, "not in allow list"
+}
+
+// shouldReport reports whether a denial of remote should be passed on.
+// Repeated denials of the same remote within rejectLogInterval, such as
+// every packet of a UDP client, are reported once.
+func (acl *mappingACL) shouldReport(remote string, now time.Time) bool {
+	acl.mu.Lock()
+	defer acl.mu.Unlock()
+
+	if last, ok := acl.deniedAt[remote]; ok && now.Sub(last) < rejectLogInterval {
+		return false
+	}
+	if acl.deniedAt == nil || len(acl.deniedAt) >= maxDenialTracked {
+		acl.deniedAt = make(map[string]time.Time)
+	}
+	acl.deniedAt[remote] = now
+	return true
+}
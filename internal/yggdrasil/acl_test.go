@@ -0,0 +1,101 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestMappingACL_Check(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyAddr := net.IP(address.AddrForKey(publicKey)[:])
+	subnet := address.SubnetForKey(publicKey)
+	keySubnetHost := make(net.IP, net.IPv6len)
+	copy(keySubnetHost, subnet[:])
+	keySubnetHost[15] = 1
+
+	acl, err := newMappingACL(
+		[]string{hex.EncodeToString(publicKey), "300:1234::/64", "[200::1]"},
+		[]string{"300:1234::dead"},
+	)
+	if err != nil {
+		t.Fatalf("newMappingACL() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ip   string
+		want bool
+	}{
+		{"key address", keyAddr.String(), true},
+		{"key subnet", keySubnetHost.String(), true},
+		{"allowed subnet", "300:1234::1", true},
+		{"allowed address", "200::1", true},
+		{"denied in allowed subnet", "300:1234::dead", false},
+		{"not listed", "200::2", false},
+	}
+	for _, tt := range tests {
+		allowed, reason := acl.check(&net.TCPAddr{IP: net.ParseIP(tt.ip), Port: 22})
+		if allowed != tt.want {
+			t.Errorf("%s: check(%s) = %v (%s), want %v", tt.name, tt.ip, allowed, reason, tt.want)
+		}
+	}
+
+	// Deny only admits everything else
+	acl, _ = newMappingACL(nil, []string{"200::2"})
+	if ok, _ := acl.check(&net.UDPAddr{IP: net.ParseIP("200::3")}); !ok {
+		t.Error("deny list should admit unlisted remotes")
+	}
+	if ok, _ := acl.check(&net.UDPAddr{IP: net.ParseIP("200::2")}); ok {
+		t.Error("deny list should refuse listed remotes")
+	}
+
+	for _, entry := range []string{"example.com", "10.0.0.0/8", "192.0.2.1", "zz"} {
+		if _, err := newMappingACL([]string{entry}, nil); err == nil {
+			t.Errorf("newMappingACL(%q) should fail", entry)
+		}
+	}
+}
+
+func TestMappingManager_DeniesRemote(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	mm := NewMappingManager(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	if err := mm.AddMapping(PortMapping{Type: MappingLocalTCP, Source: "127.0.0.1:8080",
+		Target: "[200::1]:80", Allow: []string{"200::1"}}); err == nil {
+		t.Error("local mapping should not accept an access list")
+	}
+
+	var denials []MappingDenial
+	mm.SetDenyHandler(func(d MappingDenial) { denials = append(denials, d) })
+
+	acl, _ := newMappingACL([]string{"200::1"}, nil)
+	fwd := &portForwarder{mapping: PortMapping{ID: "ssh"}, acl: acl, logger: log}
+
+	if !mm.allowRemote(fwd, &net.UDPAddr{IP: net.ParseIP("200::1"), Port: 5000}) {
+		t.Error("allowed remote was denied")
+	}
+	denied := &net.UDPAddr{IP: net.ParseIP("200::2"), Port: 5000}
+	for i := 0; i < 3; i++ {
+		if mm.allowRemote(fwd, denied) {
+			t.Fatal("unlisted remote was allowed")
+		}
+	}
+
+	// Repeated packets from one client are reported once
+	if len(denials) != 1 || denials[0].MappingID != "ssh" || denials[0].Remote != denied.String() {
+		t.Errorf("denials = %+v", denials)
+	}
+	if !fwd.acl.shouldReport(denied.String(), time.Now().Add(rejectLogInterval)) {
+		t.Error("denial should be reported again after the interval")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -28,23 +29,32 @@ type Config struct {
 	MulticastInterfaces []string            `json:"MulticastInterfaces"`
 	AllowedPublicKeys   []string            `json:"AllowedPublicKeys"`
 
-	// SOCKS Proxy settings
-	SOCKS struct {
-		Enabled       bool   `json:"Enabled"`
-		ListenAddress string `json:"ListenAddress"`
-		Nameserver    string `json:"Nameserver"`
-	} `json:"SOCKS"`
+	// Node information visible to the network
+	NodeInfo        map[string]interface{} `json:"NodeInfo,omitempty"`
+	NodeInfoPrivacy bool                   `json:"NodeInfoPrivacy,omitempty"`
 
-	// Port forwarding mappings
-	Mappings struct {
-		LocalTCP  []MappingConfig `json:"LocalTCP"`
-		LocalUDP  []MappingConfig `json:"LocalUDP"`
-		RemoteTCP []MappingConfig `json:"RemoteTCP"`
-		RemoteUDP []MappingConfig `json:"RemoteUDP"`
-	} `json:"Mappings"`
+	// Legacy SOCKS proxy and port mapping sections. Both are stored in
+	// config.json now and are only read to migrate older config files.
+	SOCKS    *LegacySOCKSConfig `json:"SOCKS,omitempty"`
+	Mappings *LegacyMappings    `json:"Mappings,omitempty"`
 }
 
-// MappingConfig represents a port mapping configuration
+// LegacySOCKSConfig is the SOCKS section of older config files
+type LegacySOCKSConfig struct {
+	Enabled       bool   `json:"Enabled"`
+	ListenAddress string `json:"ListenAddress"`
+	Nameserver    string `json:"Nameserver"`
+}
+
+// LegacyMappings is the mappings section of older config files
+type LegacyMappings struct {
+	LocalTCP  []MappingConfig `json:"LocalTCP"`
+	LocalUDP  []MappingConfig `json:"LocalUDP"`
+	RemoteTCP []MappingConfig `json:"RemoteTCP"`
+	RemoteUDP []MappingConfig `json:"RemoteUDP"`
+}
+
+// MappingConfig represents a port mapping in older config files
 type MappingConfig struct {
 	Name        string `json:"name"`
 	Enabled     bool   `json:"enabled"`
@@ -69,6 +79,7 @@ type ConfigManager struct {
 	mu     sync.RWMutex
 	config *Config
 	path   string
+	keys   KeyStore // Holds the private key when set, instead of the file
 	logger *logger.Logger
 }
 
@@ -88,6 +99,28 @@ func NewConfigManager(log *logger.Logger) *ConfigManager {
 	return cm
 }
 
+// NewConfigManagerWithPath creates a config manager for a custom path
+func NewConfigManagerWithPath(path string, log *logger.Logger) *ConfigManager {
+	return newConfigManagerWithKeys(path, nil, log)
+}
+
+// newConfigManagerWithKeys creates a config manager for a custom path that
+// keeps the private key in ks
+func newConfigManagerWithKeys(path string, ks KeyStore, log *logger.Logger) *ConfigManager {
+	cm := &ConfigManager{
+		config: defaultConfig(),
+		path:   path,
+		keys:   ks,
+		logger: log,
+	}
+
+	if err := cm.Load(); err != nil {
+		log.Debug("Failed to load config, using defaults", "path", path, "error", err)
+	}
+
+	return cm
+}
+
 // defaultConfig returns a default configuration
 func defaultConfig() *Config {
 	cfg := &Config{
@@ -97,9 +130,6 @@ func defaultConfig() *Config {
 		MulticastInterfaces: []string{},
 		AllowedPublicKeys:   []string{},
 	}
-	cfg.SOCKS.Enabled = false
-	cfg.SOCKS.ListenAddress = "127.0.0.1:1080"
-	cfg.SOCKS.Nameserver = ""
 	return cfg
 }
 
@@ -112,8 +142,22 @@ func DefaultPeers() []string {
 	}
 }
 
-// Load loads configuration from disk
+// Load loads configuration from disk. With a key store the private key is
+// read from it; a plaintext key left in the file is moved to the store.
 func (cm *ConfigManager) Load() error {
+	migrate, err := cm.load()
+	if err != nil || !migrate {
+		return err
+	}
+
+	// Saving moves the key to the store and scrubs it from the file
+	cm.logger.Info("Moving private key to secure storage", "path", cm.GetPath())
+	return cm.Save()
+}
+
+// load reads the config file and reports whether it holds a plaintext
+// private key that should move to the key store
+func (cm *ConfigManager) load() (bool, error) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
@@ -121,14 +165,26 @@ func (cm *ConfigManager) Load() error {
 	if err != nil {
 		if os.IsNotExist(err) {
 			cm.logger.Info("Config file not found, using defaults")
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
 	}
 
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
-		return err
+		return false, err
+	}
+
+	migrate := false
+	var keyErr error
+	if cm.keys != nil {
+		if cfg.PrivateKey != "" {
+			migrate = true
+		} else if cfg.PublicKey != "" {
+			if cfg.PrivateKey, keyErr = retrievePrivateKey(cm.keys, cfg.PublicKey); keyErr != nil {
+				keyErr = fmt.Errorf("private key of %s not available from secure storage: %w", cfg.PublicKey, keyErr)
+			}
+		}
 	}
 
 	// Preserve defaults if fields are empty
@@ -139,9 +195,14 @@ func (cm *ConfigManager) Load() error {
 		cfg.InterfacePeers = make(map[string][]string)
 	}
 
+	// The identity is kept even without its private key, so that a later
+	// save or start cannot replace it with a new one
 	cm.config = &cfg
+	if keyErr != nil {
+		return false, keyErr
+	}
 	cm.logger.Info("Configuration loaded", "path", cm.path, "peers", len(cfg.Peers))
-	return nil
+	return migrate, nil
 }
 
 // Save saves configuration to disk
@@ -155,7 +216,17 @@ func (cm *ConfigManager) Save() error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(cm.config, "", "  ")
+	// The file gets the private key only when it cannot go to the key store
+	saved := *cm.config
+	if cm.keys != nil && saved.PrivateKey != "" {
+		if err := storePrivateKey(cm.keys, saved.PublicKey, saved.PrivateKey); err != nil {
+			cm.logger.Warn("Failed to store private key in secure storage, keeping it in the config file", "error", err)
+		} else {
+			saved.PrivateKey = ""
+		}
+	}
+
+	data, err := json.MarshalIndent(&saved, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -239,6 +310,45 @@ func (cm *ConfigManager) RemovePeer(uri string) error {
 	return nil
 }
 
+// SetKeyStore moves the private key into ks and reloads the config.
+// Plaintext keys in the config file are migrated and scrubbed.
+func (cm *ConfigManager) SetKeyStore(ks KeyStore) error {
+	cm.mu.Lock()
+	cm.keys = ks
+	cm.mu.Unlock()
+
+	return cm.Load()
+}
+
+// keyStore returns the key store of the config manager
+func (cm *ConfigManager) keyStore() KeyStore {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.keys
+}
+
+// storedPrivateKey returns the private key from the key store, or the one
+// in memory if there is no key store or the key was not saved yet. It fails
+// when the config has a public key without its private key.
+func (cm *ConfigManager) storedPrivateKey() (string, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.keys != nil && cm.config.PublicKey != "" {
+		key, err := retrievePrivateKey(cm.keys, cm.config.PublicKey)
+		if err == nil {
+			return key, nil
+		}
+		if cm.config.PrivateKey == "" {
+			return "", fmt.Errorf("private key of %s not available from secure storage: %w", cm.config.PublicKey, err)
+		}
+	}
+	if cm.config.PrivateKey == "" && cm.config.PublicKey != "" {
+		return "", fmt.Errorf("config has the public key %s but no private key", cm.config.PublicKey)
+	}
+	return cm.config.PrivateKey, nil
+}
+
 // GetPublicKey returns the public key
 func (cm *ConfigManager) GetPublicKey() string {
 	cm.mu.RLock()
@@ -246,6 +356,32 @@ func (cm *ConfigManager) GetPublicKey() string {
 	return cm.config.PublicKey
 }
 
+// IdentityKey returns the public key of the node. Before the config is
+// loaded it is read from the file, without reading the key store.
+func (cm *ConfigManager) IdentityKey() string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.PublicKey != "" {
+		return cm.config.PublicKey
+	}
+
+	data, err := os.ReadFile(cm.path)
+	if err != nil {
+		return ""
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	if cfg.PublicKey == "" && cfg.PrivateKey != "" {
+		if key, err := hex.DecodeString(cfg.PrivateKey); err == nil && len(key) == ed25519.PrivateKeySize {
+			return hex.EncodeToString(ed25519.PrivateKey(key).Public().(ed25519.PublicKey))
+		}
+	}
+	return cfg.PublicKey
+}
+
 // GetPrivateKey returns the private key
 func (cm *ConfigManager) GetPrivateKey() string {
 	cm.mu.RLock()
@@ -271,89 +407,6 @@ func (cm *ConfigManager) SetMulticastEnabled(enabled bool) {
 	}
 }
 
-// GetSOCKSConfig returns the SOCKS proxy configuration
-func (cm *ConfigManager) GetSOCKSConfig() SOCKSConfig {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-	return SOCKSConfig{
-		Enabled:       cm.config.SOCKS.Enabled,
-		ListenAddress: cm.config.SOCKS.ListenAddress,
-		Nameserver:    cm.config.SOCKS.Nameserver,
-	}
-}
-
-// SetSOCKSConfig sets the SOCKS proxy configuration
-func (cm *ConfigManager) SetSOCKSConfig(cfg SOCKSConfig) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-	cm.config.SOCKS.Enabled = cfg.Enabled
-	cm.config.SOCKS.ListenAddress = cfg.ListenAddress
-	cm.config.SOCKS.Nameserver = cfg.Nameserver
-}
-
-// GetMappings returns all port mapping configurations
-func (cm *ConfigManager) GetMappings() (localTCP, localUDP, remoteTCP, remoteUDP []MappingConfig) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	localTCP = make([]MappingConfig, len(cm.config.Mappings.LocalTCP))
-	copy(localTCP, cm.config.Mappings.LocalTCP)
-
-	localUDP = make([]MappingConfig, len(cm.config.Mappings.LocalUDP))
-	copy(localUDP, cm.config.Mappings.LocalUDP)
-
-	remoteTCP = make([]MappingConfig, len(cm.config.Mappings.RemoteTCP))
-	copy(remoteTCP, cm.config.Mappings.RemoteTCP)
-
-	remoteUDP = make([]MappingConfig, len(cm.config.Mappings.RemoteUDP))
-	copy(remoteUDP, cm.config.Mappings.RemoteUDP)
-
-	return
-}
-
-// AddMapping adds a port mapping configuration
-func (cm *ConfigManager) AddMapping(mappingType string, cfg MappingConfig) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	switch mappingType {
-	case "local-tcp":
-		cm.config.Mappings.LocalTCP = append(cm.config.Mappings.LocalTCP, cfg)
-	case "local-udp":
-		cm.config.Mappings.LocalUDP = append(cm.config.Mappings.LocalUDP, cfg)
-	case "remote-tcp":
-		cm.config.Mappings.RemoteTCP = append(cm.config.Mappings.RemoteTCP, cfg)
-	case "remote-udp":
-		cm.config.Mappings.RemoteUDP = append(cm.config.Mappings.RemoteUDP, cfg)
-	}
-}
-
-// RemoveMapping removes a port mapping configuration by name
-func (cm *ConfigManager) RemoveMapping(mappingType, name string) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	removeByName := func(mappings []MappingConfig) []MappingConfig {
-		for i, m := range mappings {
-			if m.Name == name {
-				return append(mappings[:i], mappings[i+1:]...)
-			}
-		}
-		return mappings
-	}
-
-	switch mappingType {
-	case "local-tcp":
-		cm.config.Mappings.LocalTCP = removeByName(cm.config.Mappings.LocalTCP)
-	case "local-udp":
-		cm.config.Mappings.LocalUDP = removeByName(cm.config.Mappings.LocalUDP)
-	case "remote-tcp":
-		cm.config.Mappings.RemoteTCP = removeByName(cm.config.Mappings.RemoteTCP)
-	case "remote-udp":
-		cm.config.Mappings.RemoteUDP = removeByName(cm.config.Mappings.RemoteUDP)
-	}
-}
-
 // GetConfigInfo returns non-sensitive config information
 func (cm *ConfigManager) GetConfigInfo() *ConfigInfo {
 	cm.mu.RLock()
@@ -386,9 +439,22 @@ func (cm *ConfigManager) GetConfig() *Config {
 
 // GetPath returns the configuration file path
 func (cm *ConfigManager) GetPath() string {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	return cm.path
 }
 
+// SetPath switches to another configuration file and loads it.
+// A missing file leaves the defaults in place.
+func (cm *ConfigManager) SetPath(path string) error {
+	cm.mu.Lock()
+	cm.path = path
+	cm.config = defaultConfig()
+	cm.mu.Unlock()
+
+	return cm.Load()
+}
+
 // GetListen returns the listen addresses
 func (cm *ConfigManager) GetListen() []string {
 	cm.mu.RLock()
@@ -0,0 +1,333 @@
+package yggdrasil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// directoryFetchTimeout limits downloading a peer list
+	directoryFetchTimeout = 30 * time.Second
+	// directoryMaxSize limits the size of a peer list
+	directoryMaxSize = 4 << 20
+	// directoryProbeTimeout limits probing a single peer
+	directoryProbeTimeout = 3 * time.Second
+	// directoryProbeWorkers is the number of peers probed at once
+	directoryProbeWorkers = 16
+)
+
+// DirectoryPeer is a peer from a public peer list
+type DirectoryPeer struct {
+	URI        string  `json:"uri"`
+	Region     string  `json:"region,omitempty"`
+	Reachable  bool    `json:"reachable"`
+	RTT        float64 `json:"rtt,omitempty"` // milliseconds
+	Error      string  `json:"error,omitempty"`
+	Configured bool    `json:"configured,omitempty"` // Already a peer of the node
+}
+
+// peerURIPattern matches peer URIs in markdown peer lists
+var peerURIPattern = regexp.MustCompile("`((?:tcp|tls|quic|ws|wss)://[^`\\s]+)`")
+
+// ParsePeerMarkdown extracts peers from a markdown file in the format of
+// the yggdrasil public-peers repository. Headings set the region of the
+// peers below them; region is used until the first heading.
+func ParsePeerMarkdown(data []byte, region string) ([]DirectoryPeer, error) {
+	var peers []DirectoryPeer
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	// A list is at most directoryMaxSize, so is any line of it
+	scanner.Buffer(make([]byte, 0, 64*1024), directoryMaxSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			if heading := strings.TrimSpace(strings.TrimLeft(line, "#")); heading != "" {
+				region = heading
+			}
+			continue
+		}
+		for _, m := range peerURIPattern.FindAllStringSubmatch(line, -1) {
+			if seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			peers = append(peers, DirectoryPeer{URI: m[1], Region: region})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+// ParsePeerJSON extracts peers from a JSON peer list. It accepts the
+// publicnodes.json format, an object of region files mapping peer URIs to
+// their status, and arrays of URIs or of objects with uri and region.
+// A peer listed more than once is returned once.
+func ParsePeerJSON(data []byte) ([]DirectoryPeer, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		peers := make([]DirectoryPeer, 0, len(raw))
+		for _, item := range raw {
+			var uri string
+			if err := json.Unmarshal(item, &uri); err == nil {
+				peers = append(peers, DirectoryPeer{URI: uri})
+				continue
+			}
+			var p DirectoryPeer
+			if err := json.Unmarshal(item, &p); err != nil {
+				return nil, err
+			}
+			peers = append(peers, DirectoryPeer{URI: p.URI, Region: p.Region})
+		}
+		return uniquePeers(peers), nil
+	}
+
+	var regions map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &regions); err != nil {
+		return nil, err
+	}
+
+	var peers []DirectoryPeer
+	for file, list := range regions {
+		region := regionFromName(file)
+		for uri := range list {
+			peers = append(peers, DirectoryPeer{URI: uri, Region: region})
+		}
+	}
+	// Regions are compared too, so a peer in several region files always
+	// keeps the same one
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].URI != peers[j].URI {
+			return peers[i].URI < peers[j].URI
+		}
+		return peers[i].Region < peers[j].Region
+	})
+	return uniquePeers(peers), nil
+}
+
+// uniquePeers removes all but the first entry of every peer URI
+func uniquePeers(peers []DirectoryPeer) []DirectoryPeer {
+	seen := make(map[string]bool, len(peers))
+	unique := peers[:0]
+	for _, p := range peers {
+		if seen[p.URI] {
+			continue
+		}
+		seen[p.URI] = true
+		unique = append(unique, p)
+	}
+	return unique
+}
+
+// regionFromName turns a region file name such as "europe/germany.md"
+// into a region name
+func regionFromName(name string) string {
+	name = strings.TrimSuffix(path.Base(filepath.ToSlash(name)), path.Ext(name))
+	name = strings.ReplaceAll(name, "-", " ")
+	if name == "" || name == "." || name == "/" {
+		return ""
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// ParsePeerList parses a JSON or markdown peer list. name is the file name
+// or URL of the list, used as the region of markdown lists without
+// headings.
+func ParsePeerList(data []byte, name string) ([]DirectoryPeer, error) {
+	var peers []DirectoryPeer
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var err error
+		if peers, err = ParsePeerJSON(trimmed); err != nil {
+			return nil, fmt.Errorf("invalid peer list: %w", err)
+		}
+	} else {
+		var err error
+		if peers, err = ParsePeerMarkdown(data, regionFromName(name)); err != nil {
+			return nil, fmt.Errorf("invalid peer list: %w", err)
+		}
+	}
+
+	valid := peers[:0]
+	for _, p := range peers {
+		if ValidatePeerURI(p.URI) == nil {
+			valid = append(valid, p)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, fmt.Errorf("no peers found in %s", name)
+	}
+	return valid, nil
+}
+
+// FetchPeerList reads a peer list from an http(s) URL or a local file
+func FetchPeerList(ctx context.Context, source string) ([]DirectoryPeer, error) {
+	var data []byte
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		ctx, cancel := context.WithTimeout(ctx, directoryFetchTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch peer list: %s", resp.Status)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, directoryMaxSize)); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return nil, err
+		}
+	}
+
+	return ParsePeerList(data, source)
+}
+
+// ProbePeers measures the connection time of every peer through the OS
+// network. tcp and ws peers are dialed; tls and wss peers also complete a
+// TLS handshake. Other transports are reported as not probed.
+func ProbePeers(ctx context.Context, peers []DirectoryPeer) []DirectoryPeer {
+	probed := make([]DirectoryPeer, len(peers))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < directoryProbeWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				probed[i] = probeDirectoryPeer(ctx, peers[i])
+			}
+		}()
+	}
+
+	for i := range peers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return probed
+}
+
+// probeDirectoryPeer probes one peer
+func probeDirectoryPeer(ctx context.Context, p DirectoryPeer) DirectoryPeer {
+	u, err := url.Parse(p.URI)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+
+	var useTLS bool
+	port := u.Port()
+	switch u.Scheme {
+	case "tcp":
+	case "tls":
+		useTLS = true
+	case "ws":
+		if port == "" {
+			port = "80"
+		}
+	case "wss":
+		useTLS = true
+		if port == "" {
+			port = "443"
+		}
+	default:
+		p.Error = "transport not probed"
+		return p
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, directoryProbeTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(u.Hostname(), port)
+	start := time.Now()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	defer conn.Close()
+
+	if useTLS {
+		// Yggdrasil peers use self-signed certificates, only the handshake
+		// time matters here
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: true,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			p.Error = err.Error()
+			return p
+		}
+	}
+
+	p.Reachable = true
+	p.RTT = float64(time.Since(start).Microseconds()) / 1000
+	return p
+}
+
+// RankPeers orders peers for selection: reachable peers first, then peers
+// in one of the preferred regions in the given order, then by RTT
+func RankPeers(peers []DirectoryPeer, regions []string) []DirectoryPeer {
+	preference := func(region string) int {
+		for i, r := range regions {
+			if strings.EqualFold(r, region) {
+				return i
+			}
+		}
+		return len(regions)
+	}
+
+	ranked := append([]DirectoryPeer{}, peers...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Reachable != b.Reachable {
+			return a.Reachable
+		}
+		if pa, pb := preference(a.Region), preference(b.Region); pa != pb {
+			return pa < pb
+		}
+		if a.RTT != b.RTT {
+			return a.RTT < b.RTT
+		}
+		return a.URI < b.URI
+	})
+	return ranked
+}
@@ -0,0 +1,179 @@
+package yggdrasil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPeerMarkdown = `# Germany
+
+* Falkenstein, Hetzner
+  * ` + "`tcp://192.0.2.1:7991`" + `
+  * ` + "`tls://192.0.2.1:7992`" + `
+
+## Netherlands
+
+* Amsterdam: ` + "`quic://[2001:db8::1]:443`" + ` and ` + "`tcp://192.0.2.1:7991`" + `
+* Not a peer: ` + "`http://example.com`" + `
+`
+
+func TestParsePeerMarkdown(t *testing.T) {
+	peers, err := ParsePeerMarkdown([]byte(testPeerMarkdown), "")
+	if err != nil {
+		t.Fatalf("ParsePeerMarkdown() error = %v", err)
+	}
+
+	want := []DirectoryPeer{
+		{URI: "tcp://192.0.2.1:7991", Region: "Germany"},
+		{URI: "tls://192.0.2.1:7992", Region: "Germany"},
+		{URI: "quic://[2001:db8::1]:443", Region: "Netherlands"},
+	}
+	if len(peers) != len(want) {
+		t.Fatalf("ParsePeerMarkdown() = %+v", peers)
+	}
+	for i := range want {
+		if peers[i] != want[i] {
+			t.Errorf("peer %d = %+v, want %+v", i, peers[i], want[i])
+		}
+	}
+
+	// Lines longer than the scanner default are read, not dropped
+	long := "* " + strings.Repeat("x", 100*1024) + " `tcp://192.0.2.3:80`"
+	if peers, err := ParsePeerMarkdown([]byte(long), ""); err != nil || len(peers) != 1 {
+		t.Errorf("ParsePeerMarkdown(long line) = %+v, %v", peers, err)
+	}
+	if _, err := ParsePeerMarkdown([]byte(strings.Repeat("x", directoryMaxSize+1)), ""); err == nil {
+		t.Error("ParsePeerMarkdown() should fail on a line over the size limit")
+	}
+}
+
+func TestParsePeerList(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []DirectoryPeer
+		wantErr bool
+	}{
+		{
+			name: "publicnodes.json",
+			data: `{"europe/germany.md": {"tls://192.0.2.1:443": {"up": true}}, "north-america.md": {"tcp://192.0.2.2:80": {}, "tls://192.0.2.1:443": {}}}`,
+			want: []DirectoryPeer{
+				{URI: "tcp://192.0.2.2:80", Region: "North america"},
+				{URI: "tls://192.0.2.1:443", Region: "Germany"},
+			},
+		},
+		{
+			name: "list.json",
+			data: `["tcp://192.0.2.1:80", {"uri": "tls://192.0.2.2:443", "region": "Japan"}, "bogus", "tcp://192.0.2.1:80"]`,
+			want: []DirectoryPeer{
+				{URI: "tcp://192.0.2.1:80"},
+				{URI: "tls://192.0.2.2:443", Region: "Japan"},
+			},
+		},
+		{
+			name: "russia.md",
+			data: "* `tcp://192.0.2.1:80`",
+			want: []DirectoryPeer{{URI: "tcp://192.0.2.1:80", Region: "Russia"}},
+		},
+		{name: "empty.md", data: "no peers here", wantErr: true},
+		{name: "broken.json", data: `{"a": 1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peers, err := ParsePeerList([]byte(tt.data), tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePeerList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(peers) != len(tt.want) {
+				t.Fatalf("ParsePeerList() = %+v, want %+v", peers, tt.want)
+			}
+			for i := range tt.want {
+				if peers[i] != tt.want[i] {
+					t.Errorf("peer %d = %+v, want %+v", i, peers[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPeerList(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/peers.md" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testPeerMarkdown))
+	}))
+	defer srv.Close()
+
+	peers, err := FetchPeerList(context.Background(), srv.URL+"/peers.md")
+	if err != nil || len(peers) != 3 {
+		t.Errorf("FetchPeerList(url) = %+v, %v", peers, err)
+	}
+	if _, err := FetchPeerList(context.Background(), srv.URL+"/missing.md"); err == nil {
+		t.Error("FetchPeerList() should fail on HTTP errors")
+	}
+
+	path := filepath.Join(t.TempDir(), "peers.md")
+	if err := os.WriteFile(path, []byte(testPeerMarkdown), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if peers, err := FetchPeerList(context.Background(), path); err != nil || len(peers) != 3 {
+		t.Errorf("FetchPeerList(file) = %+v, %v", peers, err)
+	}
+}
+
+func TestProbeAndRankPeers(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	peers := ProbePeers(context.Background(), []DirectoryPeer{
+		{URI: "tcp://" + closed.Addr().String(), Region: "Germany"},
+		{URI: "quic://" + ln.Addr().String(), Region: "Germany"},
+		{URI: "tcp://" + ln.Addr().String(), Region: "France"},
+		{URI: "ws://" + ln.Addr().String(), Region: "Germany"},
+	})
+
+	if peers[0].Reachable || peers[0].Error == "" {
+		t.Errorf("closed port should be unreachable: %+v", peers[0])
+	}
+	if peers[1].Reachable || peers[1].Error != "transport not probed" {
+		t.Errorf("quic peer should not be probed: %+v", peers[1])
+	}
+	if !peers[2].Reachable || !peers[3].Reachable {
+		t.Errorf("listening peers should be reachable: %+v", peers[2:])
+	}
+
+	ranked := RankPeers(peers, []string{"germany"})
+	if ranked[0].URI != peers[3].URI || ranked[1].URI != peers[2].URI {
+		t.Errorf("reachable peers in the preferred region should come first: %+v", ranked)
+	}
+	if ranked[2].Reachable || ranked[3].Reachable {
+		t.Errorf("unreachable peers should come last: %+v", ranked)
+	}
+}
@@ -0,0 +1,454 @@
+package yggdrasil
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+const (
+	// probeTimeout limits a reachability probe of a disconnected peer
+	probeTimeout = 5 * time.Second
+	// latencyHistorySize is the number of latency samples kept per peer
+	latencyHistorySize = 20
+)
+
+// FailoverConfig configures a PeerSupervisor
+type FailoverConfig struct {
+	Enabled   bool
+	MinPeers  int           // Connected peers below which standby peers are added
+	Interval  time.Duration // Time between probe rounds
+	DeadAfter int           // Consecutive failed probes after which a peer is removed
+	Standby   []string      // Peers added when connected peers drop
+}
+
+// PeerHealth describes the probe history of a configured or standby peer
+type PeerHealth struct {
+	URI                 string    `json:"uri"`
+	Standby             bool      `json:"standby"`
+	Attached            bool      `json:"attached"` // Peer is added to the running node
+	Connected           bool      `json:"connected"`
+	Probes              int       `json:"probes"`
+	Successes           int       `json:"successes"`
+	SuccessRate         float64   `json:"successRate"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Latency             float64   `json:"latency"`        // milliseconds, last sample
+	AverageLatency      float64   `json:"averageLatency"` // milliseconds
+	LatencyHistory      []float64 `json:"latencyHistory"`
+	LastSuccess         time.Time `json:"lastSuccess,omitempty"`
+}
+
+// FailoverEvent reports a peer added or removed by a PeerSupervisor
+type FailoverEvent struct {
+	Action string `json:"action"` // "added" or "removed"
+	URI    string `json:"uri"`
+	Reason string `json:"reason"`
+}
+
+// peerState is the health record of one peer
+type peerState struct {
+	PeerHealth
+	removedAt time.Time
+}
+
+// PeerSupervisor probes the peers of a node and keeps enough of them
+// connected. When the number of connected peers drops below MinPeers it adds
+// standby peers, and peers that fail DeadAfter probes in a row are removed
+// from the running node until they answer again. Removed and added peers are
+// not written to the config file.
+type PeerSupervisor struct {
+	mu       sync.Mutex
+	service  *Service
+	logger   *logger.Logger
+	cfg      FailoverConfig
+	peers    map[string]*peerState
+	onChange func(FailoverEvent)
+	dial     func(network, address string, timeout time.Duration) (net.Conn, error)
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewPeerSupervisor creates a stopped supervisor for service
+func NewPeerSupervisor(service *Service, log *logger.Logger) *PeerSupervisor {
+	ps := &PeerSupervisor{
+		service: service,
+		logger:  log,
+		peers:   make(map[string]*peerState),
+		dial:    net.DialTimeout,
+	}
+
+	service.AddStateListener(ps.onState)
+
+	return ps
+}
+
+// SetChangeHandler sets a function called for every peer added or removed
+func (ps *PeerSupervisor) SetChangeHandler(fn func(FailoverEvent)) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.onChange = fn
+}
+
+// Configure applies cfg, starting or stopping the probe loop as needed
+func (ps *PeerSupervisor) Configure(cfg FailoverConfig) {
+	ps.mu.Lock()
+	ps.cfg = cfg
+	running := ps.stop != nil
+	ps.mu.Unlock()
+
+	switch {
+	case cfg.Enabled && !running:
+		ps.start()
+	case cfg.Enabled && running:
+		// Restart so a new interval takes effect
+		ps.Stop()
+		ps.start()
+	case !cfg.Enabled && running:
+		ps.Stop()
+	}
+}
+
+// start runs the probe loop
+func (ps *PeerSupervisor) start() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	interval := ps.cfg.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	ps.stop, ps.done = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ps.Check()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	ps.logger.Info("Peer supervisor started", "interval", interval, "minPeers", ps.cfg.MinPeers)
+}
+
+// Stop stops the probe loop
+func (ps *PeerSupervisor) Stop() {
+	ps.mu.Lock()
+	stop, done := ps.stop, ps.done
+	ps.stop, ps.done = nil, nil
+	ps.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// onState resets the peer records when the node starts, since the core
+// begins with the configured peers only
+func (ps *PeerSupervisor) onState(state ServiceState, info *NodeInfo) {
+	if state != StateRunning {
+		return
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, p := range ps.peers {
+		p.Attached = !p.Standby
+	}
+}
+
+// normalizePeerURI returns the form of uri reported by the core
+func normalizePeerURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	return u.String()
+}
+
+// Check runs one probe round and adds or removes peers as needed
+func (ps *PeerSupervisor) Check() {
+	if !ps.service.IsRunning() {
+		return
+	}
+
+	// Connection state reported by the core
+	connected := 0
+	up := make(map[string]time.Duration)
+	for _, cp := range ps.service.GetPeers() {
+		if !cp.Up {
+			continue
+		}
+		connected++
+		if !cp.Inbound {
+			up[normalizePeerURI(cp.URI)] = cp.Latency
+		}
+	}
+
+	configured := ps.service.ConfigManager().GetPeers()
+
+	ps.mu.Lock()
+	cfg := ps.cfg
+	current := ps.syncPeers(configured, cfg.Standby)
+	ps.mu.Unlock()
+
+	// Probe without holding the lock, dialing can take a while
+	results := make(map[string]probeResult, len(current))
+	for _, uri := range current {
+		if latency, ok := up[normalizePeerURI(uri)]; ok {
+			results[uri] = probeResult{ok: true, connected: true, latency: latency}
+			continue
+		}
+		results[uri] = ps.probe(uri)
+	}
+
+	var events []FailoverEvent
+	ps.mu.Lock()
+	for uri, r := range results {
+		if p, ok := ps.peers[uri]; ok {
+			p.record(r)
+		}
+	}
+	events = append(events, ps.removeDead(cfg)...)
+	if cfg.MinPeers > connected {
+		events = append(events, ps.addStandby(cfg, cfg.MinPeers-connected)...)
+	}
+	onChange := ps.onChange
+	ps.mu.Unlock()
+
+	for _, e := range events {
+		ps.logger.Info("Peer failover", "action", e.Action, "uri", e.URI, "reason", e.Reason)
+		if onChange != nil {
+			onChange(e)
+		}
+	}
+}
+
+// syncPeers adds records for new peers and drops records of peers that are
+// no longer configured (must be called with mu held). Returns the URIs to
+// probe.
+func (ps *PeerSupervisor) syncPeers(configured, standby []string) []string {
+	seen := make(map[string]bool)
+	var uris []string
+
+	for _, uri := range configured {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		uris = append(uris, uri)
+		if p, ok := ps.peers[uri]; !ok {
+			ps.peers[uri] = &peerState{PeerHealth: PeerHealth{URI: uri, Attached: true}}
+		} else if p.Standby {
+			// A standby peer that was saved to the config
+			p.Standby = false
+		}
+	}
+	for _, uri := range standby {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		uris = append(uris, uri)
+		if _, ok := ps.peers[uri]; !ok {
+			ps.peers[uri] = &peerState{PeerHealth: PeerHealth{URI: uri, Standby: true}}
+		}
+	}
+
+	for uri, p := range ps.peers {
+		if !seen[uri] {
+			if p.Attached && p.Standby {
+				// Leave peers added by the supervisor to the core alone,
+				// removing them here could drop the last connection
+				continue
+			}
+			delete(ps.peers, uri)
+		}
+	}
+
+	return uris
+}
+
+// probeResult is the outcome of probing one peer
+type probeResult struct {
+	ok        bool
+	connected bool
+	skipped   bool // Transport cannot be probed without connecting
+	latency   time.Duration
+}
+
+// probe checks whether a disconnected peer is reachable. Peers on TCP based
+// transports are dialed; other transports are only judged by the core.
+func (ps *PeerSupervisor) probe(uri string) probeResult {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return probeResult{}
+	}
+
+	switch u.Scheme {
+	case "tcp", "tls", "ws", "wss":
+	default:
+		return probeResult{skipped: true}
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "ws":
+			host = net.JoinHostPort(u.Hostname(), "80")
+		case "wss":
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+
+	start := time.Now()
+	conn, err := ps.dial("tcp", host, probeTimeout)
+	if err != nil {
+		return probeResult{}
+	}
+	conn.Close()
+	return probeResult{ok: true, latency: time.Since(start)}
+}
+
+// record adds a probe result to the peer history
+func (p *peerState) record(r probeResult) {
+	p.Connected = r.connected
+	if r.skipped {
+		if !p.Attached {
+			return
+		}
+		// An attached peer the core has not connected is failing
+		r.ok = false
+	}
+
+	p.Probes++
+	if !r.ok {
+		p.ConsecutiveFailures++
+	} else {
+		p.Successes++
+		p.ConsecutiveFailures = 0
+		p.LastSuccess = time.Now()
+		p.Latency = float64(r.latency.Microseconds()) / 1000
+		p.LatencyHistory = append(p.LatencyHistory, p.Latency)
+		if len(p.LatencyHistory) > latencyHistorySize {
+			p.LatencyHistory = p.LatencyHistory[len(p.LatencyHistory)-latencyHistorySize:]
+		}
+		var sum float64
+		for _, l := range p.LatencyHistory {
+			sum += l
+		}
+		p.AverageLatency = sum / float64(len(p.LatencyHistory))
+	}
+	p.SuccessRate = float64(p.Successes) / float64(p.Probes)
+}
+
+// removeDead removes attached peers that failed too many probes (must be
+// called with mu held)
+func (ps *PeerSupervisor) removeDead(cfg FailoverConfig) []FailoverEvent {
+	var events []FailoverEvent
+	for _, p := range ps.sortedPeers() {
+		if !p.Attached || p.Connected || cfg.DeadAfter <= 0 || p.ConsecutiveFailures < cfg.DeadAfter {
+			continue
+		}
+		if err := ps.service.RemovePeer(p.URI); err != nil {
+			ps.logger.Debug("Failed to remove dead peer", "uri", p.URI, "error", err)
+			continue
+		}
+		p.Attached = false
+		p.removedAt = time.Now()
+		events = append(events, FailoverEvent{Action: "removed", URI: p.URI, Reason: "unreachable"})
+	}
+	return events
+}
+
+// addStandby adds up to n detached peers that look alive, preferring
+// configured peers, then the best success rate and latency (must be called
+// with mu held)
+func (ps *PeerSupervisor) addStandby(cfg FailoverConfig, n int) []FailoverEvent {
+	var candidates []*peerState
+	for _, p := range ps.sortedPeers() {
+		if p.Attached || !ps.usable(cfg, p) {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Standby != b.Standby {
+			return !a.Standby
+		}
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate > b.SuccessRate
+		}
+		return a.AverageLatency < b.AverageLatency
+	})
+
+	var events []FailoverEvent
+	for _, p := range candidates {
+		if n <= 0 {
+			break
+		}
+		if err := ps.service.AddPeer(p.URI); err != nil {
+			ps.logger.Debug("Failed to add standby peer", "uri", p.URI, "error", err)
+			continue
+		}
+		p.Attached = true
+		p.ConsecutiveFailures = 0
+		reason := "standby"
+		if !p.Standby {
+			reason = "reachable again"
+		}
+		events = append(events, FailoverEvent{Action: "added", URI: p.URI, Reason: reason})
+		n--
+	}
+	return events
+}
+
+// usable reports whether a detached peer may be added. Peers that cannot be
+// probed are retried once they have been removed for DeadAfter intervals.
+func (ps *PeerSupervisor) usable(cfg FailoverConfig, p *peerState) bool {
+	if cfg.DeadAfter <= 0 || p.ConsecutiveFailures < cfg.DeadAfter {
+		return true
+	}
+	return !p.removedAt.IsZero() && time.Since(p.removedAt) >= time.Duration(cfg.DeadAfter)*cfg.Interval
+}
+
+// sortedPeers returns the peer records ordered by URI (must be called with
+// mu held)
+func (ps *PeerSupervisor) sortedPeers() []*peerState {
+	peers := make([]*peerState, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].URI < peers[j].URI
+	})
+	return peers
+}
+
+// Health returns the probe history of all known peers
+func (ps *PeerSupervisor) Health() []PeerHealth {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	health := make([]PeerHealth, 0, len(ps.peers))
+	for _, p := range ps.sortedPeers() {
+		h := p.PeerHealth
+		h.LatencyHistory = append([]float64{}, p.LatencyHistory...)
+		health = append(health, h)
+	}
+	return health
+}
@@ -0,0 +1,95 @@
+package yggdrasil
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestPeerSupervisor_Failover(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+
+	// A standby peer that accepts connections and a configured one that
+	// does not
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "tcp://" + closed.Addr().String()
+	closed.Close()
+	standby := "tcp://" + ln.Addr().String()
+
+	cm := newTestConfigManager(t)
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	cm.SetPeers([]string{dead})
+	cm.SetListen([]string{})
+
+	svc := NewServiceWithConfig(cm, log)
+	ps := NewPeerSupervisor(svc, log)
+	ps.cfg = FailoverConfig{MinPeers: 1, Interval: time.Second, DeadAfter: 2, Standby: []string{standby}}
+
+	var events []FailoverEvent
+	ps.SetChangeHandler(func(e FailoverEvent) { events = append(events, e) })
+
+	// Nothing happens while the node is stopped
+	ps.Check()
+	if len(ps.Health()) != 0 {
+		t.Error("stopped node should not be probed")
+	}
+
+	if err := svc.Start(nil); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer svc.Stop()
+
+	ps.Check()
+	if len(events) != 1 || events[0].Action != "added" || events[0].URI != standby {
+		t.Fatalf("first round events = %+v", events)
+	}
+
+	ps.Check()
+	if len(events) != 2 || events[1].Action != "removed" || events[1].URI != dead {
+		t.Fatalf("second round events = %+v", events)
+	}
+
+	health := ps.Health()
+	if len(health) != 2 {
+		t.Fatalf("health = %+v", health)
+	}
+	for _, h := range health {
+		switch h.URI {
+		case dead:
+			if h.Attached || h.SuccessRate != 0 || h.ConsecutiveFailures != 2 {
+				t.Errorf("dead peer health = %+v", h)
+			}
+		case standby:
+			if !h.Attached || !h.Standby || h.SuccessRate != 1 || len(h.LatencyHistory) != 2 {
+				t.Errorf("standby peer health = %+v", h)
+			}
+		}
+	}
+}
+
+func TestPeerSupervisor_Configure(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	ps := NewPeerSupervisor(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	ps.Configure(FailoverConfig{Enabled: true, MinPeers: 1, Interval: time.Hour})
+	if ps.stop == nil {
+		t.Error("enabled supervisor should run")
+	}
+	ps.Configure(FailoverConfig{Enabled: false})
+	if ps.stop != nil {
+		t.Error("disabled supervisor should stop")
+	}
+	ps.Stop()
+}
@@ -0,0 +1,471 @@
+package yggdrasil
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// Traffic history resolutions
+const (
+	HistoryMinute = "minute"
+	HistoryHour   = "hour"
+)
+
+const (
+	// historyMinutes is the number of per-minute buckets, 24 hours
+	historyMinutes = 24 * 60
+	// historyHours is the number of per-hour buckets, 30 days
+	historyHours = 30 * 24
+	// historySampleInterval is how often traffic counters are sampled
+	historySampleInterval = time.Minute
+	// historySaveInterval is how often the history is written to disk
+	historySaveInterval = 10 * time.Minute
+	// historyVersion is the version of the history file format
+	historyVersion = 1
+)
+
+// TrafficPoint is the traffic of one series during one bucket
+type TrafficPoint struct {
+	Time     int64  `json:"time"` // Bucket start, unix milliseconds
+	BytesIn  uint64 `json:"bytesIn"`
+	BytesOut uint64 `json:"bytesOut"`
+}
+
+// TrafficSeries is the traffic history of one peer group, proxy or mapping
+type TrafficSeries struct {
+	Name       string         `json:"name"` // "peers", "proxy" or "mapping/<id>"
+	Resolution string         `json:"resolution"`
+	BytesIn    uint64         `json:"bytesIn"`  // Total of the points
+	BytesOut   uint64         `json:"bytesOut"` // Total of the points
+	Points     []TrafficPoint `json:"points"`
+}
+
+// trafficRing keeps fixed size time buckets. The bucket of a time is
+// chosen by its index modulo the ring size, so old buckets are reused.
+type trafficRing struct {
+	step    int64 // Bucket length, milliseconds
+	buckets []TrafficPoint
+}
+
+func newTrafficRing(step time.Duration, size int) *trafficRing {
+	return &trafficRing{step: step.Milliseconds(), buckets: make([]TrafficPoint, size)}
+}
+
+// bucket returns the start of the bucket of t
+func (r *trafficRing) bucket(t int64) int64 {
+	return t - t%r.step
+}
+
+// add adds traffic to the bucket of t
+func (r *trafficRing) add(t int64, in, out uint64) {
+	start := r.bucket(t)
+	b := &r.buckets[(start/r.step)%int64(len(r.buckets))]
+	if b.Time != start {
+		*b = TrafficPoint{Time: start}
+	}
+	b.BytesIn += in
+	b.BytesOut += out
+}
+
+// oldest returns the start of the oldest bucket kept at now
+func (r *trafficRing) oldest(now int64) int64 {
+	return r.bucket(now) - r.step*int64(len(r.buckets)-1)
+}
+
+// points returns the non-empty buckets kept at now, oldest first
+func (r *trafficRing) points(now int64) []TrafficPoint {
+	oldest := r.oldest(now)
+	var points []TrafficPoint
+	for _, b := range r.buckets {
+		if b.Time >= oldest && b.Time <= now && (b.BytesIn > 0 || b.BytesOut > 0) {
+			points = append(points, b)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time < points[j].Time
+	})
+	return points
+}
+
+// historySeries is a series at both resolutions
+type historySeries struct {
+	minutes *trafficRing
+	hours   *trafficRing
+}
+
+func newHistorySeries() *historySeries {
+	return &historySeries{
+		minutes: newTrafficRing(time.Minute, historyMinutes),
+		hours:   newTrafficRing(time.Hour, historyHours),
+	}
+}
+
+func (s *historySeries) ring(resolution string) *trafficRing {
+	if resolution == HistoryMinute {
+		return s.minutes
+	}
+	return s.hours
+}
+
+// trafficCounter is the last seen value of a cumulative byte counter
+type trafficCounter struct {
+	in, out uint64
+}
+
+// historyFile is the on-disk format of the history. Points are stored as
+// [time, bytesIn, bytesOut] triples.
+type historyFile struct {
+	Version int                               `json:"version"`
+	Series  map[string]map[string][][3]uint64 `json:"series"`
+}
+
+// TrafficHistory records the traffic of nodes as time series, per minute
+// for 24 hours and per hour for 30 days. It samples the byte counters of
+// the peers, the SOCKS proxy and every mapping of a node, which only count
+// since they started, and keeps the history across restarts in a file.
+//
+// Series are named "<node>/peers", "<node>/proxy" and "<node>/mapping/<id>",
+// where node is the profile the node runs.
+type TrafficHistory struct {
+	mu       sync.Mutex
+	path     string
+	logger   *logger.Logger
+	series   map[string]*historySeries
+	counters map[*Node]map[string]trafficCounter
+	dirty    bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewTrafficHistory creates an empty history saved to path
+func NewTrafficHistory(path string, log *logger.Logger) *TrafficHistory {
+	return &TrafficHistory{
+		path:     path,
+		logger:   log,
+		series:   make(map[string]*historySeries),
+		counters: make(map[*Node]map[string]trafficCounter),
+	}
+}
+
+// Load reads the history file. A missing file leaves the history empty.
+func (th *TrafficHistory) Load() error {
+	data, err := os.ReadFile(th.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var f historyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid traffic history: %w", err)
+	}
+	if f.Version != historyVersion {
+		return fmt.Errorf("unsupported traffic history version %d", f.Version)
+	}
+
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	th.series = make(map[string]*historySeries)
+	for name, rings := range f.Series {
+		s := newHistorySeries()
+		for _, resolution := range []string{HistoryMinute, HistoryHour} {
+			r := s.ring(resolution)
+			for _, p := range rings[resolution] {
+				r.add(int64(p[0]), p[1], p[2])
+			}
+		}
+		th.series[name] = s
+	}
+	return nil
+}
+
+// Save writes the history file. Empty and expired buckets are left out.
+func (th *TrafficHistory) Save() error {
+	th.mu.Lock()
+	now := time.Now().UnixMilli()
+	f := historyFile{
+		Version: historyVersion,
+		Series:  make(map[string]map[string][][3]uint64, len(th.series)),
+	}
+	for name, s := range th.series {
+		rings := make(map[string][][3]uint64, 2)
+		for _, resolution := range []string{HistoryMinute, HistoryHour} {
+			for _, p := range s.ring(resolution).points(now) {
+				rings[resolution] = append(rings[resolution], [3]uint64{uint64(p.Time), p.BytesIn, p.BytesOut})
+			}
+		}
+		if len(rings) > 0 {
+			f.Series[name] = rings
+		}
+	}
+	th.dirty = false
+	th.mu.Unlock()
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(th.path), 0755); err != nil {
+		return err
+	}
+
+	tmpPath := th.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, th.path)
+}
+
+// Add records traffic of series name at now
+func (th *TrafficHistory) Add(name string, in, out uint64, now time.Time) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	th.add(name, in, out, now)
+}
+
+// add records traffic, must be called with the lock held
+func (th *TrafficHistory) add(name string, in, out uint64, now time.Time) {
+	if in == 0 && out == 0 {
+		return
+	}
+
+	s, ok := th.series[name]
+	if !ok {
+		s = newHistorySeries()
+		th.series[name] = s
+	}
+	t := now.UnixMilli()
+	s.minutes.add(t, in, out)
+	s.hours.add(t, in, out)
+	th.dirty = true
+}
+
+// counterDelta returns the traffic counted by a cumulative counter since it was
+// last seen and remembers its values. A counter that went down was
+// restarted and counts from zero.
+func counterDelta(last map[string]trafficCounter, key string, in, out uint64) (uint64, uint64) {
+	prev := last[key]
+	last[key] = trafficCounter{in: in, out: out}
+	if in < prev.in || out < prev.out {
+		return in, out
+	}
+	return in - prev.in, out - prev.out
+}
+
+// SampleNodes records the traffic of nodes since the last sample. nodes
+// maps the profile each node runs to the node.
+func (th *TrafficHistory) SampleNodes(nodes map[string]*Node, now time.Time) {
+	type sample struct {
+		peers    []PeerInfo
+		proxy    *SOCKSStats
+		mappings []PortMapping
+	}
+
+	// Read counters before locking, the managers have their own locks
+	samples := make(map[*Node]sample, len(nodes))
+	for _, n := range nodes {
+		peers, _ := n.Peers.GetPeers()
+		samples[n] = sample{peers: peers, proxy: n.SOCKS.GetStats(), mappings: n.Mappings.GetMappings()}
+	}
+
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	counters := make(map[*Node]map[string]trafficCounter, len(nodes))
+	for prefix, n := range nodes {
+		last := th.counters[n]
+		if last == nil {
+			last = make(map[string]trafficCounter)
+		}
+		next := make(map[string]trafficCounter)
+		s := samples[n]
+
+		// Peer counters restart with every connection, so each peer is
+		// counted on its own
+		var peersIn, peersOut uint64
+		for _, p := range s.peers {
+			key := "peer/" + p.URI
+			next[key] = last[key]
+			in, out := counterDelta(next, key, p.RxBytes, p.TxBytes)
+			peersIn += in
+			peersOut += out
+		}
+		th.add(prefix+"/peers", peersIn, peersOut, now)
+
+		if s.proxy != nil {
+			next["proxy"] = last["proxy"]
+			in, out := counterDelta(next, "proxy", s.proxy.BytesIn, s.proxy.BytesOut)
+			th.add(prefix+"/proxy", in, out, now)
+		}
+
+		for _, m := range s.mappings {
+			key := "mapping/" + m.ID
+			next[key] = last[key]
+			in, out := counterDelta(next, key, m.BytesIn, m.BytesOut)
+			th.add(prefix+"/"+key, in, out, now)
+		}
+
+		counters[n] = next
+	}
+	th.counters = counters
+}
+
+// Start samples the counters every minute using sample, which should call
+// SampleNodes, and saves the history every 10 minutes
+func (th *TrafficHistory) Start(sample func(now time.Time)) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	if th.stop != nil {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	th.stop, th.done = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(historySampleInterval)
+		defer ticker.Stop()
+		lastSave := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				sample(now)
+				if now.Sub(lastSave) >= historySaveInterval {
+					th.saveIfChanged()
+					lastSave = now
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling and saves the history
+func (th *TrafficHistory) Stop() {
+	th.mu.Lock()
+	stop, done := th.stop, th.done
+	th.stop, th.done = nil, nil
+	th.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	th.saveIfChanged()
+}
+
+// saveIfChanged saves the history when traffic was recorded since the last
+// save
+func (th *TrafficHistory) saveIfChanged() {
+	th.mu.Lock()
+	dirty := th.dirty
+	th.mu.Unlock()
+
+	if !dirty {
+		return
+	}
+	if err := th.Save(); err != nil {
+		th.logger.Warn("Failed to save traffic history", "error", err)
+	}
+}
+
+// Query returns the series of node at resolution between from and to,
+// with empty buckets filled with zeros. names selects series by name, such
+// as "proxy" or "mapping/web"; all series of the node are returned if it is
+// empty. A zero from starts at the oldest bucket kept.
+func (th *TrafficHistory) Query(node string, names []string, resolution string, from, to time.Time) ([]TrafficSeries, error) {
+	if resolution == "" {
+		resolution = HistoryHour
+	}
+	if resolution != HistoryMinute && resolution != HistoryHour {
+		return nil, fmt.Errorf("invalid resolution %q: use %s or %s", resolution, HistoryMinute, HistoryHour)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	prefix := node + "/"
+	result := []TrafficSeries{}
+	for fullName, s := range th.series {
+		name := strings.TrimPrefix(fullName, prefix)
+		if name == fullName || (len(wanted) > 0 && !wanted[name]) {
+			continue
+		}
+
+		r := s.ring(resolution)
+		end := to.UnixMilli()
+		start := r.oldest(end)
+		if !from.IsZero() && r.bucket(from.UnixMilli()) > start {
+			start = r.bucket(from.UnixMilli())
+		}
+
+		kept := make(map[int64]TrafficPoint)
+		for _, p := range r.points(end) {
+			kept[p.Time] = p
+		}
+
+		series := TrafficSeries{Name: name, Resolution: resolution, Points: []TrafficPoint{}}
+		for t := start; t <= end; t += r.step {
+			p, ok := kept[t]
+			if !ok {
+				p = TrafficPoint{Time: t}
+			}
+			series.BytesIn += p.BytesIn
+			series.BytesOut += p.BytesOut
+			series.Points = append(series.Points, p)
+		}
+		result = append(result, series)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// WriteTrafficCSV writes series as CSV with one row per series and bucket
+func WriteTrafficCSV(w io.Writer, series []TrafficSeries) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "series", "bytes_in", "bytes_out"}); err != nil {
+		return err
+	}
+	for _, s := range series {
+		for _, p := range s.Points {
+			row := []string{
+				time.UnixMilli(p.Time).UTC().Format(time.RFC3339),
+				s.Name,
+				strconv.FormatUint(p.BytesIn, 10),
+				strconv.FormatUint(p.BytesOut, 10),
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,103 @@
+package yggdrasil
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestTrafficHistory_QuerySaveLoad(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	path := filepath.Join(t.TempDir(), "traffic.json")
+	th := NewTrafficHistory(path, log)
+
+	now := time.Now().Truncate(time.Hour).Add(-30 * time.Minute)
+	th.Add("default/proxy", 100, 10, now.Add(-2*time.Hour))
+	th.Add("default/proxy", 50, 5, now.Add(-time.Minute))
+	th.Add("default/proxy", 25, 5, now)
+	th.Add("default/mapping/web", 1000, 2000, now)
+	th.Add("work/proxy", 7, 7, now)
+
+	series, err := th.Query("default", nil, HistoryHour, now.Add(-3*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(series) != 2 || series[0].Name != "mapping/web" || series[1].Name != "proxy" {
+		t.Fatalf("Query() series = %+v", series)
+	}
+	proxy := series[1]
+	if len(proxy.Points) != 4 || proxy.BytesIn != 175 || proxy.Points[3].BytesIn != 75 || proxy.Points[2].BytesIn != 0 {
+		t.Errorf("hourly proxy series = %+v", proxy)
+	}
+
+	series, err = th.Query("default", []string{"proxy"}, HistoryMinute, now.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(series) != 1 || len(series[0].Points) != 2 || series[0].Points[0].BytesIn != 50 || series[0].Points[1].BytesIn != 25 {
+		t.Errorf("minute proxy series = %+v", series)
+	}
+
+	if _, err := th.Query("default", nil, "day", time.Time{}, now); err == nil {
+		t.Error("Query() should reject an unknown resolution")
+	}
+
+	if err := th.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded := NewTrafficHistory(path, log)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	series, _ = loaded.Query("default", []string{"proxy"}, HistoryHour, now.Add(-3*time.Hour), now)
+	if len(series) != 1 || series[0].BytesIn != 175 || series[0].BytesOut != 20 {
+		t.Errorf("loaded proxy series = %+v", series)
+	}
+
+	var csv strings.Builder
+	if err := WriteTrafficCSV(&csv, series); err != nil {
+		t.Fatalf("WriteTrafficCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 5 || lines[0] != "time,series,bytes_in,bytes_out" || !strings.HasSuffix(lines[4], ",proxy,75,10") {
+		t.Errorf("CSV = %q", csv.String())
+	}
+}
+
+func TestTrafficRing_Expires(t *testing.T) {
+	r := newTrafficRing(time.Minute, 3)
+	base := time.Now().Truncate(time.Minute).UnixMilli()
+	minute := time.Minute.Milliseconds()
+
+	r.add(base, 1, 1)
+	r.add(base+minute, 2, 2)
+	r.add(base+3*minute, 4, 4) // Reuses the bucket of base
+
+	points := r.points(base + 3*minute)
+	if len(points) != 2 || points[0].BytesIn != 2 || points[1].BytesIn != 4 {
+		t.Errorf("points = %+v", points)
+	}
+
+	// Buckets older than the ring are not returned
+	if points := r.points(base + 10*minute); len(points) != 0 {
+		t.Errorf("expired points = %+v", points)
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	last := make(map[string]trafficCounter)
+
+	if in, out := counterDelta(last, "proxy", 100, 50); in != 100 || out != 50 {
+		t.Errorf("first delta = %d/%d, want 100/50", in, out)
+	}
+	if in, out := counterDelta(last, "proxy", 150, 60); in != 50 || out != 10 {
+		t.Errorf("delta = %d/%d, want 50/10", in, out)
+	}
+	// Restarted counter
+	if in, out := counterDelta(last, "proxy", 20, 5); in != 20 || out != 5 {
+		t.Errorf("delta after restart = %d/%d, want 20/5", in, out)
+	}
+}
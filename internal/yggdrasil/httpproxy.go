@@ -0,0 +1,242 @@
+package yggdrasil
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/things-go/go-socks5"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// httpProxyIdleTimeout is how long forwarded plain HTTP connections are
+// kept for reuse
+const httpProxyIdleTimeout = 90 * time.Second
+
+// httpProxy serves HTTP proxy requests through the same dialer and resolver
+// as the SOCKS5 server. CONNECT requests are tunnelled; plain requests with
+// an absolute URI are forwarded.
+type httpProxy struct {
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	resolver  socks5.NameResolver
+	creds     *socksCredentials // nil when authentication is off
+	transport *http.Transport
+	forward   *httputil.ReverseProxy
+	logger    *logger.Logger
+
+	mu      sync.Mutex
+	tunnels map[net.Conn]struct{} // Client and target conns of CONNECT tunnels
+	closed  bool
+}
+
+// newHTTPProxy creates the HTTP proxy handler
+func newHTTPProxy(dial func(ctx context.Context, network, addr string) (net.Conn, error),
+	resolver socks5.NameResolver, creds *socksCredentials, log *logger.Logger) *httpProxy {
+	hp := &httpProxy{
+		dial:     dial,
+		resolver: resolver,
+		creds:    creds,
+		logger:   log,
+		tunnels:  make(map[net.Conn]struct{}),
+	}
+
+	hp.transport = &http.Transport{
+		DialContext:     hp.dialHost,
+		IdleConnTimeout: httpProxyIdleTimeout,
+	}
+	hp.forward = &httputil.ReverseProxy{
+		// The request already carries the absolute target URL; hop-by-hop
+		// headers, including Proxy-Authorization, are removed by the proxy
+		Rewrite:   func(*httputil.ProxyRequest) {},
+		Transport: hp.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			hp.logger.Debug("HTTP proxy request failed", "url", r.URL.String(), "error", err)
+			if errors.Is(err, errRouteRejected) {
+				http.Error(w, "Destination not allowed", http.StatusForbidden)
+				return
+			}
+			http.Error(w, "Bad gateway", http.StatusBadGateway)
+		},
+	}
+	return hp
+}
+
+// dialHost resolves the host of addr, including .pk.ygg names, and dials
+// it. A resolver without an address leaves the name to the dialer.
+func (hp *httpProxy) dialHost(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	_, ip, err := hp.resolver.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if ip != nil {
+		addr = net.JoinHostPort(ip.String(), port)
+	}
+	return hp.dial(ctx, network, addr)
+}
+
+// ServeHTTP implements http.Handler
+func (hp *httpProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !hp.authorized(r) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="yggstack"`)
+		http.Error(w, "Proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		hp.tunnel(w, r)
+		return
+	}
+
+	if r.URL.Scheme != "http" || r.URL.Host == "" {
+		http.Error(w, "Only absolute http:// URIs are forwarded, use CONNECT for https", http.StatusBadRequest)
+		return
+	}
+	hp.forward.ServeHTTP(w, r)
+}
+
+// authorized checks the Basic credentials in Proxy-Authorization
+func (hp *httpProxy) authorized(r *http.Request) bool {
+	if hp.creds == nil {
+		return true
+	}
+
+	var username, password string
+	if encoded, ok := strings.CutPrefix(r.Header.Get("Proxy-Authorization"), "Basic "); ok {
+		if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			username, password, _ = strings.Cut(string(decoded), ":")
+		}
+	}
+	return hp.creds.Valid(username, password, r.RemoteAddr)
+}
+
+// tunnel handles a CONNECT request by relaying raw bytes between the client
+// and the target
+func (hp *httpProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := net.SplitHostPort(r.Host); err != nil {
+		http.Error(w, "CONNECT target must be host:port", http.StatusBadRequest)
+		return
+	}
+
+	target, err := hp.dialHost(r.Context(), "tcp", r.Host)
+	if err != nil {
+		hp.logger.Debug("HTTP proxy CONNECT failed", "target", r.Host, "error", err)
+		if errors.Is(err, errRouteRejected) {
+			http.Error(w, "Destination not allowed", http.StatusForbidden)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to connect to %s", r.Host), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "Tunnelling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	// Hijacked connections are not closed by the HTTP server
+	if !hp.track(client, target) {
+		client.Close()
+		target.Close()
+		return
+	}
+	defer hp.untrack(client, target)
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		target.Close()
+		return
+	}
+	// Bytes the client sent right after the request are already buffered
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		if _, err := target.Write(data); err != nil {
+			client.Close()
+			target.Close()
+			return
+		}
+	}
+
+	relay(client, target)
+}
+
+// relay copies data both ways until either side closes, then closes both
+func relay(c1, c2 net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		c1.Close()
+		c2.Close()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(c2, c1)
+		once.Do(closeBoth)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(c1, c2)
+		once.Do(closeBoth)
+	}()
+	wg.Wait()
+}
+
+// track registers the connections of a tunnel. It returns false once the
+// proxy is closed.
+func (hp *httpProxy) track(conns ...net.Conn) bool {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	if hp.closed {
+		return false
+	}
+	for _, c := range conns {
+		hp.tunnels[c] = struct{}{}
+	}
+	return true
+}
+
+// untrack removes the connections of a finished tunnel
+func (hp *httpProxy) untrack(conns ...net.Conn) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	for _, c := range conns {
+		delete(hp.tunnels, c)
+	}
+}
+
+// close drops the idle forwarded connections and ends open tunnels
+func (hp *httpProxy) close() {
+	hp.transport.CloseIdleConnections()
+
+	hp.mu.Lock()
+	tunnels := hp.tunnels
+	hp.tunnels = make(map[net.Conn]struct{})
+	hp.closed = true
+	hp.mu.Unlock()
+
+	for c := range tunnels {
+		c.Close()
+	}
+}
@@ -0,0 +1,243 @@
+package yggdrasil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// newTestHTTPProxy serves an HTTP proxy that dials on loopback instead of
+// through Yggdrasil
+func newTestHTTPProxy(t *testing.T, creds *socksCredentials) *httptest.Server {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	var dialer net.Dialer
+	hp := newHTTPProxy(dialer.DialContext, NewNameResolver(nil, ""), creds, log)
+	server := httptest.NewServer(hp)
+	t.Cleanup(func() {
+		server.Close()
+		hp.close()
+	})
+	return server
+}
+
+func proxyClient(proxyURL string) *http.Client {
+	u, _ := url.Parse(proxyURL)
+	return &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(u)},
+		Timeout:   5 * time.Second,
+	}
+}
+
+func TestHTTPProxy_Forward(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != "" {
+			t.Error("Proxy-Authorization should not be forwarded")
+		}
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	}))
+	defer backend.Close()
+
+	proxy := newTestHTTPProxy(t, nil)
+	resp, err := proxyClient(proxy.URL).Get(backend.URL + "/pkg")
+	if err != nil {
+		t.Fatalf("GET through proxy error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello /pkg" {
+		t.Errorf("response = %d %q", resp.StatusCode, body)
+	}
+
+	// Names other than .pk.ygg need a nameserver
+	resp, err = proxyClient(proxy.URL).Get("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("unresolvable host status = %d, want 502", resp.StatusCode)
+	}
+
+	// Origin-form requests are not proxy requests
+	resp, err = http.Get(proxy.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("origin-form status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestHTTPProxy_Connect(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	proxy := newTestHTTPProxy(t, nil)
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The first payload bytes follow the request without waiting for the reply
+	target := echo.Addr().String()
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\nping", target, target)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT response = %v, %v", resp, err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("tunnel echo = %q, %v", buf, err)
+	}
+}
+
+func TestHTTPProxy_Auth(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	sp := NewSOCKSProxy(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	proxy := newTestHTTPProxy(t, &socksCredentials{proxy: sp, username: "alice", password: "secret"})
+
+	resp, err := proxyClient(proxy.URL).Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusProxyAuthRequired || resp.Header.Get("Proxy-Authenticate") == "" {
+		t.Errorf("unauthenticated status = %d", resp.StatusCode)
+	}
+
+	u, _ := url.Parse(proxy.URL)
+	u.User = url.UserPassword("alice", "secret")
+	resp, err = proxyClient(u.String()).Get(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("authenticated status = %d, want 200", resp.StatusCode)
+	}
+
+	if got := sp.GetStats().AuthFailures; got != 1 {
+		t.Errorf("AuthFailures = %d, want 1", got)
+	}
+}
+
+func TestHTTPProxy_CloseEndsTunnels(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	upstream := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := target.Accept(); err == nil {
+			upstream <- conn
+		}
+	}()
+
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	var dialer net.Dialer
+	hp := newHTTPProxy(dialer.DialContext, NewNameResolver(nil, ""), nil, log)
+	server := httptest.NewServer(hp)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	addr := target.Addr().String()
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", addr, addr)
+	reader := bufio.NewReader(conn)
+	if resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect}); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT response = %v, %v", resp, err)
+	}
+	var up net.Conn
+	select {
+	case up = <-upstream:
+		defer up.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel did not reach the target")
+	}
+
+	// Closing the proxy ends the tunnel on both sides
+	hp.close()
+	if _, err := reader.ReadByte(); err == nil {
+		t.Error("client side of the tunnel is still open")
+	}
+	up.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := up.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("target side read error = %v, want EOF", err)
+	}
+}
+
+func TestSOCKSProxy_FailedStartKeepsConfig(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	svc := NewServiceWithConfig(newTestConfigManager(t), log)
+	if err := svc.Start(nil); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer svc.Stop()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	sp := NewSOCKSProxy(svc, log)
+	before := sp.GetConfig()
+	bad := SOCKSConfig{Enabled: true, ListenAddress: "127.0.0.1:0", HTTPListenAddress: busy.Addr().String()}
+	if err := sp.Start(bad); err == nil {
+		t.Fatal("Start() with a busy HTTP address should fail")
+	}
+	if sp.IsRunning() || sp.GetConfig().HTTPListenAddress != before.HTTPListenAddress {
+		t.Errorf("failed Start() took the config: %+v", sp.GetConfig())
+	}
+
+	good := SOCKSConfig{Enabled: true, ListenAddress: "127.0.0.1:0"}
+	if err := sp.Start(good); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := sp.SetConfig(bad); err == nil {
+		t.Fatal("SetConfig() with a busy HTTP address should fail")
+	}
+	if got := sp.GetConfig(); got.HTTPListenAddress != "" || got.ListenAddress != good.ListenAddress {
+		t.Errorf("failed SetConfig() took the config: %+v", got)
+	}
+}
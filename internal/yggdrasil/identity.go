@@ -0,0 +1,180 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+const (
+	// identityFormat marks identity bundle files
+	identityFormat = "yggstack-identity"
+	// identityVersion is the current identity bundle version
+	identityVersion = 1
+	// MinPassphraseLength is the shortest passphrase accepted for bundles
+	MinPassphraseLength = 8
+)
+
+var (
+	ErrPassphraseTooShort = fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLength)
+	ErrWrongPassphrase    = errors.New("wrong passphrase or corrupted identity bundle")
+)
+
+// IdentityBundle is the sealed content of an identity backup. Peers and
+// mappings are optional.
+type IdentityBundle struct {
+	PrivateKey  string                   `json:"privateKey"`
+	PublicKey   string                   `json:"publicKey"`
+	IPv6Address string                   `json:"ipv6Address"`
+	Peers       []string                 `json:"peers,omitempty"`
+	Mappings    *config.MappingsSettings `json:"mappings,omitempty"`
+}
+
+// identityFile is the on-disk format of an identity bundle. The public key
+// and address are readable without the passphrase.
+type identityFile struct {
+	Format      string `json:"format"`
+	Version     int    `json:"version"`
+	PublicKey   string `json:"publicKey"`
+	IPv6Address string `json:"ipv6Address"`
+	Data        []byte `json:"data"` // Encrypted JSON of IdentityBundle
+}
+
+// addressForKey returns the Yggdrasil IPv6 address of a public key
+func addressForKey(publicKey ed25519.PublicKey) string {
+	addr := address.AddrForKey(publicKey)
+	return net.IP(addr[:]).String()
+}
+
+// verifyIdentity checks that the private key of b derives its public key
+// and address
+func verifyIdentity(b *IdentityBundle) error {
+	key, err := hex.DecodeString(b.PrivateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key")
+	}
+	defer security.ZeroBytes(key)
+
+	publicKey := ed25519.PrivateKey(key).Public().(ed25519.PublicKey)
+	if hex.EncodeToString(publicKey) != b.PublicKey {
+		return fmt.Errorf("private key does not match the public key")
+	}
+	if addressForKey(publicKey) != b.IPv6Address {
+		return fmt.Errorf("public key does not match the IPv6 address")
+	}
+	return nil
+}
+
+// SealIdentity encrypts an identity bundle with passphrase
+func SealIdentity(b *IdentityBundle, passphrase string) ([]byte, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, ErrPassphraseTooShort
+	}
+	if err := verifyIdentity(b); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	defer security.ZeroBytes(plaintext)
+
+	sealed, err := security.EncryptWithPassword(plaintext, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(identityFile{
+		Format:      identityFormat,
+		Version:     identityVersion,
+		PublicKey:   b.PublicKey,
+		IPv6Address: b.IPv6Address,
+		Data:        sealed,
+	}, "", "  ")
+}
+
+// OpenIdentity decrypts an identity bundle and verifies that its keys and
+// address belong together
+func OpenIdentity(data []byte, passphrase string) (*IdentityBundle, error) {
+	var f identityFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != identityFormat {
+		return nil, fmt.Errorf("not an identity bundle")
+	}
+	if f.Version != identityVersion {
+		return nil, fmt.Errorf("unsupported identity bundle version %d", f.Version)
+	}
+
+	plaintext, err := security.DecryptWithPassword(f.Data, passphrase)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	defer security.ZeroBytes(plaintext)
+
+	var b IdentityBundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	if b.PublicKey != f.PublicKey || b.IPv6Address != f.IPv6Address {
+		return nil, fmt.Errorf("identity bundle header does not match its content")
+	}
+	if err := verifyIdentity(&b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// Identity returns the node identity as a bundle, with the configured
+// peers if includePeers is set
+func (cm *ConfigManager) Identity(includePeers bool) (*IdentityBundle, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.PrivateKey == "" || cm.config.PublicKey == "" {
+		return nil, fmt.Errorf("node has no identity")
+	}
+	publicKey, err := hex.DecodeString(cm.config.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key")
+	}
+
+	b := &IdentityBundle{
+		PrivateKey:  cm.config.PrivateKey,
+		PublicKey:   cm.config.PublicKey,
+		IPv6Address: addressForKey(publicKey),
+	}
+	if includePeers {
+		b.Peers = append([]string{}, cm.config.Peers...)
+	}
+	return b, nil
+}
+
+// SetIdentity replaces the node keys with those of b. Peers of the bundle,
+// if any, replace the configured peers. Call Save to persist the change.
+func (cm *ConfigManager) SetIdentity(b *IdentityBundle) error {
+	if err := verifyIdentity(b); err != nil {
+		return err
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.config.PrivateKey = b.PrivateKey
+	cm.config.PublicKey = b.PublicKey
+	if len(b.Peers) > 0 {
+		cm.config.Peers = append([]string{}, b.Peers...)
+	}
+
+	cm.logger.Info("Identity imported", "publicKey", b.PublicKey[:16]+"...")
+	return nil
+}
@@ -0,0 +1,105 @@
+package yggdrasil
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+const testPassphrase = "correct horse battery"
+
+func TestIdentityBundle_RoundTrip(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	src := NewConfigManagerWithPath(filepath.Join(t.TempDir(), "src.conf"), log)
+	if err := src.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	src.SetPeers([]string{"tls://peer.example.com:443"})
+
+	b, err := src.Identity(true)
+	if err != nil {
+		t.Fatalf("Identity() error = %v", err)
+	}
+	b.Mappings = &config.MappingsSettings{
+		LocalTCP: []config.PortMapping{{ID: "a", Source: "127.0.0.1:8080", Target: "[200::1]:80", Enabled: true}},
+	}
+
+	if _, err := SealIdentity(b, "short"); err != ErrPassphraseTooShort {
+		t.Errorf("SealIdentity() with short passphrase error = %v", err)
+	}
+
+	data, err := SealIdentity(b, testPassphrase)
+	if err != nil {
+		t.Fatalf("SealIdentity() error = %v", err)
+	}
+	if strings.Contains(string(data), b.PrivateKey) || strings.Contains(string(data), "peer.example.com") {
+		t.Error("sealed bundle leaks its content")
+	}
+
+	if _, err := OpenIdentity(data, "wrong passphrase"); err != ErrWrongPassphrase {
+		t.Errorf("OpenIdentity() with wrong passphrase error = %v", err)
+	}
+
+	opened, err := OpenIdentity(data, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenIdentity() error = %v", err)
+	}
+	if opened.PublicKey != b.PublicKey || opened.IPv6Address != b.IPv6Address {
+		t.Error("identity changed during round trip")
+	}
+	if opened.Mappings == nil || len(opened.Mappings.LocalTCP) != 1 {
+		t.Error("mappings lost during round trip")
+	}
+
+	dst := NewConfigManagerWithPath(filepath.Join(t.TempDir(), "dst.conf"), log)
+	if err := dst.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := dst.SetIdentity(opened); err != nil {
+		t.Fatalf("SetIdentity() error = %v", err)
+	}
+	info := dst.GetConfigInfo()
+	if info.PublicKey != b.PublicKey || info.IPv6Address != b.IPv6Address {
+		t.Errorf("imported identity = %s %s, want %s %s",
+			info.PublicKey, info.IPv6Address, b.PublicKey, b.IPv6Address)
+	}
+	if peers := dst.GetPeers(); len(peers) != 1 || peers[0] != "tls://peer.example.com:443" {
+		t.Errorf("imported peers = %v", peers)
+	}
+}
+
+func TestOpenIdentity_Tampered(t *testing.T) {
+	cm := NewConfigManagerWithPath(filepath.Join(t.TempDir(), "a.conf"),
+		logger.NewWithConfig(logger.Config{Level: "error", Console: false}))
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	b, err := cm.Identity(false)
+	if err != nil {
+		t.Fatalf("Identity() error = %v", err)
+	}
+
+	data, err := SealIdentity(b, testPassphrase)
+	if err != nil {
+		t.Fatalf("SealIdentity() error = %v", err)
+	}
+
+	// Swap the readable address for another one
+	var f identityFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	f.IPv6Address = "200::1"
+	tampered, _ := json.Marshal(f)
+
+	if _, err := OpenIdentity(tampered, testPassphrase); err == nil {
+		t.Error("OpenIdentity() accepted a bundle with a mismatched address")
+	}
+	if _, err := OpenIdentity([]byte(`{"PrivateKey":"x"}`), testPassphrase); err == nil {
+		t.Error("OpenIdentity() accepted a config file")
+	}
+}
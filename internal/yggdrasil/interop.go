@@ -0,0 +1,409 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hjson/hjson-go/v4"
+	yggconfig "github.com/yggdrasil-network/yggdrasil-go/src/config"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+)
+
+// Export formats
+const (
+	FormatHJSON = "hjson"
+	FormatJSON  = "json"
+)
+
+// defaultForwardHost is the local address used by yggstack when a
+// mapping argument has no host part
+const defaultForwardHost = "127.0.0.1"
+
+// ImportedConfig is the result of parsing a yggdrasil-go or yggstack config
+type ImportedConfig struct {
+	Config   *Config                 // Node settings, PrivateKey is empty if none was found
+	Proxy    *config.ProxySettings   // From -socks and -nameserver, nil if absent
+	Mappings config.MappingsSettings // From -local-tcp style arguments, IDs are empty
+}
+
+// ParseNodeConfig parses a yggdrasil.conf in HJSON or JSON format and
+// optional yggstack command-line arguments such as
+// "-socks 127.0.0.1:1080 -local-tcp 8080:[200::1]:80".
+// Content may come from any IPC caller, so PrivateKeyPath is refused; use
+// ParseNodeConfigFile for configs read from disk.
+func ParseNodeConfig(data []byte, args string) (*ImportedConfig, error) {
+	return parseNodeConfig(data, args, "")
+}
+
+// ParseNodeConfigFile reads and parses a yggdrasil.conf like ParseNodeConfig.
+// A PrivateKeyPath in the file is followed, relative to the file.
+func ParseNodeConfigFile(path, args string) (*ImportedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNodeConfig(data, args, filepath.Dir(path))
+}
+
+// parseNodeConfig parses a node config. keyDir is the directory that
+// PrivateKeyPath is resolved against; empty refuses PrivateKeyPath.
+func parseNodeConfig(data []byte, args, keyDir string) (*ImportedConfig, error) {
+	var nc yggconfig.NodeConfig
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := hjson.Unmarshal(data, &nc); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	if nc.PrivateKeyPath != "" {
+		if keyDir == "" {
+			return nil, fmt.Errorf("PrivateKeyPath is only supported when importing a config file by path")
+		}
+		keyPath := nc.PrivateKeyPath
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(keyDir, keyPath)
+		}
+		pem, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		if err := nc.UnmarshalPEMPrivateKey(pem); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := &Config{
+		Peers:               nonNil(nc.Peers),
+		Listen:              nonNil(nc.Listen),
+		InterfacePeers:      nc.InterfacePeers,
+		MulticastInterfaces: []string{},
+		AllowedPublicKeys:   nonNil(nc.AllowedPublicKeys),
+		NodeInfo:            nc.NodeInfo,
+		NodeInfoPrivacy:     nc.NodeInfoPrivacy,
+	}
+	if cfg.InterfacePeers == nil {
+		cfg.InterfacePeers = make(map[string][]string)
+	}
+
+	if len(nc.PrivateKey) > 0 {
+		if len(nc.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid private key length: %d", len(nc.PrivateKey))
+		}
+		privateKey := ed25519.PrivateKey(nc.PrivateKey)
+		cfg.PrivateKey = hex.EncodeToString(privateKey)
+		cfg.PublicKey = hex.EncodeToString(privateKey.Public().(ed25519.PublicKey))
+	}
+
+	// Only the interface pattern is kept, disabled entries are dropped
+	for _, intf := range nc.MulticastInterfaces {
+		if intf.Beacon || intf.Listen {
+			cfg.MulticastInterfaces = append(cfg.MulticastInterfaces, intf.Regex)
+		}
+	}
+
+	imported := &ImportedConfig{
+		Config: cfg,
+		Mappings: config.MappingsSettings{
+			LocalTCP:  []config.PortMapping{},
+			RemoteTCP: []config.PortMapping{},
+			LocalUDP:  []config.PortMapping{},
+			RemoteUDP: []config.PortMapping{},
+		},
+	}
+
+	if err := parseYggstackArgs(args, imported); err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+// Import replaces the node settings with an imported config.
+// The current identity is kept if the imported config has no private key.
+func (cm *ConfigManager) Import(cfg *Config) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	imported := *cfg
+	if imported.PrivateKey == "" {
+		imported.PrivateKey = cm.config.PrivateKey
+		imported.PublicKey = cm.config.PublicKey
+	}
+	if len(imported.Listen) == 0 {
+		imported.Listen = []string{"tcp://0.0.0.0:0"}
+	}
+
+	cm.config = &imported
+	cm.logger.Info("Configuration imported", "peers", len(imported.Peers))
+}
+
+// Export returns the node settings as a yggdrasil-go config file
+func (cm *ConfigManager) Export(format string) ([]byte, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	// Start from platform defaults so the file is usable by yggdrasil-go as is
+	nc := yggconfig.GenerateConfig()
+	nc.Certificate = nil
+	nc.PrivateKey = nil
+
+	if cm.config.PrivateKey != "" {
+		key, err := hex.DecodeString(cm.config.PrivateKey)
+		if err != nil || len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid private key in configuration")
+		}
+		nc.PrivateKey = yggconfig.KeyBytes(key)
+	}
+
+	nc.Peers = nonNil(cm.config.Peers)
+	nc.Listen = nonNil(cm.config.Listen)
+	nc.AllowedPublicKeys = nonNil(cm.config.AllowedPublicKeys)
+	nc.NodeInfo = cm.config.NodeInfo
+	nc.NodeInfoPrivacy = cm.config.NodeInfoPrivacy
+	if cm.config.InterfacePeers != nil {
+		nc.InterfacePeers = cm.config.InterfacePeers
+	}
+
+	if len(cm.config.MulticastInterfaces) > 0 {
+		nc.MulticastInterfaces = multicastInterfaces(cm.config.MulticastInterfaces)
+	}
+
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(nc, "", "  ")
+	case FormatHJSON, "":
+		return hjson.Marshal(nc)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// YggstackArgs returns yggstack command-line arguments for the proxy and mappings.
+// Disabled proxy and mappings are left out.
+func YggstackArgs(proxy config.ProxySettings, mappings config.MappingsSettings) (string, error) {
+	var args []string
+
+	if proxy.Enabled && proxy.ListenAddress != "" {
+		args = append(args, "-socks", proxy.ListenAddress)
+		if proxy.Nameserver != "" {
+			args = append(args, "-nameserver", proxy.Nameserver)
+		}
+	}
+
+	groups := []struct {
+		mappingType MappingType
+		mappings    []config.PortMapping
+	}{
+		{MappingLocalTCP, mappings.LocalTCP},
+		{MappingRemoteTCP, mappings.RemoteTCP},
+		{MappingLocalUDP, mappings.LocalUDP},
+		{MappingRemoteUDP, mappings.RemoteUDP},
+	}
+
+	for _, group := range groups {
+		for _, m := range group.mappings {
+			if !m.Enabled {
+				continue
+			}
+			value, err := formatYggstackMapping(group.mappingType, m)
+			if err != nil {
+				return "", fmt.Errorf("mapping %s: %w", m.ID, err)
+			}
+			args = append(args, "-"+string(group.mappingType), value)
+		}
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+// parseYggstackArgs reads proxy and mapping flags from yggstack arguments.
+// Unknown flags are ignored so a full command line can be pasted.
+func parseYggstackArgs(args string, imported *ImportedConfig) error {
+	fields := strings.Fields(args)
+
+	for i := 0; i < len(fields); i++ {
+		name := strings.TrimLeft(fields[i], "-")
+		if name == fields[i] {
+			continue // Not a flag
+		}
+
+		var value string
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			name, value = name[:eq], name[eq+1:]
+		} else if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+
+		switch name {
+		case "socks":
+			if imported.Proxy == nil {
+				imported.Proxy = &config.ProxySettings{}
+			}
+			imported.Proxy.Enabled = true
+			imported.Proxy.ListenAddress = value
+
+		case "nameserver":
+			if imported.Proxy == nil {
+				imported.Proxy = &config.ProxySettings{}
+			}
+			imported.Proxy.Nameserver = value
+
+		case "local-tcp", "remote-tcp", "local-udp", "remote-udp":
+			m, err := parseYggstackMapping(MappingType(name), value)
+			if err != nil {
+				return fmt.Errorf("invalid -%s value %q: %w", name, value, err)
+			}
+			target := map[MappingType]*[]config.PortMapping{
+				MappingLocalTCP:  &imported.Mappings.LocalTCP,
+				MappingRemoteTCP: &imported.Mappings.RemoteTCP,
+				MappingLocalUDP:  &imported.Mappings.LocalUDP,
+				MappingRemoteUDP: &imported.Mappings.RemoteUDP,
+			}[MappingType(name)]
+			*target = append(*target, m)
+
+		default:
+			continue
+		}
+
+		if !strings.Contains(fields[i], "=") {
+			i++ // Skip the value
+		}
+	}
+
+	return nil
+}
+
+// parseYggstackMapping parses a yggstack mapping value.
+//
+// Local mappings: [local-addr:]local-port:remote-addr:remote-port,
+// e.g. 22:[200::1]:2022 or 127.0.0.1:22:[200::1]:22.
+//
+// Remote mappings: [yggdrasil-port:][local-addr:]local-port,
+// e.g. 22, 2022:22 or 22:192.168.1.1:2022.
+func parseYggstackMapping(mappingType MappingType, value string) (config.PortMapping, error) {
+	parts := splitMappingValue(value)
+	for _, p := range parts {
+		if p == "" {
+			return config.PortMapping{}, fmt.Errorf("empty address part")
+		}
+	}
+
+	m := config.PortMapping{Enabled: true}
+
+	switch mappingType {
+	case MappingLocalTCP, MappingLocalUDP:
+		switch len(parts) {
+		case 3:
+			m.Source = net.JoinHostPort(defaultForwardHost, parts[0])
+			m.Target = net.JoinHostPort(parts[1], parts[2])
+		case 4:
+			m.Source = net.JoinHostPort(parts[0], parts[1])
+			m.Target = net.JoinHostPort(parts[2], parts[3])
+		default:
+			return m, fmt.Errorf("expected [local-addr:]local-port:remote-addr:remote-port")
+		}
+
+	case MappingRemoteTCP, MappingRemoteUDP:
+		switch len(parts) {
+		case 1:
+			m.Source = net.JoinHostPort("", parts[0])
+			m.Target = net.JoinHostPort(defaultForwardHost, parts[0])
+		case 2:
+			m.Source = net.JoinHostPort("", parts[0])
+			m.Target = net.JoinHostPort(defaultForwardHost, parts[1])
+		case 3:
+			m.Source = net.JoinHostPort("", parts[0])
+			m.Target = net.JoinHostPort(parts[1], parts[2])
+		default:
+			return m, fmt.Errorf("expected [yggdrasil-port:][local-addr:]local-port")
+		}
+
+	default:
+		return m, fmt.Errorf("invalid mapping type: %s", mappingType)
+	}
+
+	for _, addr := range []string{m.Source, m.Target} {
+		_, port, _ := net.SplitHostPort(addr)
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return m, fmt.Errorf("invalid port in %s", addr)
+		}
+	}
+
+	return m, nil
+}
+
+// formatYggstackMapping formats a mapping as a yggstack flag value
+func formatYggstackMapping(mappingType MappingType, m config.PortMapping) (string, error) {
+	_, sourcePort, err := net.SplitHostPort(m.Source)
+	if err != nil {
+		return "", err
+	}
+	targetHost, targetPort, err := net.SplitHostPort(m.Target)
+	if err != nil {
+		return "", err
+	}
+	target := net.JoinHostPort(targetHost, targetPort)
+
+	switch mappingType {
+	case MappingLocalTCP, MappingLocalUDP:
+		return m.Source + ":" + target, nil
+	case MappingRemoteTCP, MappingRemoteUDP:
+		// yggstack always listens on the node address, only the port matters
+		return sourcePort + ":" + target, nil
+	default:
+		return "", fmt.Errorf("invalid mapping type: %s", mappingType)
+	}
+}
+
+// splitMappingValue splits a mapping value on colons outside of brackets.
+// Brackets around IPv6 addresses are removed.
+func splitMappingValue(value string) []string {
+	var parts []string
+	var current strings.Builder
+	inBrackets := false
+
+	for _, r := range value {
+		switch {
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	return append(parts, current.String())
+}
+
+// multicastInterfaces converts interface patterns to yggdrasil-go settings
+func multicastInterfaces(patterns []string) []yggconfig.MulticastInterfaceConfig {
+	result := make([]yggconfig.MulticastInterfaceConfig, 0, len(patterns))
+	for _, regex := range patterns {
+		result = append(result, yggconfig.MulticastInterfaceConfig{
+			Regex:  regex,
+			Beacon: true,
+			Listen: true,
+		})
+	}
+	return result
+}
+
+// nonNil returns an empty slice instead of nil so configs serialize as []
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
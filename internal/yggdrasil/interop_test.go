@@ -0,0 +1,283 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yggconfig "github.com/yggdrasil-network/yggdrasil-go/src/config"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+)
+
+// testHJSON is a yggdrasil.conf as written by yggdrasil -genconf
+const testHJSON = `
+{
+  # Your private key. DO NOT share this with anyone!
+  PrivateKey: %s
+  Peers: [
+    tls://peer.example.com:443
+  ]
+  InterfacePeers: {}
+  Listen: [
+    tls://[::]:0
+  ]
+  AdminListen: unix:///var/run/yggdrasil.sock
+  MulticastInterfaces: [
+    {
+      Regex: .*
+      Beacon: true
+      Listen: true
+      Password: ""
+    }
+    {
+      Regex: wlan0
+      Beacon: false
+      Listen: false
+    }
+  ]
+  AllowedPublicKeys: []
+  IfName: auto
+  IfMTU: 65535
+  NodeInfoPrivacy: true
+  NodeInfo: {
+    name: test-node
+  }
+}
+`
+
+func testPrivateKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+func TestParseNodeConfig(t *testing.T) {
+	key := testPrivateKey(t)
+	data := strings.Replace(testHJSON, "%s", hex.EncodeToString(key), 1)
+
+	imported, err := ParseNodeConfig([]byte(data), "")
+	if err != nil {
+		t.Fatalf("ParseNodeConfig() error = %v", err)
+	}
+
+	cfg := imported.Config
+	if cfg.PrivateKey != hex.EncodeToString(key) {
+		t.Error("private key not imported")
+	}
+	if cfg.PublicKey != hex.EncodeToString(key.Public().(ed25519.PublicKey)) {
+		t.Error("public key not derived from private key")
+	}
+	if len(cfg.Peers) != 1 || cfg.Peers[0] != "tls://peer.example.com:443" {
+		t.Errorf("Peers = %v", cfg.Peers)
+	}
+	if len(cfg.Listen) != 1 || cfg.Listen[0] != "tls://[::]:0" {
+		t.Errorf("Listen = %v", cfg.Listen)
+	}
+	if len(cfg.MulticastInterfaces) != 1 || cfg.MulticastInterfaces[0] != ".*" {
+		t.Errorf("MulticastInterfaces = %v, want only enabled patterns", cfg.MulticastInterfaces)
+	}
+	if cfg.NodeInfo["name"] != "test-node" || !cfg.NodeInfoPrivacy {
+		t.Errorf("NodeInfo = %v, privacy = %v", cfg.NodeInfo, cfg.NodeInfoPrivacy)
+	}
+	if imported.Proxy != nil {
+		t.Error("Proxy should be nil without -socks")
+	}
+}
+
+func TestParseNodeConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		args string
+	}{
+		{"bad syntax", "{ Peers: [ }", ""},
+		{"short key", "{ PrivateKey: abcd }", ""},
+		{"bad mapping", "", "-local-tcp 8080"},
+		{"bad port", "", "-remote-tcp 99999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseNodeConfig([]byte(tt.data), tt.args); err == nil {
+				t.Error("ParseNodeConfig() should fail")
+			}
+		})
+	}
+}
+
+func TestParseYggstackArgs(t *testing.T) {
+	args := "yggstack -useconffile /etc/yggdrasil.conf -socks=127.0.0.1:1080 -nameserver [308:62:45:62::]:53 " +
+		"-local-tcp 8080:[200::1]:80 -local-tcp 127.0.0.2:22:[200::2]:22 " +
+		"--remote-tcp 22 -remote-tcp 2022:22 -remote-udp 53:192.168.1.1:5353 -local-udp [::1]:53:[200::3]:53"
+
+	imported, err := ParseNodeConfig(nil, args)
+	if err != nil {
+		t.Fatalf("ParseNodeConfig() error = %v", err)
+	}
+
+	want := config.ProxySettings{Enabled: true, ListenAddress: "127.0.0.1:1080", Nameserver: "[308:62:45:62::]:53"}
+	if imported.Proxy == nil || !reflect.DeepEqual(*imported.Proxy, want) {
+		t.Errorf("Proxy = %+v, want %+v", imported.Proxy, want)
+	}
+
+	check := func(name string, got []config.PortMapping, want [][2]string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d mappings, want %d: %+v", name, len(got), len(want), got)
+		}
+		for i, w := range want {
+			if got[i].Source != w[0] || got[i].Target != w[1] || !got[i].Enabled {
+				t.Errorf("%s[%d] = %s -> %s, want %s -> %s", name, i, got[i].Source, got[i].Target, w[0], w[1])
+			}
+		}
+	}
+
+	check("LocalTCP", imported.Mappings.LocalTCP, [][2]string{
+		{"127.0.0.1:8080", "[200::1]:80"},
+		{"127.0.0.2:22", "[200::2]:22"},
+	})
+	check("RemoteTCP", imported.Mappings.RemoteTCP, [][2]string{
+		{":22", "127.0.0.1:22"},
+		{":2022", "127.0.0.1:22"},
+	})
+	check("RemoteUDP", imported.Mappings.RemoteUDP, [][2]string{
+		{":53", "192.168.1.1:5353"},
+	})
+	check("LocalUDP", imported.Mappings.LocalUDP, [][2]string{
+		{"[::1]:53", "[200::3]:53"},
+	})
+}
+
+func TestConfigManager_ExportRoundTrip(t *testing.T) {
+	cm := newTestConfigManager(t)
+	key := testPrivateKey(t)
+
+	cm.Import(&Config{
+		PrivateKey:          hex.EncodeToString(key),
+		PublicKey:           hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Peers:               []string{"tcp://a.example.com:1234"},
+		Listen:              []string{"tcp://0.0.0.0:5000"},
+		MulticastInterfaces: []string{"eth.*"},
+		AllowedPublicKeys:   []string{},
+		NodeInfo:            map[string]interface{}{"name": "exported"},
+	})
+
+	for _, format := range []string{FormatHJSON, FormatJSON} {
+		data, err := cm.Export(format)
+		if err != nil {
+			t.Fatalf("Export(%s) error = %v", format, err)
+		}
+
+		imported, err := ParseNodeConfig(data, "")
+		if err != nil {
+			t.Fatalf("ParseNodeConfig(%s) error = %v", format, err)
+		}
+
+		cfg := imported.Config
+		if cfg.PrivateKey != cm.GetPrivateKey() {
+			t.Errorf("%s: private key changed", format)
+		}
+		if len(cfg.Peers) != 1 || cfg.Peers[0] != "tcp://a.example.com:1234" {
+			t.Errorf("%s: Peers = %v", format, cfg.Peers)
+		}
+		if len(cfg.MulticastInterfaces) != 1 || cfg.MulticastInterfaces[0] != "eth.*" {
+			t.Errorf("%s: MulticastInterfaces = %v", format, cfg.MulticastInterfaces)
+		}
+		if cfg.NodeInfo["name"] != "exported" {
+			t.Errorf("%s: NodeInfo = %v", format, cfg.NodeInfo)
+		}
+	}
+
+	if _, err := cm.Export("yaml"); err == nil {
+		t.Error("Export(yaml) should fail")
+	}
+}
+
+func TestConfigManager_ImportKeepsIdentity(t *testing.T) {
+	cm := newTestConfigManager(t)
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	privateKey := cm.GetPrivateKey()
+
+	cm.Import(&Config{Peers: []string{"tcp://b.example.com:1"}})
+
+	if cm.GetPrivateKey() != privateKey {
+		t.Error("Import without key should keep the current identity")
+	}
+	if len(cm.GetListen()) == 0 {
+		t.Error("Import should keep a default listen address")
+	}
+}
+
+func TestYggstackArgs(t *testing.T) {
+	proxy := config.ProxySettings{Enabled: true, ListenAddress: "127.0.0.1:1080"}
+	mappings := config.MappingsSettings{
+		LocalTCP:  []config.PortMapping{{ID: "a", Source: "127.0.0.1:8080", Target: "[200::1]:80", Enabled: true}},
+		RemoteTCP: []config.PortMapping{{ID: "b", Source: "[::]:2022", Target: "127.0.0.1:22", Enabled: true}},
+		LocalUDP:  []config.PortMapping{{ID: "c", Source: "127.0.0.1:53", Target: "[200::1]:53", Enabled: false}},
+	}
+
+	args, err := YggstackArgs(proxy, mappings)
+	if err != nil {
+		t.Fatalf("YggstackArgs() error = %v", err)
+	}
+
+	want := "-socks 127.0.0.1:1080 -local-tcp 127.0.0.1:8080:[200::1]:80 -remote-tcp 2022:127.0.0.1:22"
+	if args != want {
+		t.Errorf("YggstackArgs() = %q, want %q", args, want)
+	}
+
+	// Arguments parse back to the same mappings
+	imported, err := ParseNodeConfig(nil, args)
+	if err != nil {
+		t.Fatalf("ParseNodeConfig() error = %v", err)
+	}
+	if got := imported.Mappings.LocalTCP; len(got) != 1 || got[0].Target != "[200::1]:80" {
+		t.Errorf("LocalTCP = %+v", got)
+	}
+	if got := imported.Mappings.RemoteTCP; len(got) != 1 || got[0].Source != ":2022" {
+		t.Errorf("RemoteTCP = %+v", got)
+	}
+}
+
+func TestParseNodeConfig_PrivateKeyPath(t *testing.T) {
+	dir := t.TempDir()
+	var nc yggconfig.NodeConfig
+	nc.NewPrivateKey()
+	pem, err := nc.MarshalPEMPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "node.pem"), pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("{\n  PrivateKeyPath: node.pem\n  Peers: []\n}\n")
+
+	// Inline content must not make the application read local files
+	if _, err := ParseNodeConfig(content, ""); err == nil {
+		t.Error("ParseNodeConfig() should refuse PrivateKeyPath")
+	}
+
+	// A config file may point to a key next to it
+	path := filepath.Join(dir, "yggdrasil.conf")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ParseNodeConfigFile(path, "")
+	if err != nil {
+		t.Fatalf("ParseNodeConfigFile() error = %v", err)
+	}
+	want := hex.EncodeToString(ed25519.PrivateKey(nc.PrivateKey).Public().(ed25519.PublicKey))
+	if imported.Config.PublicKey != want {
+		t.Errorf("PublicKey = %s, want %s", imported.Config.PublicKey, want)
+	}
+}
@@ -0,0 +1,343 @@
+package yggdrasil
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+const (
+	// keySearchReportInterval is how often progress is reported
+	keySearchReportInterval = 500 * time.Millisecond
+	// keySearchBatch is how many keys a worker tries between counter updates
+	keySearchBatch = 256
+)
+
+// KeySearchOptions configures a key search
+type KeySearchOptions struct {
+	// Workers is the number of search goroutines, GOMAXPROCS if zero
+	Workers int `json:"workers,omitempty"`
+	// Prefix is an IPv6 prefix the address must start with, e.g. "201:ab".
+	// The first group and every group but the last are full groups; the
+	// last one gives leading hex digits.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// KeySearchStatus reports the progress of a key search
+type KeySearchStatus struct {
+	Running       bool    `json:"running"`
+	Prefix        string  `json:"prefix,omitempty"`
+	Workers       int     `json:"workers"`
+	Tried         uint64  `json:"tried"`
+	Rate          float64 `json:"rate"`    // keys per second
+	Elapsed       int64   `json:"elapsed"` // milliseconds
+	Found         bool    `json:"found"`
+	BestAddress   string  `json:"bestAddress,omitempty"`
+	BestPublicKey string  `json:"bestPublicKey,omitempty"`
+	LeadingOnes   int     `json:"leadingOnes"`
+}
+
+// KeySearch looks for node keys with stronger addresses, like the genkeys
+// tool of yggdrasil-go. A key is better when its public key is smaller,
+// which gives the address more leading ones. Only one search runs at a time.
+type KeySearch struct {
+	mu       sync.Mutex
+	logger   *logger.Logger
+	cancel   context.CancelFunc
+	done     chan struct{}
+	opts     KeySearchOptions
+	tried    uint64 // atomic
+	best     ed25519.PrivateKey
+	started  time.Time
+	finished time.Time
+}
+
+// NewKeySearch creates an idle key search
+func NewKeySearch(log *logger.Logger) *KeySearch {
+	return &KeySearch{logger: log}
+}
+
+// parseAddressPrefix converts an IPv6 prefix into address nibbles
+func parseAddressPrefix(prefix string) ([]byte, error) {
+	if prefix == "" {
+		return nil, nil
+	}
+
+	groups := strings.Split(strings.ToLower(prefix), ":")
+	var digits string
+	for i, group := range groups {
+		if len(group) > 4 || (group == "" && i < len(groups)-1) {
+			return nil, fmt.Errorf("invalid address prefix %q", prefix)
+		}
+		if i == 0 || i < len(groups)-1 {
+			group = strings.Repeat("0", 4-len(group)) + group
+		}
+		digits += group
+	}
+	if len(digits) > 2*len(address.Address{}) {
+		return nil, fmt.Errorf("address prefix %q is too long", prefix)
+	}
+
+	nibbles := make([]byte, len(digits))
+	for i, c := range digits {
+		switch {
+		case c >= '0' && c <= '9':
+			nibbles[i] = byte(c - '0')
+		case c >= 'a' && c <= 'f':
+			nibbles[i] = byte(c-'a') + 10
+		default:
+			return nil, fmt.Errorf("invalid address prefix %q", prefix)
+		}
+	}
+
+	// Node addresses are in 200::/8
+	if nibbles[0] != 0 || (len(nibbles) > 1 && nibbles[1] != 2) {
+		return nil, fmt.Errorf("address prefix %q is outside 200::/8", prefix)
+	}
+	return nibbles, nil
+}
+
+// matchesPrefix reports whether the address of publicKey starts with nibbles
+func matchesPrefix(publicKey ed25519.PublicKey, nibbles []byte) bool {
+	if len(nibbles) == 0 {
+		return true
+	}
+	addr := address.AddrForKey(publicKey)
+	for i, n := range nibbles {
+		b := addr[i/2]
+		if i%2 == 0 {
+			b >>= 4
+		}
+		if b&0x0f != n {
+			return false
+		}
+	}
+	return true
+}
+
+// isBetterKey reports whether next gives a stronger address than current
+func isBetterKey(current, next ed25519.PublicKey) bool {
+	if current == nil {
+		return true
+	}
+	for i := range current {
+		if next[i] != current[i] {
+			return next[i] < current[i]
+		}
+	}
+	return false
+}
+
+// Start begins a search. onProgress, if set, is called periodically and
+// whenever a better key is found, and once more when the search ends.
+func (ks *KeySearch) Start(opts KeySearchOptions, onProgress func(KeySearchStatus)) error {
+	nibbles, err := parseAddressPrefix(opts.Prefix)
+	if err != nil {
+		return err
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.cancel != nil {
+		return fmt.Errorf("key search is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ks.cancel = cancel
+	ks.done = make(chan struct{})
+	ks.opts = opts
+	atomic.StoreUint64(&ks.tried, 0)
+	ks.best = nil
+	ks.started = time.Now()
+	ks.finished = time.Time{}
+
+	found := make(chan ed25519.PrivateKey, opts.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ks.searchKeys(ctx, nibbles, found)
+		}()
+	}
+
+	go ks.collect(ctx, &wg, found, onProgress)
+
+	ks.logger.Info("Key search started", "workers", opts.Workers, "prefix", opts.Prefix)
+	return nil
+}
+
+// searchKeys generates keys until ctx is done and sends each key that is
+// better than the previous one it found
+func (ks *KeySearch) searchKeys(ctx context.Context, nibbles []byte, found chan<- ed25519.PrivateKey) {
+	var best ed25519.PublicKey
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		for i := 0; i < keySearchBatch; i++ {
+			pub, priv, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				continue
+			}
+			if !isBetterKey(best, pub) || !matchesPrefix(pub, nibbles) {
+				continue
+			}
+			best = pub
+			select {
+			case found <- priv:
+			case <-ctx.Done():
+				return
+			}
+		}
+		atomic.AddUint64(&ks.tried, keySearchBatch)
+	}
+}
+
+// collect keeps the best key found by the workers and reports progress
+func (ks *KeySearch) collect(ctx context.Context, wg *sync.WaitGroup, found chan ed25519.PrivateKey, onProgress func(KeySearchStatus)) {
+	report := func() {
+		if onProgress != nil {
+			onProgress(ks.Status())
+		}
+	}
+
+	ticker := time.NewTicker(keySearchReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case priv := <-found:
+			ks.mu.Lock()
+			better := ks.best == nil || isBetterKey(ks.best.Public().(ed25519.PublicKey), priv.Public().(ed25519.PublicKey))
+			if better {
+				ks.best = priv
+			}
+			ks.mu.Unlock()
+			if better {
+				report()
+			}
+
+		case <-ticker.C:
+			report()
+
+		case <-ctx.Done():
+			wg.Wait()
+
+			ks.mu.Lock()
+			ks.cancel = nil
+			ks.finished = time.Now()
+			close(ks.done)
+			ks.mu.Unlock()
+
+			status := ks.Status()
+			ks.logger.Info("Key search stopped", "tried", status.Tried, "best", status.BestAddress)
+			if onProgress != nil {
+				onProgress(status)
+			}
+			return
+		}
+	}
+}
+
+// Stop cancels a running search and waits for it to end
+func (ks *KeySearch) Stop() {
+	ks.mu.Lock()
+	cancel, done := ks.cancel, ks.done
+	ks.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// IsRunning returns whether a search is running
+func (ks *KeySearch) IsRunning() bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.cancel != nil
+}
+
+// Status returns the progress of the current or last search
+func (ks *KeySearch) Status() KeySearchStatus {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	status := KeySearchStatus{
+		Running: ks.cancel != nil,
+		Prefix:  ks.opts.Prefix,
+		Workers: ks.opts.Workers,
+		Tried:   atomic.LoadUint64(&ks.tried),
+	}
+
+	if !ks.started.IsZero() {
+		end := ks.finished
+		if status.Running || end.IsZero() {
+			end = time.Now()
+		}
+		elapsed := end.Sub(ks.started)
+		status.Elapsed = elapsed.Milliseconds()
+		if elapsed > 0 {
+			status.Rate = float64(status.Tried) / elapsed.Seconds()
+		}
+	}
+
+	if ks.best != nil {
+		pub := ks.best.Public().(ed25519.PublicKey)
+		addr := address.AddrForKey(pub)
+		status.Found = true
+		status.BestAddress = addressForKey(pub)
+		status.BestPublicKey = hex.EncodeToString(pub)
+		status.LeadingOnes = int(addr[1])
+	}
+
+	return status
+}
+
+// Best returns a copy of the best key found, or nil
+func (ks *KeySearch) Best() ed25519.PrivateKey {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.best == nil {
+		return nil
+	}
+	return append(ed25519.PrivateKey{}, ks.best...)
+}
+
+// SetKeys replaces the node keys with privateKey. Call Save to persist the
+// change.
+func (cm *ConfigManager) SetKeys(privateKey ed25519.PrivateKey) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key")
+	}
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.config.PrivateKey = hex.EncodeToString(privateKey)
+	cm.config.PublicKey = hex.EncodeToString(publicKey)
+
+	cm.logger.Info("Node keys replaced", "address", addressForKey(publicKey))
+	return nil
+}
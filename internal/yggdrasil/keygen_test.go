@@ -0,0 +1,105 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestParseAddressPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"200", "0200", false},
+		{"201:ab", "0201ab", false},
+		{"200:0:1", "020000001", false},
+		{"2FF:", "02ff", false},
+		{"300", "", true},
+		{"::1", "", true},
+		{"200:xyz", "", true},
+		{"200:12345", "", true},
+	}
+
+	for _, tt := range tests {
+		nibbles, err := parseAddressPrefix(tt.prefix)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAddressPrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+			continue
+		}
+		var got strings.Builder
+		for _, n := range nibbles {
+			got.WriteByte("0123456789abcdef"[n])
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("parseAddressPrefix(%q) = %s, want %s", tt.prefix, got.String(), tt.want)
+		}
+	}
+}
+
+func TestKeySearch(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	ks := NewKeySearch(log)
+
+	if err := ks.Start(KeySearchOptions{Prefix: "300"}, nil); err == nil {
+		t.Error("Start() should reject a prefix outside 200::/8")
+	}
+
+	var mu sync.Mutex
+	var reports []KeySearchStatus
+	progress := func(s KeySearchStatus) {
+		mu.Lock()
+		reports = append(reports, s)
+		mu.Unlock()
+	}
+
+	if err := ks.Start(KeySearchOptions{Workers: 2, Prefix: "200"}, progress); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := ks.Start(KeySearchOptions{}, nil); err == nil {
+		t.Error("Start() should fail while a search is running")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ks.Best() == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ks.Stop()
+
+	if ks.IsRunning() {
+		t.Error("search still running after Stop()")
+	}
+	best := ks.Best()
+	if best == nil {
+		t.Fatal("no key found")
+	}
+	status := ks.Status()
+	if !status.Found || !strings.HasPrefix(status.BestAddress, "200:") || status.LeadingOnes != 0 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	mu.Lock()
+	last := reports[len(reports)-1]
+	mu.Unlock()
+	if last.Running || last.BestAddress != status.BestAddress {
+		t.Errorf("final report = %+v", last)
+	}
+
+	cm := NewConfigManagerWithPath(filepath.Join(t.TempDir(), "yggdrasil.conf"), log)
+	if err := cm.SetKeys(best); err != nil {
+		t.Fatalf("SetKeys() error = %v", err)
+	}
+	if cm.GetConfigInfo().IPv6Address != status.BestAddress {
+		t.Errorf("adopted address = %s, want %s", cm.GetConfigInfo().IPv6Address, status.BestAddress)
+	}
+	if err := cm.SetKeys(ed25519.PrivateKey{1}); err == nil {
+		t.Error("SetKeys() should reject an invalid key")
+	}
+}
@@ -0,0 +1,59 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+// KeyStore keeps node private keys out of the config file.
+// security.SecureStore implements it.
+type KeyStore interface {
+	Store(key string, value []byte) error
+	Retrieve(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// privateKeyAccount returns the secure storage account of a node private
+// key. Keys are stored per identity, so profiles that share keys also share
+// the entry and renaming a profile keeps it.
+func privateKeyAccount(publicKey string) string {
+	return security.AccountName + ":" + publicKey
+}
+
+// storePrivateKey saves a hex encoded private key in the key store. The key
+// must belong to publicKey, otherwise it would be stored under an identity
+// it cannot serve.
+func storePrivateKey(ks KeyStore, publicKey, privateKey string) error {
+	key, err := hex.DecodeString(privateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key")
+	}
+	if hex.EncodeToString(ed25519.PrivateKey(key).Public().(ed25519.PublicKey)) != publicKey {
+		security.ZeroBytes(key)
+		return fmt.Errorf("private key does not match the public key")
+	}
+	// The store zeroes the value after use
+	return ks.Store(privateKeyAccount(publicKey), key)
+}
+
+// retrievePrivateKey reads the private key of publicKey from the key store
+// and returns it hex encoded
+func retrievePrivateKey(ks KeyStore, publicKey string) (string, error) {
+	key, err := ks.Retrieve(privateKeyAccount(publicKey))
+	if err != nil {
+		return "", err
+	}
+	defer security.ZeroBytes(key)
+
+	if len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("invalid private key in secure storage")
+	}
+	if hex.EncodeToString(ed25519.PrivateKey(key).Public().(ed25519.PublicKey)) != publicKey {
+		return "", fmt.Errorf("private key in secure storage does not match the public key")
+	}
+
+	return hex.EncodeToString(key), nil
+}
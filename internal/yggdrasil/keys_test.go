@@ -0,0 +1,210 @@
+package yggdrasil
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+// memKeyStore is an in-memory KeyStore
+type memKeyStore map[string][]byte
+
+func (m memKeyStore) Store(key string, value []byte) error {
+	m[key] = append([]byte{}, value...)
+	security.ZeroBytes(value)
+	return nil
+}
+
+func (m memKeyStore) Retrieve(key string) ([]byte, error) {
+	value, ok := m[key]
+	if !ok {
+		return nil, security.ErrKeyNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (m memKeyStore) Delete(key string) error {
+	delete(m, key)
+	return nil
+}
+
+// brokenKeyStore is a KeyStore that cannot be read
+type brokenKeyStore struct{ memKeyStore }
+
+func (brokenKeyStore) Retrieve(key string) ([]byte, error) {
+	return nil, errors.New("keyring locked")
+}
+
+func readConfigFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	return string(data)
+}
+
+func TestConfigManager_MigratePlaintextKey(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	path := filepath.Join(t.TempDir(), "yggdrasil.conf")
+
+	// An older config with the key in the file
+	plain := NewConfigManagerWithPath(path, log)
+	if err := plain.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := plain.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	privateKey := plain.GetPrivateKey()
+	if !strings.Contains(readConfigFile(t, path), privateKey) {
+		t.Fatal("config without key store should contain the private key")
+	}
+
+	keys := memKeyStore{}
+	cm := NewConfigManagerWithPath(path, log)
+	if err := cm.SetKeyStore(keys); err != nil {
+		t.Fatalf("SetKeyStore() error = %v", err)
+	}
+
+	if cm.GetPrivateKey() != privateKey {
+		t.Error("private key changed during migration")
+	}
+	if strings.Contains(readConfigFile(t, path), privateKey) {
+		t.Error("private key not scrubbed from the config file")
+	}
+	if _, ok := keys[privateKeyAccount(cm.GetPublicKey())]; !ok {
+		t.Error("private key not moved to the key store")
+	}
+
+	// The key is read back from the store
+	loaded := newConfigManagerWithKeys(path, keys, log)
+	if loaded.GetPrivateKey() != privateKey {
+		t.Error("private key not loaded from the key store")
+	}
+	if key, err := loaded.storedPrivateKey(); err != nil || key != privateKey {
+		t.Error("storedPrivateKey() should return the key from the store")
+	}
+
+	// Without the store only the public key is left
+	if NewConfigManagerWithPath(path, log).GetPrivateKey() != "" {
+		t.Error("config file still yields a private key")
+	}
+}
+
+func TestConfigManager_KeyStoreMismatch(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	path := filepath.Join(t.TempDir(), "yggdrasil.conf")
+	keys := memKeyStore{}
+
+	cm := newConfigManagerWithKeys(path, keys, log)
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A key that belongs to another identity is rejected
+	other := testPrivateKey(t)
+	keys[privateKeyAccount(cm.GetPublicKey())] = other
+
+	if got := newConfigManagerWithKeys(path, keys, log).GetPrivateKey(); got != "" {
+		t.Errorf("mismatched key should not be loaded, got %q", got)
+	}
+}
+
+func TestConfigManager_KeyStoreUnavailable(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	path := filepath.Join(t.TempDir(), "yggdrasil.conf")
+	keys := memKeyStore{}
+
+	cm := newConfigManagerWithKeys(path, keys, log)
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	publicKey := cm.GetPublicKey()
+
+	// The identity is kept, but the node does not start with a new key
+	locked := newConfigManagerWithKeys(path, brokenKeyStore{keys}, log)
+	if err := locked.Load(); err == nil {
+		t.Error("Load() should fail when the private key cannot be read")
+	}
+	if locked.GetPublicKey() != publicKey {
+		t.Errorf("public key changed to %q", locked.GetPublicKey())
+	}
+
+	svc := NewServiceWithConfig(locked, log)
+	if err := svc.Start(nil); err == nil {
+		svc.Stop()
+		t.Fatal("Start() should fail without the private key")
+	}
+	if locked.GetPublicKey() != publicKey || !strings.Contains(readConfigFile(t, path), publicKey) {
+		t.Error("failed start replaced the node identity")
+	}
+}
+
+func TestStorePrivateKey_Mismatch(t *testing.T) {
+	keys := memKeyStore{}
+	cm := NewConfigManagerWithPath(filepath.Join(t.TempDir(), "yggdrasil.conf"), logger.NewWithConfig(logger.Config{Level: "error", Console: false}))
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	other := hex.EncodeToString(testPrivateKey(t))
+	if err := storePrivateKey(keys, cm.GetPublicKey(), other); err == nil {
+		t.Error("a key of another identity should be rejected")
+	}
+	if len(keys) != 0 {
+		t.Error("mismatched key was written to the store")
+	}
+	if err := storePrivateKey(keys, cm.GetPublicKey(), cm.GetPrivateKey()); err != nil {
+		t.Errorf("storePrivateKey() error = %v", err)
+	}
+}
+
+func TestProfileManager_DeleteRemovesUnusedKey(t *testing.T) {
+	pm, cm := newTestProfileManager(t)
+	keys := memKeyStore{}
+	if err := cm.SetKeyStore(keys); err != nil {
+		t.Fatalf("SetKeyStore() error = %v", err)
+	}
+
+	if err := pm.Create("lab"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := pm.Clone("lab", "lab copy", false); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	labKey := pm.publicKey("lab")
+	if _, ok := keys[privateKeyAccount(labKey)]; !ok {
+		t.Fatal("new profile key not in the key store")
+	}
+	if strings.Contains(readConfigFile(t, pm.path("lab copy")), "PrivateKey") {
+		t.Error("cloned profile config contains the private key")
+	}
+
+	// The clone still uses the identity
+	if err := pm.Delete("lab"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := keys[privateKeyAccount(labKey)]; !ok {
+		t.Error("shared key deleted while still in use")
+	}
+
+	if err := pm.Delete("lab copy"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := keys[privateKeyAccount(labKey)]; ok {
+		t.Error("unused key left in the key store")
+	}
+}
@@ -0,0 +1,93 @@
+package yggdrasil
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// rejectLogInterval limits how often rejected connections of one mapping
+// are logged, so a client retrying in a loop does not flood the log
+const rejectLogInterval = 10 * time.Second
+
+// newRateLimiter creates a token bucket for a limit in bytes per second.
+// It returns nil for 0, which means unlimited.
+func newRateLimiter(bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	// One second worth of traffic may pass at once
+	burst := bytesPerSec
+	if burst > math.MaxInt32 {
+		burst = math.MaxInt32
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), int(burst))
+}
+
+// waitBytes blocks until n bytes may pass the limiter. Writes larger than
+// the bucket are taken in bucket-sized steps.
+func waitBytes(ctx context.Context, limiter *rate.Limiter, n int) error {
+	if limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		step := n
+		if burst := limiter.Burst(); step > burst {
+			step = burst
+		}
+		if err := limiter.WaitN(ctx, step); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// mappingLimits holds the limiters of one mapping. The rate limits are
+// shared by all connections of the mapping.
+type mappingLimits struct {
+	upload   *rate.Limiter // Client to target
+	download *rate.Limiter // Target to client
+
+	mu         sync.Mutex
+	lastLog    time.Time
+	suppressed int
+}
+
+// newMappingLimits creates the limiters for a mapping
+func newMappingLimits(m PortMapping) *mappingLimits {
+	return &mappingLimits{
+		upload:   newRateLimiter(m.UploadLimit),
+		download: newRateLimiter(m.DownloadLimit),
+	}
+}
+
+// logRejected logs a rejected connection or session. Rejections within
+// rejectLogInterval of the last logged one are counted and reported with
+// the next log line.
+func (l *mappingLimits) logRejected(log *logger.Logger, id, remote, reason string, limit int) {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastLog) < rejectLogInterval {
+		l.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	suppressed := l.suppressed
+	l.lastLog = now
+	l.suppressed = 0
+	l.mu.Unlock()
+
+	log.Warn("Rejected mapping connection",
+		"id", id,
+		"remote", remote,
+		"reason", reason,
+		"limit", limit,
+		"suppressed", suppressed,
+	)
+}
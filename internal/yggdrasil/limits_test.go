@@ -0,0 +1,89 @@
+package yggdrasil
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestWaitBytes_Throttles(t *testing.T) {
+	if newRateLimiter(0) != nil {
+		t.Error("zero limit should be unlimited")
+	}
+	if err := waitBytes(context.Background(), nil, 1<<20); err != nil {
+		t.Errorf("unlimited waitBytes() error = %v", err)
+	}
+
+	limiter := newRateLimiter(1000)
+	start := time.Now()
+	// The first 1000 bytes use the burst, the next 500 take half a second
+	if err := waitBytes(context.Background(), limiter, 1500); err != nil {
+		t.Fatalf("waitBytes() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("1500 bytes at 1000 B/s took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitBytes(ctx, limiter, 1000); err == nil {
+		t.Error("waitBytes() should fail on a cancelled context")
+	}
+}
+
+func TestPortForwarder_Admit(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	mapping := PortMapping{ID: "web", Type: MappingRemoteTCP, MaxConnections: 2}
+	fwd := &portForwarder{mapping: mapping, limits: newMappingLimits(mapping), logger: log}
+	remote := &net.TCPAddr{IP: net.ParseIP("200::1"), Port: 1234}
+
+	for i := 0; i < 2; i++ {
+		if !fwd.admit(remote, mapping.MaxConnections, "connection limit reached") {
+			t.Fatalf("connection %d should be admitted", i+1)
+		}
+	}
+	if fwd.admit(remote, mapping.MaxConnections, "connection limit reached") {
+		t.Error("third connection should be rejected")
+	}
+	if fwd.activeConnections != 2 || fwd.totalConnections != 2 || fwd.rejectedConnections != 1 {
+		t.Errorf("active = %d, total = %d, rejected = %d",
+			fwd.activeConnections, fwd.totalConnections, fwd.rejectedConnections)
+	}
+
+	// Rejections right after a logged one are only counted
+	if fwd.admit(remote, mapping.MaxConnections, "connection limit reached") || fwd.limits.suppressed != 1 {
+		t.Errorf("suppressed = %d, want 1", fwd.limits.suppressed)
+	}
+
+	// No limit
+	if !fwd.admit(remote, 0, "") {
+		t.Error("unlimited mapping should admit connections")
+	}
+}
+
+func TestMappingManager_RejectsNegativeLimits(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	mm := NewMappingManager(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	tests := []PortMapping{
+		{Type: MappingLocalTCP, Source: "127.0.0.1:8080", Target: "[200::1]:80", UploadLimit: -1},
+		{Type: MappingLocalUDP, Source: "127.0.0.1:5353", Target: "[200::1]:53", MaxSessions: -1},
+	}
+	for _, m := range tests {
+		if err := mm.AddMapping(m); err == nil {
+			t.Errorf("AddMapping(%+v) should fail", m)
+		}
+	}
+
+	if err := mm.AddMapping(PortMapping{ID: "limited", Type: MappingRemoteTCP, Source: "[::]:8080",
+		Target: "127.0.0.1:80", DownloadLimit: 1 << 20, MaxConnections: 10}); err != nil {
+		t.Fatalf("AddMapping() error = %v", err)
+	}
+	m, _ := mm.GetMapping("limited")
+	if m.DownloadLimit != 1<<20 || m.MaxConnections != 10 {
+		t.Errorf("mapping limits = %+v", m)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
 	"github.com/JB-SelfCompany/yggstack-gui/internal/yggdrasil/netstack"
@@ -32,29 +33,52 @@ type PortMapping struct {
 	Active   bool        `json:"active"` // Currently forwarding
 	BytesIn  uint64      `json:"bytesIn"`
 	BytesOut uint64      `json:"bytesOut"`
+
+	// Limits, 0 means unlimited. Rates are in bytes per second and shared
+	// by all connections of the mapping.
+	UploadLimit    int64 `json:"uploadLimit,omitempty"`    // Client to target
+	DownloadLimit  int64 `json:"downloadLimit,omitempty"`  // Target to client
+	MaxConnections int   `json:"maxConnections,omitempty"` // Concurrent TCP connections
+	MaxSessions    int   `json:"maxSessions,omitempty"`    // Concurrent UDP sessions
+
+	// Seconds a UDP session may be idle before it is closed, 0 means
+	// DefaultUDPIdleTimeout
+	UDPIdleTimeout int `json:"udpIdleTimeout,omitempty"`
+
+	// Access lists of remote mappings: public keys, addresses or subnets
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
 }
 
 // MappingStats contains statistics for a port mapping
 type MappingStats struct {
-	ID                string `json:"id"`
-	ActiveConnections int64  `json:"activeConnections"`
-	TotalConnections  uint64 `json:"totalConnections"`
-	BytesIn           uint64 `json:"bytesIn"`
-	BytesOut          uint64 `json:"bytesOut"`
+	ID                  string `json:"id"`
+	ActiveConnections   int64  `json:"activeConnections"`
+	TotalConnections    uint64 `json:"totalConnections"`
+	RejectedConnections uint64 `json:"rejectedConnections"`
+	ActiveSessions      int    `json:"activeSessions"`  // UDP mappings
+	EvictedSessions     uint64 `json:"evictedSessions"` // UDP sessions closed for being idle
+	BytesIn             uint64 `json:"bytesIn"`
+	BytesOut            uint64 `json:"bytesOut"`
 }
 
-// portForwarder handles a single port forwarding instance
+// portForwarder handles a single port forwarding instance.
+// For UDP mappings, connections count client sessions.
 type portForwarder struct {
-	mapping           PortMapping
-	listener          net.Listener
-	udpConn           net.PacketConn
-	ctx               context.Context
-	cancel            context.CancelFunc
-	activeConnections int64
-	totalConnections  uint64
-	bytesIn           uint64
-	bytesOut          uint64
-	logger            *logger.Logger
+	mapping             PortMapping
+	listener            net.Listener
+	udpConn             net.PacketConn
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	limits              *mappingLimits
+	acl                 *mappingACL
+	sessions            *udpSessionTable // Replaced under the manager lock on restart
+	activeConnections   int64
+	totalConnections    uint64
+	rejectedConnections uint64
+	bytesIn             uint64
+	bytesOut            uint64
+	logger              *logger.Logger
 }
 
 // MappingManager manages port forwarding rules
@@ -62,6 +86,8 @@ type MappingManager struct {
 	mu         sync.RWMutex
 	service    *Service
 	forwarders map[string]*portForwarder
+	order      []string // Mapping IDs in the order they were added
+	onDeny     func(MappingDenial)
 	logger     *logger.Logger
 	idCounter  uint64
 }
@@ -85,6 +111,11 @@ func (mm *MappingManager) AddMapping(mapping PortMapping) error {
 		return err
 	}
 
+	acl, err := newMappingACL(mapping.Allow, mapping.Deny)
+	if err != nil {
+		return err
+	}
+
 	// Generate ID if not provided
 	if mapping.ID == "" {
 		mm.idCounter++
@@ -106,10 +137,13 @@ func (mm *MappingManager) AddMapping(mapping PortMapping) error {
 	// Create forwarder
 	fwd := &portForwarder{
 		mapping: mapping,
+		limits:  newMappingLimits(mapping),
+		acl:     acl,
 		logger:  mm.logger,
 	}
 
 	mm.forwarders[mapping.ID] = fwd
+	mm.order = append(mm.order, mapping.ID)
 
 	// Start if enabled and service is running
 	if mapping.Enabled && mm.service.IsRunning() {
@@ -143,6 +177,12 @@ func (mm *MappingManager) RemoveMapping(id string) error {
 	}
 
 	delete(mm.forwarders, id)
+	for i, mappingID := range mm.order {
+		if mappingID == id {
+			mm.order = append(mm.order[:i], mm.order[i+1:]...)
+			break
+		}
+	}
 
 	mm.logger.Info("Removed port mapping", "id", id)
 	return nil
@@ -185,13 +225,14 @@ func (mm *MappingManager) DisableMapping(id string) error {
 	return mm.stopForwarder(fwd)
 }
 
-// GetMappings returns all port mappings
+// GetMappings returns all port mappings in the order they were added
 func (mm *MappingManager) GetMappings() []PortMapping {
 	mm.mu.RLock()
 	defer mm.mu.RUnlock()
 
-	mappings := make([]PortMapping, 0, len(mm.forwarders))
-	for _, fwd := range mm.forwarders {
+	mappings := make([]PortMapping, 0, len(mm.order))
+	for _, id := range mm.order {
+		fwd := mm.forwarders[id]
 		mapping := fwd.mapping
 		mapping.BytesIn = atomic.LoadUint64(&fwd.bytesIn)
 		mapping.BytesOut = atomic.LoadUint64(&fwd.bytesOut)
@@ -228,13 +269,20 @@ func (mm *MappingManager) GetMappingStats(id string) (*MappingStats, error) {
 		return nil, fmt.Errorf("mapping not found: %s", id)
 	}
 
-	return &MappingStats{
-		ID:                id,
-		ActiveConnections: atomic.LoadInt64(&fwd.activeConnections),
-		TotalConnections:  atomic.LoadUint64(&fwd.totalConnections),
-		BytesIn:           atomic.LoadUint64(&fwd.bytesIn),
-		BytesOut:          atomic.LoadUint64(&fwd.bytesOut),
-	}, nil
+	stats := &MappingStats{
+		ID:                  id,
+		ActiveConnections:   atomic.LoadInt64(&fwd.activeConnections),
+		TotalConnections:    atomic.LoadUint64(&fwd.totalConnections),
+		RejectedConnections: atomic.LoadUint64(&fwd.rejectedConnections),
+		BytesIn:             atomic.LoadUint64(&fwd.bytesIn),
+		BytesOut:            atomic.LoadUint64(&fwd.bytesOut),
+	}
+	if fwd.sessions != nil {
+		stats.ActiveSessions = fwd.sessions.len()
+		stats.EvictedSessions = fwd.sessions.evicted()
+	}
+
+	return stats, nil
 }
 
 // validateMapping validates a port mapping configuration
@@ -254,9 +302,85 @@ func (mm *MappingManager) validateMapping(mapping *PortMapping) error {
 		return fmt.Errorf("invalid mapping type: %s", mapping.Type)
 	}
 
+	if mapping.UploadLimit < 0 || mapping.DownloadLimit < 0 {
+		return fmt.Errorf("rate limits must not be negative")
+	}
+
+	if mapping.MaxConnections < 0 || mapping.MaxSessions < 0 || mapping.UDPIdleTimeout < 0 {
+		return fmt.Errorf("connection limits must not be negative")
+	}
+
+	remote := mapping.Type == MappingRemoteTCP || mapping.Type == MappingRemoteUDP
+	if !remote && (len(mapping.Allow) > 0 || len(mapping.Deny) > 0) {
+		return fmt.Errorf("access lists are only supported for remote mappings")
+	}
+
 	return nil
 }
 
+// SetDenyHandler sets the function called when a remote mapping refuses a
+// connection because of its access list
+func (mm *MappingManager) SetDenyHandler(handler func(MappingDenial)) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.onDeny = handler
+}
+
+// allowRemote checks a remote against the access list of a mapping and
+// reports denials
+func (mm *MappingManager) allowRemote(fwd *portForwarder, remote net.Addr) bool {
+	allowed, reason := fwd.acl.check(remote)
+	if allowed {
+		return true
+	}
+
+	if !fwd.acl.shouldReport(remote.String(), time.Now()) {
+		return false
+	}
+
+	fwd.logger.Warn("Denied mapping connection",
+		"id", fwd.mapping.ID,
+		"remote", remote.String(),
+		"reason", reason,
+	)
+
+	mm.mu.RLock()
+	onDeny := mm.onDeny
+	mm.mu.RUnlock()
+	if onDeny != nil {
+		onDeny(MappingDenial{MappingID: fwd.mapping.ID, Remote: remote.String(), Reason: reason})
+	}
+	return false
+}
+
+// startSessions creates the UDP session table of a starting mapping and
+// expires idle sessions until the mapping stops. Stats of the previous
+// run are carried over. The forwarding goroutines get the returned table,
+// so they never read the field while a restart replaces it.
+func (fwd *portForwarder) startSessions() *udpSessionTable {
+	table := newUDPSessionTable(time.Duration(fwd.mapping.UDPIdleTimeout) * time.Second)
+	if fwd.sessions != nil {
+		table.evictions = fwd.sessions.evicted()
+	}
+	fwd.sessions = table
+	go table.run(fwd.ctx)
+	return table
+}
+
+// admit reserves a connection slot for a new TCP connection or UDP
+// session. It returns false, and logs why, when the mapping is at its limit.
+func (fwd *portForwarder) admit(remote net.Addr, limit int, reason string) bool {
+	active := atomic.AddInt64(&fwd.activeConnections, 1)
+	if limit > 0 && active > int64(limit) {
+		atomic.AddInt64(&fwd.activeConnections, -1)
+		atomic.AddUint64(&fwd.rejectedConnections, 1)
+		fwd.limits.logRejected(fwd.logger, fwd.mapping.ID, remote.String(), reason, limit)
+		return false
+	}
+	atomic.AddUint64(&fwd.totalConnections, 1)
+	return true
+}
+
 // startForwarder starts a port forwarder
 func (mm *MappingManager) startForwarder(fwd *portForwarder) error {
 	if fwd.mapping.Active {
@@ -371,8 +495,9 @@ func (mm *MappingManager) startLocalUDP(fwd *portForwarder, ns *netstack.Yggdras
 	fwd.udpConn = conn
 	fwd.ctx, fwd.cancel = context.WithCancel(context.Background())
 	fwd.mapping.Active = true
+	sessions := fwd.startSessions()
 
-	go mm.handleLocalUDP(fwd, ns)
+	go mm.handleLocalUDP(fwd, sessions, ns)
 
 	mm.logger.Info("Started local UDP forwarding",
 		"source", fwd.mapping.Source,
@@ -397,8 +522,9 @@ func (mm *MappingManager) startRemoteUDP(fwd *portForwarder, ns *netstack.Yggdra
 	fwd.udpConn = conn
 	fwd.ctx, fwd.cancel = context.WithCancel(context.Background())
 	fwd.mapping.Active = true
+	sessions := fwd.startSessions()
 
-	go mm.handleRemoteUDP(fwd)
+	go mm.handleRemoteUDP(fwd, sessions)
 
 	mm.logger.Info("Started remote UDP forwarding",
 		"source", fwd.mapping.Source,
@@ -424,8 +550,10 @@ func (mm *MappingManager) acceptLocalTCP(fwd *portForwarder, ns *netstack.Yggdra
 			}
 		}
 
-		atomic.AddInt64(&fwd.activeConnections, 1)
-		atomic.AddUint64(&fwd.totalConnections, 1)
+		if !fwd.admit(conn.RemoteAddr(), fwd.mapping.MaxConnections, "connection limit reached") {
+			conn.Close()
+			continue
+		}
 
 		go mm.handleLocalTCPConnection(fwd, conn, ns, mtu)
 	}
@@ -447,8 +575,15 @@ func (mm *MappingManager) acceptRemoteTCP(fwd *portForwarder) {
 			}
 		}
 
-		atomic.AddInt64(&fwd.activeConnections, 1)
-		atomic.AddUint64(&fwd.totalConnections, 1)
+		if !mm.allowRemote(fwd, conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+
+		if !fwd.admit(conn.RemoteAddr(), fwd.mapping.MaxConnections, "connection limit reached") {
+			conn.Close()
+			continue
+		}
 
 		go mm.handleRemoteTCPConnection(fwd, conn, mtu)
 	}
@@ -473,7 +608,7 @@ func (mm *MappingManager) handleLocalTCPConnection(fwd *portForwarder, conn net.
 	defer target.Close()
 
 	// Proxy data
-	proxyTCP(mtu, conn, target, &fwd.bytesIn, &fwd.bytesOut)
+	proxyTCP(fwd.ctx, mtu, conn, target, &fwd.bytesIn, &fwd.bytesOut, fwd.limits)
 }
 
 // handleRemoteTCPConnection handles a single remote TCP connection
@@ -495,17 +630,14 @@ func (mm *MappingManager) handleRemoteTCPConnection(fwd *portForwarder, conn net
 	defer target.Close()
 
 	// Proxy data
-	proxyTCP(mtu, conn, target, &fwd.bytesIn, &fwd.bytesOut)
+	proxyTCP(fwd.ctx, mtu, conn, target, &fwd.bytesIn, &fwd.bytesOut, fwd.limits)
 }
 
 // handleLocalUDP handles local UDP forwarding
-func (mm *MappingManager) handleLocalUDP(fwd *portForwarder, ns *netstack.YggdrasilNetstack) {
+func (mm *MappingManager) handleLocalUDP(fwd *portForwarder, sessions *udpSessionTable, ns *netstack.YggdrasilNetstack) {
 	mtu := mm.service.GetMTU()
 	buf := make([]byte, mtu)
 
-	// Map of client addresses to Yggdrasil connections
-	sessions := sync.Map{}
-
 	for {
 		select {
 		case <-fwd.ctx.Done():
@@ -519,63 +651,44 @@ func (mm *MappingManager) handleLocalUDP(fwd *portForwarder, ns *netstack.Yggdra
 			continue
 		}
 
-		atomic.AddUint64(&fwd.bytesIn, uint64(n))
-
 		// Get or create session for this client
 		sessionKey := addr.String()
-		var yggConn net.Conn
+		session := sessions.get(sessionKey)
+
+		if session == nil {
+			if !fwd.admit(addr, fwd.mapping.MaxSessions, "session limit reached") {
+				continue
+			}
 
-		if existing, ok := sessions.Load(sessionKey); ok {
-			yggConn = existing.(net.Conn)
-		} else {
 			// Create new connection to Yggdrasil target
 			conn, err := ns.DialContext(fwd.ctx, "udp", fwd.mapping.Target)
 			if err != nil {
+				atomic.AddInt64(&fwd.activeConnections, -1)
 				fwd.logger.Debug("Failed to dial Yggdrasil target", "error", err)
 				continue
 			}
-			yggConn = conn
-			sessions.Store(sessionKey, conn)
+			session = sessions.add(sessionKey, conn, addr)
 
 			// Start reverse proxy for this session
-			go func(clientAddr net.Addr, conn net.Conn) {
-				defer func() {
-					conn.Close()
-					sessions.Delete(sessionKey)
-				}()
-
-				respBuf := make([]byte, mtu)
-				for {
-					select {
-					case <-fwd.ctx.Done():
-						return
-					default:
-					}
-
-					n, err := conn.Read(respBuf)
-					if err != nil {
-						return
-					}
-
-					atomic.AddUint64(&fwd.bytesOut, uint64(n))
-					fwd.udpConn.WriteTo(respBuf[:n], clientAddr)
-				}
-			}(addr, yggConn)
+			go mm.serveUDPSession(fwd, sessions, sessionKey, session, mtu)
 		}
 
 		// Forward packet to Yggdrasil
-		yggConn.Write(buf[:n])
+		if waitBytes(fwd.ctx, fwd.limits.upload, n) != nil {
+			session.release()
+			return
+		}
+		atomic.AddUint64(&fwd.bytesIn, uint64(n))
+		session.conn.Write(buf[:n])
+		session.release()
 	}
 }
 
 // handleRemoteUDP handles remote UDP forwarding (from Yggdrasil to local)
-func (mm *MappingManager) handleRemoteUDP(fwd *portForwarder) {
+func (mm *MappingManager) handleRemoteUDP(fwd *portForwarder, sessions *udpSessionTable) {
 	mtu := mm.service.GetMTU()
 	buf := make([]byte, mtu)
 
-	// Map of client addresses to local connections
-	sessions := sync.Map{}
-
 	for {
 		select {
 		case <-fwd.ctx.Done():
@@ -583,21 +696,21 @@ func (mm *MappingManager) handleRemoteUDP(fwd *portForwarder) {
 		default:
 		}
 
-		n, addr, err := fwd.udpConn.(net.PacketConn).ReadFrom(buf)
+		n, addr, err := fwd.udpConn.ReadFrom(buf)
 		if err != nil {
 			fwd.logger.Debug("UDP read error", "error", err)
 			continue
 		}
 
-		atomic.AddUint64(&fwd.bytesIn, uint64(n))
-
 		// Get or create session for this client
 		sessionKey := addr.String()
-		var localConn *net.UDPConn
+		session := sessions.get(sessionKey)
+
+		if session == nil {
+			if !mm.allowRemote(fwd, addr) {
+				continue
+			}
 
-		if existing, ok := sessions.Load(sessionKey); ok {
-			localConn = existing.(*net.UDPConn)
-		} else {
 			// Resolve target address
 			targetAddr, err := net.ResolveUDPAddr("udp", fwd.mapping.Target)
 			if err != nil {
@@ -605,48 +718,63 @@ func (mm *MappingManager) handleRemoteUDP(fwd *portForwarder) {
 				continue
 			}
 
+			if !fwd.admit(addr, fwd.mapping.MaxSessions, "session limit reached") {
+				continue
+			}
+
 			// Create new connection to local target
 			conn, err := net.DialUDP("udp", nil, targetAddr)
 			if err != nil {
+				atomic.AddInt64(&fwd.activeConnections, -1)
 				fwd.logger.Debug("Failed to dial local target", "error", err)
 				continue
 			}
-			localConn = conn
-			sessions.Store(sessionKey, conn)
+			session = sessions.add(sessionKey, conn, addr)
 
 			// Start reverse proxy for this session
-			go func(clientAddr net.Addr, conn *net.UDPConn) {
-				defer func() {
-					conn.Close()
-					sessions.Delete(sessionKey)
-				}()
-
-				respBuf := make([]byte, mtu)
-				for {
-					select {
-					case <-fwd.ctx.Done():
-						return
-					default:
-					}
-
-					n, err := conn.Read(respBuf)
-					if err != nil {
-						return
-					}
-
-					atomic.AddUint64(&fwd.bytesOut, uint64(n))
-					fwd.udpConn.(net.PacketConn).WriteTo(respBuf[:n], clientAddr)
-				}
-			}(addr, localConn)
+			go mm.serveUDPSession(fwd, sessions, sessionKey, session, mtu)
 		}
 
 		// Forward packet to local target
-		localConn.Write(buf[:n])
+		if waitBytes(fwd.ctx, fwd.limits.upload, n) != nil {
+			session.release()
+			return
+		}
+		atomic.AddUint64(&fwd.bytesIn, uint64(n))
+		session.conn.Write(buf[:n])
+		session.release()
 	}
 }
 
-// proxyTCP proxies data between two TCP connections
-func proxyTCP(mtu uint64, c1, c2 net.Conn, bytesIn, bytesOut *uint64) {
+// serveUDPSession relays the replies of a session's target back to its
+// client. It returns when the session is closed, for being idle or because
+// the mapping stopped.
+func (mm *MappingManager) serveUDPSession(fwd *portForwarder, sessions *udpSessionTable, key string, s *udpSession, mtu uint64) {
+	defer func() {
+		s.conn.Close()
+		sessions.remove(key, s)
+		atomic.AddInt64(&fwd.activeConnections, -1)
+	}()
+
+	respBuf := make([]byte, mtu)
+	for {
+		n, err := s.conn.Read(respBuf)
+		if err != nil {
+			return
+		}
+		s.touch(time.Now())
+
+		if waitBytes(fwd.ctx, fwd.limits.download, n) != nil {
+			return
+		}
+		atomic.AddUint64(&fwd.bytesOut, uint64(n))
+		fwd.udpConn.WriteTo(respBuf[:n], s.client)
+	}
+}
+
+// proxyTCP proxies data between two TCP connections. c1 is the client and
+// c2 the target; traffic is throttled by the mapping's rate limits.
+func proxyTCP(ctx context.Context, mtu uint64, c1, c2 net.Conn, bytesIn, bytesOut *uint64, limits *mappingLimits) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -657,6 +785,9 @@ func proxyTCP(mtu uint64, c1, c2 net.Conn, bytesIn, bytesOut *uint64) {
 		for {
 			n, err := c1.Read(buf)
 			if n > 0 {
+				if waitBytes(ctx, limits.upload, n) != nil {
+					break
+				}
 				atomic.AddUint64(bytesOut, uint64(n))
 				c2.Write(buf[:n])
 			}
@@ -676,6 +807,9 @@ func proxyTCP(mtu uint64, c1, c2 net.Conn, bytesIn, bytesOut *uint64) {
 		for {
 			n, err := c2.Read(buf)
 			if n > 0 {
+				if waitBytes(ctx, limits.download, n) != nil {
+					break
+				}
 				atomic.AddUint64(bytesIn, uint64(n))
 				c1.Write(buf[:n])
 			}
@@ -0,0 +1,83 @@
+package yggdrasil
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+)
+
+// LegacySettings returns the SOCKS and mapping sections of the loaded
+// config file converted to application settings, or nil if there are none
+func (cm *ConfigManager) LegacySettings() *config.LegacySettings {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.config.SOCKS == nil && cm.config.Mappings == nil {
+		return nil
+	}
+
+	legacy := &config.LegacySettings{}
+
+	if socks := cm.config.SOCKS; socks != nil {
+		legacy.Proxy = &config.ProxySettings{
+			Enabled:       socks.Enabled,
+			ListenAddress: socks.ListenAddress,
+			Nameserver:    socks.Nameserver,
+		}
+	}
+
+	if m := cm.config.Mappings; m != nil {
+		legacy.Mappings = config.MappingsSettings{
+			LocalTCP:  convertLegacyMappings(MappingLocalTCP, m.LocalTCP),
+			RemoteTCP: convertLegacyMappings(MappingRemoteTCP, m.RemoteTCP),
+			LocalUDP:  convertLegacyMappings(MappingLocalUDP, m.LocalUDP),
+			RemoteUDP: convertLegacyMappings(MappingRemoteUDP, m.RemoteUDP),
+		}
+	}
+
+	return legacy
+}
+
+// ClearLegacySettings removes the SOCKS and mapping sections from the
+// config. Call Save afterwards to write the change to disk.
+func (cm *ConfigManager) ClearLegacySettings() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.config.SOCKS = nil
+	cm.config.Mappings = nil
+}
+
+// convertLegacyMappings converts legacy mappings to application settings.
+// Local mappings listen on the local side, remote mappings on Yggdrasil.
+func convertLegacyMappings(mappingType MappingType, mappings []MappingConfig) []config.PortMapping {
+	result := make([]config.PortMapping, 0, len(mappings))
+
+	for i, m := range mappings {
+		local := joinLegacyAddr(m.LocalAddr, m.LocalPort)
+		remote := joinLegacyAddr(m.RemoteAddr, m.RemotePort)
+
+		pm := config.PortMapping{
+			ID:      m.Name,
+			Source:  local,
+			Target:  remote,
+			Enabled: m.Enabled,
+		}
+		if mappingType == MappingRemoteTCP || mappingType == MappingRemoteUDP {
+			pm.Source, pm.Target = remote, local
+		}
+		if pm.ID == "" {
+			pm.ID = fmt.Sprintf("legacy-%s-%d", mappingType, i+1)
+		}
+
+		result = append(result, pm)
+	}
+
+	return result
+}
+
+// joinLegacyAddr joins a legacy address and port into host:port
+func joinLegacyAddr(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
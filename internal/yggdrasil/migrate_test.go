@@ -0,0 +1,76 @@
+package yggdrasil
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigManager_LegacySettings(t *testing.T) {
+	cm := newTestConfigManager(t)
+
+	data := `{
+  "Peers": [],
+  "Listen": [],
+  "SOCKS": {"Enabled": true, "ListenAddress": "127.0.0.1:2080", "Nameserver": ""},
+  "Mappings": {
+    "LocalTCP": [{"name": "web", "enabled": true, "localAddr": "127.0.0.1", "localPort": 8080, "remoteAddr": "200::1", "remotePort": 80}],
+    "RemoteUDP": [{"enabled": true, "localAddr": "127.0.0.1", "localPort": 53, "remotePort": 53}]
+  }
+}`
+	if err := os.WriteFile(cm.path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+	if err := cm.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	legacy := cm.LegacySettings()
+	if legacy == nil {
+		t.Fatal("LegacySettings() = nil")
+	}
+	if legacy.Proxy == nil || legacy.Proxy.ListenAddress != "127.0.0.1:2080" || !legacy.Proxy.Enabled {
+		t.Errorf("Proxy = %+v", legacy.Proxy)
+	}
+
+	if len(legacy.Mappings.LocalTCP) != 1 {
+		t.Fatalf("len(LocalTCP) = %d, want 1", len(legacy.Mappings.LocalTCP))
+	}
+	local := legacy.Mappings.LocalTCP[0]
+	if local.ID != "web" || local.Source != "127.0.0.1:8080" || local.Target != "[200::1]:80" {
+		t.Errorf("LocalTCP[0] = %+v", local)
+	}
+
+	if len(legacy.Mappings.RemoteUDP) != 1 {
+		t.Fatalf("len(RemoteUDP) = %d, want 1", len(legacy.Mappings.RemoteUDP))
+	}
+	remote := legacy.Mappings.RemoteUDP[0]
+	if remote.Source != ":53" || remote.Target != "127.0.0.1:53" || remote.ID == "" {
+		t.Errorf("RemoteUDP[0] = %+v", remote)
+	}
+
+	// Sections are dropped from the file once cleared
+	cm.ClearLegacySettings()
+	if err := cm.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	saved, err := os.ReadFile(cm.path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if strings.Contains(string(saved), "SOCKS") || strings.Contains(string(saved), "Mappings") {
+		t.Errorf("legacy sections still saved:\n%s", saved)
+	}
+	if cm.LegacySettings() != nil {
+		t.Error("LegacySettings() after clear should be nil")
+	}
+}
+
+func TestConfigManager_LegacySettings_None(t *testing.T) {
+	cm := newTestConfigManager(t)
+	cm.config = defaultConfig()
+
+	if legacy := cm.LegacySettings(); legacy != nil {
+		t.Errorf("LegacySettings() = %+v, want nil for default config", legacy)
+	}
+}
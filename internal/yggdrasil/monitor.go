@@ -0,0 +1,235 @@
+package yggdrasil
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// DefaultStatsInterval is the default time between stats polls
+const DefaultStatsInterval = 2 * time.Second
+
+// PeerChange reports a peer that connected to or disconnected from a node
+type PeerChange struct {
+	Connected bool
+	Peer      PeerInfo
+}
+
+// TrafficStats is a snapshot of the traffic of a node
+type TrafficStats struct {
+	TotalRxBytes uint64    `json:"totalRxBytes"`
+	TotalTxBytes uint64    `json:"totalTxBytes"`
+	RxRate       float64   `json:"rxRate"` // bytes per second
+	TxRate       float64   `json:"txRate"` // bytes per second
+	PeerCount    int       `json:"peerCount"`
+	SessionCount int       `json:"sessionCount"`
+	Uptime       int64     `json:"uptime"` // seconds
+	Time         time.Time `json:"time"`
+}
+
+// StatsMonitor polls the peers of a running node. It reports peers that
+// connect or disconnect between two polls, and the traffic of the node with
+// rates computed from the byte counters of its peers.
+type StatsMonitor struct {
+	mu       sync.Mutex
+	service  *Service
+	peers    *PeerManager
+	sessions *SessionManager
+	logger   *logger.Logger
+	interval time.Duration
+	onPeers  func([]PeerChange, []PeerInfo)
+	onStats  func(TrafficStats)
+	last     map[string]PeerInfo // Connected peers of the last poll, by URI
+	lastPoll time.Time
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewStatsMonitor creates a monitor that runs while service is running
+func NewStatsMonitor(service *Service, peers *PeerManager, sessions *SessionManager, log *logger.Logger) *StatsMonitor {
+	m := &StatsMonitor{
+		service:  service,
+		peers:    peers,
+		sessions: sessions,
+		logger:   log,
+		interval: DefaultStatsInterval,
+	}
+
+	service.AddStateListener(m.onState)
+
+	return m
+}
+
+// SetHandlers sets the functions called with the peer changes and the
+// connected peers of a poll that found changes, and with the stats of every
+// poll
+func (m *StatsMonitor) SetHandlers(onPeers func([]PeerChange, []PeerInfo), onStats func(TrafficStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPeers = onPeers
+	m.onStats = onStats
+}
+
+// SetInterval changes the time between polls, restarting a running poll
+// loop so it takes effect
+func (m *StatsMonitor) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultStatsInterval
+	}
+
+	m.mu.Lock()
+	changed := m.interval != interval
+	m.interval = interval
+	running := m.stop != nil
+	m.mu.Unlock()
+
+	if changed && running {
+		m.Stop()
+		m.start()
+	}
+}
+
+// onState polls while the node is running. Peers still connected when the
+// node stops are reported as disconnected.
+func (m *StatsMonitor) onState(state ServiceState, info *NodeInfo) {
+	switch state {
+	case StateRunning:
+		m.start()
+	case StateStopping, StateStopped:
+		m.Stop()
+		m.update(nil, 0, 0, time.Now())
+	}
+}
+
+// start runs the poll loop
+func (m *StatsMonitor) start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	m.stop, m.done = stop, done
+
+	go func(interval time.Duration) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Poll()
+			case <-stop:
+				return
+			}
+		}
+	}(m.interval)
+
+	m.logger.Debug("Stats monitor started", "interval", m.interval)
+}
+
+// Stop stops the poll loop
+func (m *StatsMonitor) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Poll takes a snapshot of the peers and sessions of the node and reports
+// the changes since the last one
+func (m *StatsMonitor) Poll() {
+	peers, err := m.peers.GetPeers()
+	if err != nil {
+		return
+	}
+	sessions := m.sessions.GetSessionStats().Total
+	uptime := int64(m.service.GetUptime().Seconds())
+
+	m.update(peers, sessions, uptime, time.Now())
+}
+
+// update diffs peers against the last snapshot and calls the handlers
+func (m *StatsMonitor) update(peers []PeerInfo, sessions int, uptime int64, now time.Time) {
+	m.mu.Lock()
+	current := make(map[string]PeerInfo, len(peers))
+	var changes []PeerChange
+	connected := make([]PeerInfo, 0, len(peers))
+	stats := TrafficStats{SessionCount: sessions, Uptime: uptime, Time: now}
+
+	var rxDelta, txDelta uint64
+	for _, p := range peers {
+		if !p.Connected {
+			continue
+		}
+		current[p.URI] = p
+		connected = append(connected, p)
+		stats.PeerCount++
+		stats.TotalRxBytes += p.RxBytes
+		stats.TotalTxBytes += p.TxBytes
+
+		prev, known := m.last[p.URI]
+		if !known {
+			changes = append(changes, PeerChange{Connected: true, Peer: p})
+			prev = PeerInfo{}
+		}
+		// Counters restart with a reconnect
+		if p.RxBytes >= prev.RxBytes {
+			rxDelta += p.RxBytes - prev.RxBytes
+		}
+		if p.TxBytes >= prev.TxBytes {
+			txDelta += p.TxBytes - prev.TxBytes
+		}
+	}
+	var gone []string
+	for uri := range m.last {
+		if _, ok := current[uri]; !ok {
+			gone = append(gone, uri)
+		}
+	}
+	sort.Strings(gone)
+	for _, uri := range gone {
+		p := m.last[uri]
+		p.Connected = false
+		changes = append(changes, PeerChange{Connected: false, Peer: p})
+	}
+
+	if !m.lastPoll.IsZero() {
+		if elapsed := now.Sub(m.lastPoll).Seconds(); elapsed > 0 {
+			stats.RxRate = float64(rxDelta) / elapsed
+			stats.TxRate = float64(txDelta) / elapsed
+		}
+	}
+
+	m.last = current
+	m.lastPoll = now
+	if peers == nil {
+		// Node stopped, rates start over with the next run
+		m.lastPoll = time.Time{}
+	}
+	onPeers, onStats := m.onPeers, m.onStats
+	m.mu.Unlock()
+
+	for _, c := range changes {
+		if c.Connected {
+			m.logger.Info("Peer connected", "uri", c.Peer.URI, "address", c.Peer.Address)
+		} else {
+			m.logger.Info("Peer disconnected", "uri", c.Peer.URI, "address", c.Peer.Address)
+		}
+	}
+	if onPeers != nil && len(changes) > 0 {
+		onPeers(changes, connected)
+	}
+	if onStats != nil && peers != nil {
+		onStats(stats)
+	}
+}
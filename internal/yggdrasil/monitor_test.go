@@ -0,0 +1,72 @@
+package yggdrasil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestStatsMonitor_Update(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	svc := NewServiceWithConfig(newTestConfigManager(t), log)
+	m := NewStatsMonitor(svc, NewPeerManager(svc), NewSessionManager(svc), log)
+
+	var changes []PeerChange
+	var connected []PeerInfo
+	var stats []TrafficStats
+	m.SetHandlers(func(c []PeerChange, peers []PeerInfo) {
+		changes = append(changes, c...)
+		connected = peers
+	}, func(s TrafficStats) {
+		stats = append(stats, s)
+	})
+
+	a := PeerInfo{URI: "tls://a.example.com:443", Connected: true, RxBytes: 1000, TxBytes: 500}
+	b := PeerInfo{URI: "tcp://b.example.com:1234", Connected: true, RxBytes: 100, TxBytes: 100}
+	pending := PeerInfo{URI: "tcp://c.example.com:1234"}
+	start := time.Now()
+
+	m.update([]PeerInfo{a, pending}, 1, 10, start)
+	if len(changes) != 1 || !changes[0].Connected || changes[0].Peer.URI != a.URI {
+		t.Fatalf("first poll changes = %+v", changes)
+	}
+	if stats[0].PeerCount != 1 || stats[0].RxRate != 0 {
+		t.Errorf("first poll stats = %+v, want one peer and no rate", stats[0])
+	}
+
+	// a transferred 2000/1000 bytes in two seconds, b connected
+	a.RxBytes, a.TxBytes = 5000, 2500
+	m.update([]PeerInfo{a, b}, 1, 12, start.Add(2*time.Second))
+	if len(changes) != 2 || changes[1].Peer.URI != b.URI || len(connected) != 2 {
+		t.Fatalf("second poll changes = %+v", changes)
+	}
+	s := stats[1]
+	if s.TotalRxBytes != 5100 || s.RxRate != 2050 || s.TxRate != 1050 {
+		t.Errorf("second poll stats = %+v", s)
+	}
+
+	// a dropped, its counters are gone from the totals
+	m.update([]PeerInfo{b}, 0, 14, start.Add(4*time.Second))
+	if len(changes) != 3 || changes[2].Connected || changes[2].Peer.URI != a.URI {
+		t.Fatalf("third poll changes = %+v", changes)
+	}
+	if stats[2].PeerCount != 1 || stats[2].RxRate != 0 {
+		t.Errorf("third poll stats = %+v", stats[2])
+	}
+
+	// Unchanged peers only report stats
+	m.update([]PeerInfo{b}, 0, 16, start.Add(6*time.Second))
+	if len(changes) != 3 || len(stats) != 4 {
+		t.Errorf("unchanged poll: %d changes, %d stats", len(changes), len(stats))
+	}
+
+	// Stopping reports the remaining peers as disconnected
+	m.onState(StateStopping, nil)
+	if len(changes) != 4 || changes[3].Connected || changes[3].Peer.URI != b.URI {
+		t.Errorf("stop changes = %+v", changes)
+	}
+	if len(stats) != 4 {
+		t.Error("stopped node should not report stats")
+	}
+}
@@ -0,0 +1,129 @@
+package yggdrasil
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+// Node is a Yggdrasil service together with the managers built on its
+// netstack. Every node has its own identity, peers, proxy and mappings.
+type Node struct {
+	Service    *Service
+	Peers      *PeerManager
+	Sessions   *SessionManager
+	SOCKS      *SOCKSProxy
+	Mappings   *MappingManager
+	Supervisor *PeerSupervisor
+	Monitor    *StatsMonitor
+}
+
+// NewNode creates the managers for service. Enabled port mappings are
+// started and stopped together with the service.
+func NewNode(service *Service, log *logger.Logger) *Node {
+	n := &Node{
+		Service:    service,
+		Peers:      NewPeerManager(service),
+		Sessions:   NewSessionManager(service),
+		SOCKS:      NewSOCKSProxy(service, log),
+		Mappings:   NewMappingManager(service, log),
+		Supervisor: NewPeerSupervisor(service, log),
+	}
+	n.Monitor = NewStatsMonitor(service, n.Peers, n.Sessions, log)
+
+	service.AddStateListener(n.syncMappingsWithState)
+
+	return n
+}
+
+// Config returns the config manager of the node
+func (n *Node) Config() *ConfigManager {
+	return n.Service.ConfigManager()
+}
+
+// Stop stops the peer supervisor, the stats monitor, the proxy, the
+// mappings and the service of the node
+func (n *Node) Stop() error {
+	n.Supervisor.Stop()
+	n.Monitor.Stop()
+	if n.SOCKS.IsRunning() {
+		if err := n.SOCKS.Stop(); err != nil {
+			return err
+		}
+	}
+	if n.Service.GetState() == StateStopped {
+		return nil
+	}
+	return n.Service.Stop()
+}
+
+// syncMappingsWithState starts enabled mappings when the node is running
+// and stops them when it is shutting down
+func (n *Node) syncMappingsWithState(state ServiceState, info *NodeInfo) {
+	switch state {
+	case StateRunning:
+		n.Mappings.StartAllEnabled()
+	case StateStopping:
+		n.Mappings.StopAll()
+	}
+}
+
+// NodeRegistry holds nodes running next to each other in one process,
+// keyed by node ID
+type NodeRegistry struct {
+	mu    sync.RWMutex
+	nodes map[string]*Node
+}
+
+// NewNodeRegistry creates an empty node registry
+func NewNodeRegistry() *NodeRegistry {
+	return &NodeRegistry{
+		nodes: make(map[string]*Node),
+	}
+}
+
+// Add registers a node under id
+func (r *NodeRegistry) Add(id string, n *Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.nodes[id]; exists {
+		return fmt.Errorf("node already exists: %s", id)
+	}
+	r.nodes[id] = n
+	return nil
+}
+
+// Get returns the node registered under id
+func (r *NodeRegistry) Get(id string) (*Node, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n, ok := r.nodes[id]
+	return n, ok
+}
+
+// Remove unregisters the node with id and returns it
+func (r *NodeRegistry) Remove(id string) (*Node, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.nodes[id]
+	delete(r.nodes, id)
+	return n, ok
+}
+
+// IDs returns the IDs of all registered nodes, sorted
+func (r *NodeRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.nodes))
+	for id := range r.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
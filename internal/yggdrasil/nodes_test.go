@@ -0,0 +1,71 @@
+package yggdrasil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestNodeRegistry(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	r := NewNodeRegistry()
+
+	a := NewNode(NewServiceWithConfig(newTestConfigManager(t), log), log)
+	b := NewNode(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	if err := r.Add("mesh-b", b); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := r.Add("mesh-a", a); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := r.Add("mesh-a", b); err == nil {
+		t.Error("Add() of existing ID should fail")
+	}
+
+	if got := r.IDs(); !reflect.DeepEqual(got, []string{"mesh-a", "mesh-b"}) {
+		t.Errorf("IDs() = %v", got)
+	}
+	if n, ok := r.Get("mesh-a"); !ok || n != a {
+		t.Error("Get() returned the wrong node")
+	}
+
+	// Nodes have their own config and managers
+	if a.Config() == b.Config() || a.SOCKS == b.SOCKS || a.Mappings == b.Mappings {
+		t.Error("nodes share components")
+	}
+
+	if n, ok := r.Remove("mesh-a"); !ok || n != a {
+		t.Error("Remove() returned the wrong node")
+	}
+	if _, ok := r.Get("mesh-a"); ok {
+		t.Error("node still registered after Remove()")
+	}
+	if err := a.Stop(); err != nil {
+		t.Errorf("Stop() of a stopped node error = %v", err)
+	}
+}
+
+func TestProfileManager_OpenConfig(t *testing.T) {
+	pm, cm := newTestProfileManager(t)
+	if err := pm.Create("lab"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	lab, err := pm.OpenConfig("lab")
+	if err != nil {
+		t.Fatalf("OpenConfig() error = %v", err)
+	}
+	if lab.GetPrivateKey() == "" || lab.GetPrivateKey() == cm.GetPrivateKey() {
+		t.Error("OpenConfig() should load the identity of the profile")
+	}
+
+	if _, err := pm.OpenConfig(config.DefaultProfile); err == nil {
+		t.Error("OpenConfig() of the active profile should fail")
+	}
+	if _, err := pm.OpenConfig("missing"); err == nil {
+		t.Error("OpenConfig() of a missing profile should fail")
+	}
+}
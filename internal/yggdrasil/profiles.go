@@ -0,0 +1,381 @@
+package yggdrasil
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yggdrasil-network/yggdrasil-go/src/address"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/platform"
+)
+
+// profileConfigName is the config file name inside a profile directory
+const profileConfigName = "yggdrasil.conf"
+
+// profileNamePattern limits profile names to what is safe as a directory name
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]{0,63}$`)
+
+// ProfileInfo describes a node profile
+type ProfileInfo struct {
+	Name        string `json:"name"`
+	Active      bool   `json:"active"`
+	PublicKey   string `json:"publicKey,omitempty"`
+	IPv6Address string `json:"ipv6Address,omitempty"`
+}
+
+// ProfileManager manages named node profiles. Every profile has its own
+// Yggdrasil config file; the default profile keeps the original file.
+type ProfileManager struct {
+	mu          sync.Mutex
+	dir         string
+	defaultPath string
+	active      string
+	config      *ConfigManager
+	logger      *logger.Logger
+}
+
+// NewProfileManager creates a profile manager that switches cm between profiles
+func NewProfileManager(cm *ConfigManager, log *logger.Logger) *ProfileManager {
+	return NewProfileManagerWithPaths(cm, platform.GetProfilesDir(), platform.GetYggdrasilConfigPath(), log)
+}
+
+// NewProfileManagerWithPaths creates a profile manager with custom paths
+func NewProfileManagerWithPaths(cm *ConfigManager, dir, defaultPath string, log *logger.Logger) *ProfileManager {
+	return &ProfileManager{
+		dir:         dir,
+		defaultPath: defaultPath,
+		active:      config.DefaultProfile,
+		config:      cm,
+		logger:      log,
+	}
+}
+
+// ValidateProfileName checks that name can be used for a profile
+func ValidateProfileName(name string) error {
+	if name != strings.TrimSpace(name) || !profileNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid profile name %q: use up to 64 letters, digits, spaces, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// Active returns the name of the active profile
+func (pm *ProfileManager) Active() string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.active
+}
+
+// Exists reports whether a profile exists
+func (pm *ProfileManager) Exists(name string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.exists(name)
+}
+
+// List returns all profiles sorted by name, the default profile first
+func (pm *ProfileManager) List() ([]ProfileInfo, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	names := []string{}
+	entries, err := os.ReadDir(pm.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != config.DefaultProfile && ValidateProfileName(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{config.DefaultProfile}, names...)
+
+	profiles := make([]ProfileInfo, 0, len(names))
+	for _, name := range names {
+		info := ProfileInfo{Name: name, Active: name == pm.active}
+
+		publicKey := pm.publicKey(name)
+		if publicKey != "" {
+			info.PublicKey = publicKey
+			if keyBytes, err := hex.DecodeString(publicKey); err == nil && len(keyBytes) == ed25519.PublicKeySize {
+				addr := address.AddrForKey(ed25519.PublicKey(keyBytes))
+				info.IPv6Address = net.IP(addr[:]).String()
+			}
+		}
+
+		profiles = append(profiles, info)
+	}
+
+	return profiles, nil
+}
+
+// Create creates a profile with a new identity and default settings
+func (pm *ProfileManager) Create(name string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if err := pm.checkNew(name); err != nil {
+		return err
+	}
+
+	cm := pm.open(name)
+	if err := cm.Generate(); err != nil {
+		return err
+	}
+	if err := cm.Save(); err != nil {
+		return err
+	}
+
+	pm.logger.Info("Profile created", "name", name)
+	return nil
+}
+
+// Clone creates a profile from the settings of an existing one.
+// With newIdentity the clone gets its own keys, otherwise it shares them.
+func (pm *ProfileManager) Clone(src, dst string, newIdentity bool) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.exists(src) {
+		return fmt.Errorf("profile not found: %s", src)
+	}
+	if err := pm.checkNew(dst); err != nil {
+		return err
+	}
+
+	// The active profile may have changes that are not saved yet
+	var source *Config
+	if src == pm.active {
+		source = pm.config.GetConfig()
+	} else {
+		source = pm.open(src).GetConfig()
+	}
+
+	cm := pm.open(dst)
+	privateKey := source.PrivateKey
+	publicKey := source.PublicKey
+	if newIdentity {
+		if err := cm.Generate(); err != nil {
+			return err
+		}
+		privateKey = cm.GetPrivateKey()
+		publicKey = cm.GetPublicKey()
+	}
+
+	clone := *source
+	clone.PrivateKey = privateKey
+	clone.PublicKey = publicKey
+	clone.Peers = append([]string{}, source.Peers...)
+	clone.Listen = append([]string{}, source.Listen...)
+	clone.MulticastInterfaces = append([]string{}, source.MulticastInterfaces...)
+	clone.AllowedPublicKeys = append([]string{}, source.AllowedPublicKeys...)
+	clone.SOCKS = nil
+	clone.Mappings = nil
+	cm.Import(&clone)
+
+	if err := cm.Save(); err != nil {
+		return err
+	}
+
+	pm.logger.Info("Profile cloned", "source", src, "name", dst, "newIdentity", newIdentity)
+	return nil
+}
+
+// Rename renames a profile. The default profile cannot be renamed.
+func (pm *ProfileManager) Rename(oldName, newName string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if oldName == config.DefaultProfile {
+		return fmt.Errorf("the default profile cannot be renamed")
+	}
+	if !pm.exists(oldName) {
+		return fmt.Errorf("profile not found: %s", oldName)
+	}
+	if err := pm.checkNew(newName); err != nil {
+		return err
+	}
+
+	if err := os.Rename(filepath.Join(pm.dir, oldName), filepath.Join(pm.dir, newName)); err != nil {
+		return err
+	}
+
+	if pm.active == oldName {
+		pm.active = newName
+		if err := pm.config.SetPath(pm.path(newName)); err != nil {
+			return err
+		}
+	}
+
+	pm.logger.Info("Profile renamed", "from", oldName, "to", newName)
+	return nil
+}
+
+// Delete removes an inactive profile and its config.
+// The default profile cannot be deleted.
+func (pm *ProfileManager) Delete(name string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if name == config.DefaultProfile {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+	if name == pm.active {
+		return fmt.Errorf("the active profile cannot be deleted")
+	}
+	if !pm.exists(name) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+
+	publicKey := pm.publicKey(name)
+	if err := os.RemoveAll(filepath.Join(pm.dir, name)); err != nil {
+		return err
+	}
+	pm.deleteUnusedKey(publicKey)
+
+	pm.logger.Info("Profile deleted", "name", name)
+	return nil
+}
+
+// Activate points the config manager at the config of a profile.
+// The node must be restarted to use the new identity.
+func (pm *ProfileManager) Activate(name string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.exists(name) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+
+	if err := pm.config.SetPath(pm.path(name)); err != nil {
+		return fmt.Errorf("failed to load profile %s: %w", name, err)
+	}
+
+	pm.active = name
+	pm.logger.Info("Profile activated", "name", name, "path", pm.path(name))
+	return nil
+}
+
+// OpenConfig returns a config manager for an inactive profile, for
+// running it as an additional node
+func (pm *ProfileManager) OpenConfig(name string) (*ConfigManager, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.exists(name) {
+		return nil, fmt.Errorf("profile not found: %s", name)
+	}
+	if name == pm.active {
+		return nil, fmt.Errorf("profile %s is already used by the primary node", name)
+	}
+
+	return pm.open(name), nil
+}
+
+// MigrateKeys moves plaintext private keys of inactive profiles to the key
+// store of the active config. Loading a config performs the move.
+func (pm *ProfileManager) MigrateKeys() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.config.keyStore() == nil {
+		return
+	}
+
+	names := []string{config.DefaultProfile}
+	if entries, err := os.ReadDir(pm.dir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && ValidateProfileName(entry.Name()) == nil {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	for _, name := range names {
+		if name != pm.active && pm.exists(name) {
+			pm.open(name)
+		}
+	}
+}
+
+// path returns the config file path of a profile
+func (pm *ProfileManager) path(name string) string {
+	if name == config.DefaultProfile {
+		return pm.defaultPath
+	}
+	return filepath.Join(pm.dir, name, profileConfigName)
+}
+
+// open returns a config manager for a profile that shares the key store
+// of the active config
+func (pm *ProfileManager) open(name string) *ConfigManager {
+	return newConfigManagerWithKeys(pm.path(name), pm.config.keyStore(), pm.logger)
+}
+
+// deleteUnusedKey removes a private key from the key store once no
+// profile uses its identity anymore (must be called with lock held)
+func (pm *ProfileManager) deleteUnusedKey(publicKey string) {
+	ks := pm.config.keyStore()
+	if ks == nil || publicKey == "" {
+		return
+	}
+
+	names := []string{config.DefaultProfile}
+	if entries, err := os.ReadDir(pm.dir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	for _, name := range names {
+		if pm.publicKey(name) == publicKey {
+			return
+		}
+	}
+
+	if err := ks.Delete(privateKeyAccount(publicKey)); err != nil {
+		pm.logger.Warn("Failed to delete private key from secure storage", "error", err)
+	}
+}
+
+// exists reports whether a profile exists (must be called with lock held)
+func (pm *ProfileManager) exists(name string) bool {
+	if name == config.DefaultProfile {
+		return true
+	}
+	if ValidateProfileName(name) != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(pm.dir, name))
+	return err == nil && info.IsDir()
+}
+
+// checkNew validates the name of a profile about to be created
+func (pm *ProfileManager) checkNew(name string) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if pm.exists(name) {
+		return fmt.Errorf("profile already exists: %s", name)
+	}
+	return nil
+}
+
+// publicKey returns the public key stored in the config of a profile
+func (pm *ProfileManager) publicKey(name string) string {
+	if name == pm.active {
+		return pm.config.GetPublicKey()
+	}
+	return NewConfigManagerWithPath(pm.path(name), pm.logger).GetPublicKey()
+}
@@ -0,0 +1,168 @@
+package yggdrasil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/config"
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func newTestProfileManager(t *testing.T) (*ProfileManager, *ConfigManager) {
+	t.Helper()
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	dir := t.TempDir()
+	defaultPath := filepath.Join(dir, "yggdrasil.conf")
+
+	cm := NewConfigManagerWithPath(defaultPath, log)
+	if err := cm.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := cm.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	return NewProfileManagerWithPaths(cm, filepath.Join(dir, "profiles"), defaultPath, log), cm
+}
+
+func TestValidateProfileName(t *testing.T) {
+	valid := []string{"lab", "prod mesh", "node-1", "a.b_c"}
+	for _, name := range valid {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("ValidateProfileName(%q) error = %v", name, err)
+		}
+	}
+
+	invalid := []string{"", " lab", "lab ", "../etc", "a/b", `a\b`, ".hidden", "a..b", string(make([]byte, 65))}
+	for _, name := range invalid {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("ValidateProfileName(%q) should fail", name)
+		}
+	}
+}
+
+func TestProfileManager_CreateActivate(t *testing.T) {
+	pm, cm := newTestProfileManager(t)
+	defaultKey := cm.GetPrivateKey()
+
+	if err := pm.Create("lab"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := pm.Create("lab"); err == nil {
+		t.Error("Create() of existing profile should fail")
+	}
+	if err := pm.Create(config.DefaultProfile); err == nil {
+		t.Error("Create() of default profile should fail")
+	}
+
+	profiles, err := pm.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != config.DefaultProfile || profiles[1].Name != "lab" {
+		t.Fatalf("List() = %+v", profiles)
+	}
+	if !profiles[0].Active || profiles[1].Active {
+		t.Error("default profile should be active")
+	}
+	if profiles[1].PublicKey == "" || profiles[1].IPv6Address == "" {
+		t.Errorf("new profile has no identity: %+v", profiles[1])
+	}
+
+	if err := pm.Activate("lab"); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	if pm.Active() != "lab" {
+		t.Errorf("Active() = %q, want lab", pm.Active())
+	}
+	if cm.GetPrivateKey() == defaultKey {
+		t.Error("config manager still uses the default identity")
+	}
+	if cm.GetPath() != filepath.Join(pm.dir, "lab", profileConfigName) {
+		t.Errorf("GetPath() = %q", cm.GetPath())
+	}
+
+	if err := pm.Activate(config.DefaultProfile); err != nil {
+		t.Fatalf("Activate(default) error = %v", err)
+	}
+	if cm.GetPrivateKey() != defaultKey {
+		t.Error("default identity not restored")
+	}
+
+	if err := pm.Activate("missing"); err == nil {
+		t.Error("Activate() of missing profile should fail")
+	}
+}
+
+func TestProfileManager_Clone(t *testing.T) {
+	pm, cm := newTestProfileManager(t)
+	cm.SetPeers([]string{"tls://lab.example.com:443"})
+
+	if err := pm.Clone(config.DefaultProfile, "same", false); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if err := pm.Clone(config.DefaultProfile, "fresh", true); err != nil {
+		t.Fatalf("Clone(newIdentity) error = %v", err)
+	}
+	if err := pm.Clone("missing", "other", false); err == nil {
+		t.Error("Clone() of missing profile should fail")
+	}
+
+	same := NewConfigManagerWithPath(pm.path("same"), pm.logger)
+	if same.GetPrivateKey() != cm.GetPrivateKey() {
+		t.Error("clone without new identity should keep the keys")
+	}
+	if peers := same.GetPeers(); len(peers) != 1 || peers[0] != "tls://lab.example.com:443" {
+		t.Errorf("cloned peers = %v", peers)
+	}
+
+	fresh := NewConfigManagerWithPath(pm.path("fresh"), pm.logger)
+	if fresh.GetPrivateKey() == "" || fresh.GetPrivateKey() == cm.GetPrivateKey() {
+		t.Error("clone with new identity should have its own keys")
+	}
+	if peers := fresh.GetPeers(); len(peers) != 1 {
+		t.Errorf("cloned peers = %v", peers)
+	}
+}
+
+func TestProfileManager_RenameDelete(t *testing.T) {
+	pm, cm := newTestProfileManager(t)
+
+	if err := pm.Create("lab"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := pm.Activate("lab"); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	key := cm.GetPrivateKey()
+
+	if err := pm.Rename(config.DefaultProfile, "main"); err == nil {
+		t.Error("Rename() of default profile should fail")
+	}
+	if err := pm.Rename("lab", "prod mesh"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if pm.Active() != "prod mesh" || cm.GetPrivateKey() != key {
+		t.Errorf("active profile after rename = %q", pm.Active())
+	}
+	if pm.Exists("lab") {
+		t.Error("old profile name still exists")
+	}
+
+	if err := pm.Delete("prod mesh"); err == nil {
+		t.Error("Delete() of active profile should fail")
+	}
+	if err := pm.Delete(config.DefaultProfile); err == nil {
+		t.Error("Delete() of default profile should fail")
+	}
+
+	if err := pm.Activate(config.DefaultProfile); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	if err := pm.Delete("prod mesh"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if pm.Exists("prod mesh") {
+		t.Error("profile still exists after delete")
+	}
+}
@@ -0,0 +1,345 @@
+package yggdrasil
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/things-go/go-socks5"
+	"github.com/things-go/go-socks5/statute"
+	"golang.org/x/net/proxy"
+)
+
+// RouteAction tells the proxy how to reach a destination
+type RouteAction string
+
+const (
+	RouteYggdrasil RouteAction = "yggdrasil"
+	RouteDirect    RouteAction = "direct"
+	RouteReject    RouteAction = "reject"
+	RouteUpstream  RouteAction = "upstream"
+)
+
+// RoutingRule routes destinations matching a domain, address or CIDR
+type RoutingRule struct {
+	Match  string      `json:"match"` // e.g. "example.com" (with subdomains), "10.0.0.0/8"
+	Action RouteAction `json:"action"`
+}
+
+// RoutingConfig contains the split routing settings of the proxy. When it
+// is disabled every destination goes over Yggdrasil.
+type RoutingConfig struct {
+	Enabled  bool          `json:"enabled"`
+	Default  RouteAction   `json:"default,omitempty"`  // Destinations outside Yggdrasil; "direct" when empty
+	Upstream string        `json:"upstream,omitempty"` // socks5://[user:pass@]host:port or http://host:port
+	Rules    []RoutingRule `json:"rules,omitempty"`    // Checked in order, before the built-in routes
+}
+
+// errRouteRejected is returned when a routing rule rejects a destination
+var errRouteRejected = errors.New("destination rejected by routing rules")
+
+// yggdrasilNetwork is the address range of the Yggdrasil network
+var _, yggdrasilNetwork, _ = net.ParseCIDR("200::/7")
+
+// routeRule is a parsed routing rule
+type routeRule struct {
+	domain  string
+	network *net.IPNet
+	action  RouteAction
+}
+
+// router decides the route of proxy destinations
+type router struct {
+	rules    []routeRule
+	fallback RouteAction
+	upstream func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// ValidateRouting checks the rules, actions and upstream of a routing config
+func ValidateRouting(cfg RoutingConfig) error {
+	_, err := newRouter(cfg)
+	return err
+}
+
+// newRouter parses a routing config. It returns nil when routing is off.
+func newRouter(cfg RoutingConfig) (*router, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	r := &router{fallback: cfg.Default}
+	if r.fallback == "" {
+		r.fallback = RouteDirect
+	}
+	if err := validateRouteAction(r.fallback); err != nil {
+		return nil, err
+	}
+
+	needUpstream := r.fallback == RouteUpstream
+	for _, rule := range cfg.Rules {
+		parsed, err := parseRouteRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		needUpstream = needUpstream || parsed.action == RouteUpstream
+		r.rules = append(r.rules, parsed)
+	}
+
+	if cfg.Upstream != "" {
+		upstream, err := upstreamDialer(cfg.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		r.upstream = upstream
+	} else if needUpstream {
+		return nil, fmt.Errorf("upstream route used without an upstream proxy")
+	}
+	return r, nil
+}
+
+func validateRouteAction(action RouteAction) error {
+	switch action {
+	case RouteYggdrasil, RouteDirect, RouteReject, RouteUpstream:
+		return nil
+	}
+	return fmt.Errorf("invalid route action: %q", action)
+}
+
+// parseRouteRule parses the match of a rule as a CIDR, an address or a
+// domain. Domains also match their subdomains.
+func parseRouteRule(rule RoutingRule) (routeRule, error) {
+	if err := validateRouteAction(rule.Action); err != nil {
+		return routeRule{}, err
+	}
+
+	match := strings.ToLower(strings.TrimSpace(rule.Match))
+	match = strings.TrimPrefix(strings.TrimPrefix(match, "*"), ".")
+	parsed := routeRule{action: rule.Action}
+
+	switch {
+	case strings.Contains(match, "/"):
+		_, network, err := net.ParseCIDR(match)
+		if err != nil {
+			return routeRule{}, fmt.Errorf("invalid route subnet: %s", rule.Match)
+		}
+		parsed.network = network
+	case net.ParseIP(strings.Trim(match, "[]")) != nil:
+		ip := net.ParseIP(strings.Trim(match, "[]"))
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		parsed.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	case match != "" && !strings.ContainsAny(match, ":/ "):
+		parsed.domain = strings.TrimSuffix(match, ".")
+	default:
+		return routeRule{}, fmt.Errorf("invalid route match: %q", rule.Match)
+	}
+	return parsed, nil
+}
+
+// route returns the action for a destination host, a name or an address
+func (r *router) route(host string) RouteAction {
+	action, _ := r.match(host)
+	return action
+}
+
+// match returns the action for a destination host and whether a rule
+// chose it
+func (r *router) match(host string) (RouteAction, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+
+	for _, rule := range r.rules {
+		if ip != nil && rule.network != nil && rule.network.Contains(ip) {
+			return rule.action, true
+		}
+		if ip == nil && rule.domain != "" && (host == rule.domain || strings.HasSuffix(host, "."+rule.domain)) {
+			return rule.action, true
+		}
+	}
+
+	// Yggdrasil addresses and names always go over the mesh unless a rule
+	// says otherwise
+	if ip != nil && yggdrasilNetwork.Contains(ip) {
+		return RouteYggdrasil, false
+	}
+	if ip == nil && (host == "ygg" || strings.HasSuffix(host, ".ygg")) {
+		return RouteYggdrasil, false
+	}
+	return r.fallback, false
+}
+
+// refuseLocal is a net.Dialer Control function that refuses loopback,
+// unspecified and link-local addresses. It checks the address actually
+// dialled, so names resolving to them are refused too.
+func refuseLocal(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("%w: %s is a local address", errRouteRejected, host)
+	}
+	return nil
+}
+
+// Allow implements socks5.RuleSet, so rejected destinations get the
+// "not allowed by ruleset" reply
+func (r *router) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
+	if req.Command != statute.CommandConnect {
+		return ctx, true
+	}
+	host := req.RawDestAddr.FQDN
+	if host == "" {
+		host = req.RawDestAddr.IP.String()
+	}
+	return ctx, r.route(host) != RouteReject
+}
+
+// deferredResolver leaves name resolution to the routed dialer, which
+// resolves names according to their route
+type deferredResolver struct{}
+
+// Resolve implements socks5.NameResolver. Without an address the SOCKS
+// server dials the name itself.
+func (deferredResolver) Resolve(ctx context.Context, name string) (context.Context, net.IP, error) {
+	return ctx, nil, nil
+}
+
+// createRoutedDialer creates a dial function that sends each destination
+// over Yggdrasil, directly, to the upstream proxy or nowhere. The direct
+// route only reaches local addresses of this machine when a rule names
+// the destination.
+func (sp *SOCKSProxy) createRoutedDialer(r *router, mesh func(ctx context.Context, network, addr string) (net.Conn, error),
+	resolver socks5.NameResolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var direct net.Dialer
+	remote := net.Dialer{Control: refuseLocal}
+
+	return sp.trackDial(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		action, ruled := r.match(host)
+		sp.logger.Debug("Routing proxy connection", "host", host, "route", action)
+
+		switch action {
+		case RouteYggdrasil:
+			if net.ParseIP(host) == nil {
+				_, ip, err := resolver.Resolve(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				addr = net.JoinHostPort(ip.String(), port)
+			}
+			return mesh(ctx, network, addr)
+		case RouteDirect:
+			if ruled {
+				return direct.DialContext(ctx, network, addr)
+			}
+			return remote.DialContext(ctx, network, addr)
+		case RouteUpstream:
+			return r.upstream(ctx, network, addr)
+		default:
+			return nil, fmt.Errorf("%w: %s", errRouteRejected, host)
+		}
+	})
+}
+
+// upstreamDialer returns a dial function through a SOCKS5 or HTTP proxy
+func upstreamDialer(raw string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		// The address may hold credentials, so it is not repeated
+		return nil, fmt.Errorf("invalid upstream proxy address")
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream proxy: %w", err)
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("upstream proxy dialer does not support contexts")
+		}
+		return contextDialer.DialContext, nil
+	case "http":
+		if u.Port() == "" {
+			u.Host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialHTTPConnect(ctx, u, addr)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported upstream proxy scheme: %q", u.Scheme)
+}
+
+// dialHTTPConnect opens a tunnel to addr through an HTTP proxy
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns bytes read ahead by a bufio.Reader before reading
+// from the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
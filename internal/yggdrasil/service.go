@@ -61,6 +61,12 @@ type NodeInfo struct {
 // StateListener is called when service state changes
 type StateListener func(state ServiceState, info *NodeInfo)
 
+// stateEvent is a state change waiting to be delivered to listeners
+type stateEvent struct {
+	state ServiceState
+	info  *NodeInfo
+}
+
 // Service wraps Yggdrasil core functionality
 type Service struct {
 	mu            sync.RWMutex
@@ -68,6 +74,8 @@ type Service struct {
 	configManager *ConfigManager
 	startTime     time.Time
 	listeners     []StateListener
+	pending       []stateEvent // State changes not yet delivered
+	notifying     bool         // Whether a goroutine is delivering state changes
 	ctx           context.Context
 	cancel        context.CancelFunc
 	logger        *logger.Logger
@@ -84,12 +92,18 @@ type Service struct {
 
 // NewService creates a new Yggdrasil service
 func NewService(log *logger.Logger) *Service {
+	return NewServiceWithConfig(NewConfigManager(log), log)
+}
+
+// NewServiceWithConfig creates a Yggdrasil service that runs with the
+// config of cm
+func NewServiceWithConfig(cm *ConfigManager, log *logger.Logger) *Service {
 	// Create internal logger for yggdrasil-go components
 	yggLogger := createYggdrasilLogger(log)
 
 	return &Service{
 		state:         StateStopped,
-		configManager: NewConfigManager(log),
+		configManager: cm,
 		listeners:     make([]StateListener, 0),
 		logger:        log,
 		yggLogger:     yggLogger,
@@ -244,21 +258,36 @@ func (s *Service) prepareConfig() (*config.NodeConfig, error) {
 	if len(ourCfg.AllowedPublicKeys) > 0 {
 		yggCfg.AllowedPublicKeys = ourCfg.AllowedPublicKeys
 	}
+	if len(ourCfg.InterfacePeers) > 0 {
+		yggCfg.InterfacePeers = ourCfg.InterfacePeers
+	}
+	if len(ourCfg.MulticastInterfaces) > 0 {
+		yggCfg.MulticastInterfaces = multicastInterfaces(ourCfg.MulticastInterfaces)
+	}
+	yggCfg.NodeInfo = ourCfg.NodeInfo
+	yggCfg.NodeInfoPrivacy = ourCfg.NodeInfoPrivacy
 
-	// If we have stored private key, use it
-	if ourCfg.PrivateKey != "" {
-		privKeyBytes, err := hex.DecodeString(ourCfg.PrivateKey)
-		if err == nil && len(privKeyBytes) == ed25519.PrivateKeySize {
-			yggCfg.PrivateKey = config.KeyBytes(privKeyBytes)
-			s.logger.Debug("Using stored private key")
+	// If we have stored private key, use it. With secure storage the key
+	// is read from there rather than from the config file. A node whose key
+	// cannot be read is not started, so its identity is never replaced.
+	privateKey, err := s.configManager.storedPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if privateKey != "" {
+		privKeyBytes, err := hex.DecodeString(privateKey)
+		if err != nil || len(privKeyBytes) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid private key in config")
 		}
+		yggCfg.PrivateKey = config.KeyBytes(privKeyBytes)
+		s.logger.Debug("Using stored private key")
 	}
 
 	// Disable admin socket for GUI
 	yggCfg.AdminListen = "none"
 
 	// Store the generated keys back to our config if new
-	if ourCfg.PrivateKey == "" {
+	if privateKey == "" {
 		ourCfg.PrivateKey = hex.EncodeToString(yggCfg.PrivateKey)
 		// Get public key from private key
 		privKey := ed25519.PrivateKey(yggCfg.PrivateKey)
@@ -345,9 +374,10 @@ func (s *Service) Stop() error {
 	return nil
 }
 
-// Restart restarts the service
+// Restart restarts the service with the current configuration.
+// Listeners see the full stopping/stopped/starting/running sequence.
 func (s *Service) Restart() error {
-	if s.state == StateRunning {
+	if s.GetState() == StateRunning {
 		if err := s.Stop(); err != nil {
 			return err
 		}
@@ -398,18 +428,42 @@ func (s *Service) AddStateListener(listener StateListener) {
 }
 
 // setState updates the service state and notifies listeners
+// (must be called with lock held)
 func (s *Service) setState(state ServiceState) {
 	s.state = state
 
-	// Notify listeners (copy to avoid holding lock)
-	listeners := make([]StateListener, len(s.listeners))
-	copy(listeners, s.listeners)
+	// Queue the change so listeners see states in order, e.g. mappings
+	// are stopped for the old node before they start on the new one
+	s.pending = append(s.pending, stateEvent{state: state, info: s.nodeInfo})
+	if s.notifying {
+		return
+	}
+	s.notifying = true
+
+	go s.notifyListeners()
+}
+
+// notifyListeners delivers queued state changes until the queue is empty
+func (s *Service) notifyListeners() {
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.notifying = false
+			s.mu.Unlock()
+			return
+		}
+		event := s.pending[0]
+		s.pending = s.pending[1:]
+
+		// Copy listeners to avoid holding lock
+		listeners := make([]StateListener, len(s.listeners))
+		copy(listeners, s.listeners)
+		s.mu.Unlock()
 
-	go func() {
 		for _, listener := range listeners {
-			listener(state, s.nodeInfo)
+			listener(event.state, event.info)
 		}
-	}()
+	}
 }
 
 // AddPeer adds a peer to the running node
@@ -223,6 +223,50 @@ func TestService_StateListener(t *testing.T) {
 	}
 }
 
+func TestService_RestartListenerOrder(t *testing.T) {
+	svc := newTestService(t)
+
+	var mu sync.Mutex
+	var states []ServiceState
+	done := make(chan struct{})
+
+	svc.AddStateListener(func(state ServiceState, info *NodeInfo) {
+		// Slow listener, later states must still arrive after this one
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		states = append(states, state)
+		if len(states) == 6 {
+			close(done)
+		}
+		mu.Unlock()
+	})
+
+	if err := svc.Start(nil); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer svc.Stop()
+
+	if err := svc.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for state changes")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []ServiceState{StateStarting, StateRunning, StateStopping, StateStopped, StateStarting, StateRunning}
+	for i, state := range want {
+		if states[i] != state {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
 func TestService_AddPeer(t *testing.T) {
 	svc := newTestService(t)
 
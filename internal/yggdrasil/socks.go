@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/things-go/go-socks5"
 
@@ -18,6 +20,19 @@ type SOCKSConfig struct {
 	Enabled       bool   `json:"enabled"`
 	ListenAddress string `json:"listenAddress"` // e.g., "127.0.0.1:1080"
 	Nameserver    string `json:"nameserver"`    // Optional DNS resolver for Yggdrasil
+
+	// Optional HTTP proxy listener sharing the dialer, resolver and stats
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"`
+
+	// RFC 1929 credentials; clients must authenticate when Username is set
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Client addresses or CIDRs allowed to connect; empty allows all
+	AllowedClients []string `json:"allowedClients,omitempty"`
+
+	// Split routing of destinations outside Yggdrasil
+	Routing RoutingConfig `json:"routing"`
 }
 
 // SOCKSStats contains SOCKS5 proxy statistics
@@ -28,6 +43,10 @@ type SOCKSStats struct {
 	TotalConnections  uint64 `json:"totalConnections"`
 	BytesIn           uint64 `json:"bytesIn"`
 	BytesOut          uint64 `json:"bytesOut"`
+	AuthEnabled       bool   `json:"authEnabled"`
+	AuthFailures      uint64 `json:"authFailures"`
+	HTTPListenAddress string `json:"httpListenAddress,omitempty"` // Set while the HTTP proxy runs
+	UDPAssociations   int64  `json:"udpAssociations"`             // Open UDP ASSOCIATE relays
 }
 
 // SOCKSProxy manages a SOCKS5 proxy server that routes through Yggdrasil
@@ -37,6 +56,8 @@ type SOCKSProxy struct {
 	service           *Service
 	server            *socks5.Server
 	listener          net.Listener
+	httpProxy         *httpProxy
+	httpServer        *http.Server
 	ctx               context.Context
 	cancel            context.CancelFunc
 	running           bool
@@ -44,6 +65,11 @@ type SOCKSProxy struct {
 	totalConnections  uint64
 	bytesIn           uint64
 	bytesOut          uint64
+	authFailures      uint64
+	udpAssociations   int64
+	onAuthFailure     func(remote, username string)
+	allowlist         []*net.IPNet
+	clients           *socksClients
 	logger            *logger.Logger
 }
 
@@ -55,6 +81,7 @@ func NewSOCKSProxy(service *Service, log *logger.Logger) *SOCKSProxy {
 			ListenAddress: "127.0.0.1:1080",
 		},
 		service: service,
+		clients: newSOCKSClients(),
 		logger:  log,
 	}
 }
@@ -77,55 +104,130 @@ func (sp *SOCKSProxy) Start(config SOCKSConfig) error {
 		return fmt.Errorf("netstack not available")
 	}
 
-	sp.config = config
+	if err := validateSOCKSCredentials(config.Username, config.Password); err != nil {
+		return err
+	}
+
+	allowlist, err := parseClientAllowlist(config.AllowedClients)
+	if err != nil {
+		return err
+	}
+
+	router, err := newRouter(config.Routing)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create SOCKS5 server options
+	// Without a nameserver the resolver only supports .pk.ygg addresses
+	resolver := NewNameResolver(ns, config.Nameserver)
+
+	// Create SOCKS5 server options. With split routing, names reach the
+	// dialer unresolved so each is resolved by its route.
+	dial := sp.createDialer(ns)
+	var dialResolver socks5.NameResolver = resolver
+	if router != nil {
+		dial = sp.createRoutedDialer(router, ns.DialContext, resolver)
+		dialResolver = deferredResolver{}
+	}
 	socksOptions := []socks5.Option{
-		socks5.WithDial(sp.createDialer(ns)),
+		socks5.WithDial(dial),
+		socks5.WithResolver(dialResolver),
+	}
+	if router != nil {
+		socksOptions = append(socksOptions, socks5.WithRule(router))
+	}
+
+	// Require username/password authentication if configured
+	var creds *socksCredentials
+	if config.Username != "" {
+		creds = &socksCredentials{
+			proxy:    sp,
+			username: config.Username,
+			password: config.Password,
+		}
+		socksOptions = append(socksOptions, socks5.WithCredential(creds))
 	}
 
-	// Add DNS resolver if configured
-	if config.Nameserver != "" {
-		resolver := NewNameResolver(ns, config.Nameserver)
-		socksOptions = append(socksOptions, socks5.WithResolver(resolver))
-	} else {
-		// Use default resolver that only supports .pk.ygg addresses
-		resolver := NewNameResolver(ns, "")
-		socksOptions = append(socksOptions, socks5.WithResolver(resolver))
+	// UDP ASSOCIATE relays datagrams through a Yggdrasil UDP socket
+	listenUDP := func() (net.PacketConn, error) {
+		return ns.ListenUDP(&net.UDPAddr{})
 	}
+	socksOptions = append(socksOptions, socks5.WithAssociateHandle(sp.createAssociateHandler(ctx, listenUDP, resolver, router)))
 
 	// Create SOCKS5 server
-	sp.server = socks5.NewServer(socksOptions...)
+	server := socks5.NewServer(socksOptions...)
 
 	// Create listener
 	listener, err := net.Listen("tcp", config.ListenAddress)
 	if err != nil {
+		cancel()
 		return fmt.Errorf("failed to start SOCKS listener: %w", err)
 	}
 
+	// Check clients against the allowlist and account them per address
+	listener = &clientListener{Listener: listener, proxy: sp, allowlist: allowlist}
+
+	// The HTTP proxy shares the dialer, resolver, credentials and stats
+	if config.HTTPListenAddress != "" {
+		httpListener, err := net.Listen("tcp", config.HTTPListenAddress)
+		if err != nil {
+			listener.Close()
+			cancel()
+			return fmt.Errorf("failed to start HTTP proxy listener: %w", err)
+		}
+
+		sp.httpProxy = newHTTPProxy(dial, dialResolver, creds, sp.logger)
+		sp.httpServer = &http.Server{
+			Handler:           sp.httpProxy,
+			ReadHeaderTimeout: 30 * time.Second,
+		}
+		go func(server *http.Server, l net.Listener) {
+			if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+				sp.logger.Debug("HTTP proxy server stopped", "error", err)
+			}
+		}(sp.httpServer, &clientListener{Listener: httpListener, proxy: sp, allowlist: allowlist})
+	}
+
+	// The config is only taken once both listeners are up, so a failed
+	// start leaves the previous one
+	sp.config = config
+	sp.allowlist = allowlist
+	sp.server = server
 	sp.listener = listener
-	sp.ctx, sp.cancel = context.WithCancel(context.Background())
+	sp.ctx, sp.cancel = ctx, cancel
 	sp.running = true
 	sp.config.Enabled = true
 
 	// Start accepting connections
 	go func() {
-		if err := sp.server.Serve(listener); err != nil {
+		if err := server.Serve(listener); err != nil {
 			sp.logger.Debug("SOCKS5 server stopped", "error", err)
 		}
 	}()
 
-	sp.logger.Info("SOCKS5 proxy started", "address", config.ListenAddress)
+	sp.logger.Info("SOCKS5 proxy started", "address", config.ListenAddress,
+		"auth", config.Username != "", "allowedClients", len(allowlist), "routing", router != nil)
+	if config.HTTPListenAddress != "" {
+		sp.logger.Info("HTTP proxy started", "address", config.HTTPListenAddress)
+	}
 	return nil
 }
 
 // createDialer creates a dial function that routes through Yggdrasil netstack
 func (sp *SOCKSProxy) createDialer(ns *netstack.YggdrasilNetstack) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return sp.trackDial(ns.DialContext)
+}
+
+// trackDial wraps a dial function to track connection statistics
+func (sp *SOCKSProxy) trackDial(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(
+	ctx context.Context, network, addr string) (net.Conn, error) {
 	return func(ctx context.Context, network, addr string) (net.Conn, error) {
 		atomic.AddInt64(&sp.activeConnections, 1)
 		atomic.AddUint64(&sp.totalConnections, 1)
 
-		conn, err := ns.DialContext(ctx, network, addr)
+		conn, err := dial(ctx, network, addr)
 		if err != nil {
 			atomic.AddInt64(&sp.activeConnections, -1)
 			return nil, err
@@ -191,6 +293,14 @@ func (sp *SOCKSProxy) Stop() error {
 		sp.listener = nil
 	}
 
+	if sp.httpServer != nil {
+		// Closing the proxy also ends the hijacked CONNECT tunnels
+		sp.httpServer.Close()
+		sp.httpProxy.close()
+		sp.httpServer = nil
+		sp.httpProxy = nil
+	}
+
 	sp.server = nil
 	sp.running = false
 	sp.config.Enabled = false
@@ -211,9 +321,22 @@ func (sp *SOCKSProxy) GetStats() *SOCKSStats {
 		TotalConnections:  atomic.LoadUint64(&sp.totalConnections),
 		BytesIn:           atomic.LoadUint64(&sp.bytesIn),
 		BytesOut:          atomic.LoadUint64(&sp.bytesOut),
+		AuthEnabled:       sp.running && sp.config.Username != "",
+		AuthFailures:      atomic.LoadUint64(&sp.authFailures),
+		HTTPListenAddress: sp.httpListenAddress(),
+		UDPAssociations:   atomic.LoadInt64(&sp.udpAssociations),
 	}
 }
 
+// httpListenAddress returns the address of the running HTTP proxy.
+// The caller holds sp.mu.
+func (sp *SOCKSProxy) httpListenAddress() string {
+	if sp.httpServer == nil {
+		return ""
+	}
+	return sp.config.HTTPListenAddress
+}
+
 // GetConfig returns the current SOCKS configuration
 func (sp *SOCKSProxy) GetConfig() SOCKSConfig {
 	sp.mu.RLock()
@@ -242,15 +365,14 @@ func (sp *SOCKSProxy) SetConfig(config SOCKSConfig) error {
 		}
 	}
 
-	sp.mu.Lock()
-	sp.config = config
-	sp.mu.Unlock()
-
 	if wasRunning && config.Enabled {
-		// Restart with new config
+		// Restart with new config, which Start keeps only on success
 		return sp.Start(config)
 	}
 
+	sp.mu.Lock()
+	sp.config = config
+	sp.mu.Unlock()
 	return nil
 }
 
@@ -259,4 +381,26 @@ func (sp *SOCKSProxy) ResetStats() {
 	atomic.StoreUint64(&sp.totalConnections, 0)
 	atomic.StoreUint64(&sp.bytesIn, 0)
 	atomic.StoreUint64(&sp.bytesOut, 0)
+	atomic.StoreUint64(&sp.authFailures, 0)
+	sp.clients.reset()
+}
+
+// GetClients returns the connection and byte accounting of each client
+// address seen by the proxy
+func (sp *SOCKSProxy) GetClients() []SOCKSClientStats {
+	sp.mu.RLock()
+	allowlist := sp.allowlist
+	sp.mu.RUnlock()
+
+	return sp.clients.list(func(ip net.IP) bool {
+		return clientAllowed(allowlist, ip)
+	})
+}
+
+// SetAuthFailureHandler sets the function called when a client fails
+// username/password authentication
+func (sp *SOCKSProxy) SetAuthFailureHandler(handler func(remote, username string)) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.onAuthFailure = handler
 }
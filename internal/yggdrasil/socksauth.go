@@ -0,0 +1,110 @@
+package yggdrasil
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+// maxSOCKSCredentialLength is the RFC 1929 limit for usernames and passwords
+const maxSOCKSCredentialLength = 255
+
+// SOCKSCredentials holds the username and password clients of a SOCKS
+// proxy must present
+type SOCKSCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// validateSOCKSCredentials checks credentials against RFC 1929. Empty
+// credentials disable authentication.
+func validateSOCKSCredentials(username, password string) error {
+	if username == "" {
+		if password != "" {
+			return fmt.Errorf("SOCKS password set without a username")
+		}
+		return nil
+	}
+	if password == "" {
+		return fmt.Errorf("SOCKS password is required")
+	}
+	if len(username) > maxSOCKSCredentialLength || len(password) > maxSOCKSCredentialLength {
+		return fmt.Errorf("SOCKS username and password must be at most %d bytes", maxSOCKSCredentialLength)
+	}
+	return nil
+}
+
+// socksCredentials checks client credentials for the SOCKS server and
+// counts failures
+type socksCredentials struct {
+	proxy    *SOCKSProxy
+	username string
+	password string
+}
+
+// Valid implements socks5.CredentialStore
+func (c *socksCredentials) Valid(username, password, userAddr string) bool {
+	// Compare both fields so timing does not reveal which one was wrong
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(c.username))
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(c.password))
+	if userOK&passOK == 1 {
+		return true
+	}
+
+	sp := c.proxy
+	atomic.AddUint64(&sp.authFailures, 1)
+	sp.logger.Warn("SOCKS authentication failed", "remote", userAddr, "username", username)
+
+	sp.mu.RLock()
+	onAuthFailure := sp.onAuthFailure
+	sp.mu.RUnlock()
+	if onAuthFailure != nil {
+		onAuthFailure(userAddr, username)
+	}
+	return false
+}
+
+// socksCredentialsAccount returns the secure storage account of the SOCKS
+// credentials of a profile
+func socksCredentialsAccount(profile string) string {
+	return security.SOCKSCredentialsAccount + ":" + profile
+}
+
+// StoreSOCKSCredentials saves the SOCKS credentials of a profile in the
+// key store
+func StoreSOCKSCredentials(ks KeyStore, profile string, creds SOCKSCredentials) error {
+	if err := validateSOCKSCredentials(creds.Username, creds.Password); err != nil {
+		return err
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	// The store zeroes the value after use
+	return ks.Store(socksCredentialsAccount(profile), data)
+}
+
+// LoadSOCKSCredentials reads the SOCKS credentials of a profile from the
+// key store
+func LoadSOCKSCredentials(ks KeyStore, profile string) (*SOCKSCredentials, error) {
+	data, err := ks.Retrieve(socksCredentialsAccount(profile))
+	if err != nil {
+		return nil, err
+	}
+	defer security.ZeroBytes(data)
+
+	var creds SOCKSCredentials
+	if err := json.Unmarshal(data, &creds); err != nil || creds.Username == "" {
+		return nil, fmt.Errorf("invalid SOCKS credentials in secure storage")
+	}
+	return &creds, nil
+}
+
+// DeleteSOCKSCredentials removes the SOCKS credentials of a profile from
+// the key store
+func DeleteSOCKSCredentials(ks KeyStore, profile string) error {
+	return ks.Delete(socksCredentialsAccount(profile))
+}
@@ -0,0 +1,78 @@
+package yggdrasil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestValidateSOCKSCredentials(t *testing.T) {
+	tests := []struct {
+		username, password string
+		valid              bool
+	}{
+		{"", "", true},
+		{"alice", "secret", true},
+		{"alice", "", false},
+		{"", "secret", false},
+		{strings.Repeat("a", 256), "secret", false},
+		{"alice", strings.Repeat("p", 256), false},
+	}
+	for _, tt := range tests {
+		if err := validateSOCKSCredentials(tt.username, tt.password); (err == nil) != tt.valid {
+			t.Errorf("validateSOCKSCredentials(%q, %q) error = %v", tt.username, tt.password, err)
+		}
+	}
+}
+
+func TestSOCKSCredentials_Valid(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	sp := NewSOCKSProxy(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	var failures []string
+	sp.SetAuthFailureHandler(func(remote, username string) {
+		failures = append(failures, remote+" "+username)
+	})
+
+	creds := &socksCredentials{proxy: sp, username: "alice", password: "secret"}
+	if !creds.Valid("alice", "secret", "192.0.2.1:5000") {
+		t.Error("correct credentials were refused")
+	}
+	if creds.Valid("alice", "wrong", "192.0.2.1:5000") || creds.Valid("bob", "secret", "192.0.2.2:5000") {
+		t.Error("wrong credentials were accepted")
+	}
+
+	if got := sp.GetStats().AuthFailures; got != 2 {
+		t.Errorf("AuthFailures = %d, want 2", got)
+	}
+	if len(failures) != 2 || failures[1] != "192.0.2.2:5000 bob" {
+		t.Errorf("failures = %v", failures)
+	}
+}
+
+func TestSOCKSCredentials_Store(t *testing.T) {
+	ks := memKeyStore{}
+
+	if err := StoreSOCKSCredentials(ks, "lab", SOCKSCredentials{Username: "alice"}); err == nil {
+		t.Error("credentials without password should be rejected")
+	}
+	if err := StoreSOCKSCredentials(ks, "lab", SOCKSCredentials{Username: "alice", Password: "secret"}); err != nil {
+		t.Fatalf("StoreSOCKSCredentials() error = %v", err)
+	}
+
+	creds, err := LoadSOCKSCredentials(ks, "lab")
+	if err != nil || creds.Username != "alice" || creds.Password != "secret" {
+		t.Fatalf("LoadSOCKSCredentials() = %+v, %v", creds, err)
+	}
+	if _, err := LoadSOCKSCredentials(ks, "default"); err == nil {
+		t.Error("other profiles should have no credentials")
+	}
+
+	if err := DeleteSOCKSCredentials(ks, "lab"); err != nil {
+		t.Fatalf("DeleteSOCKSCredentials() error = %v", err)
+	}
+	if _, err := LoadSOCKSCredentials(ks, "lab"); err == nil {
+		t.Error("deleted credentials should be gone")
+	}
+}
@@ -0,0 +1,240 @@
+package yggdrasil
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSOCKSClients bounds the per-client table. When it is full, the client
+// seen least recently without open connections is dropped.
+const maxSOCKSClients = 1024
+
+// SOCKSClientStats contains the accounting of one SOCKS client address
+type SOCKSClientStats struct {
+	Address             string `json:"address"`
+	Allowed             bool   `json:"allowed"` // Passes the current allowlist
+	ActiveConnections   int64  `json:"activeConnections"`
+	TotalConnections    uint64 `json:"totalConnections"`
+	RejectedConnections uint64 `json:"rejectedConnections"`
+	BytesIn             uint64 `json:"bytesIn"`  // Sent to the client
+	BytesOut            uint64 `json:"bytesOut"` // Received from the client
+	LastSeen            int64  `json:"lastSeen"` // Unix milliseconds
+}
+
+// socksClient holds the counters of one client address
+type socksClient struct {
+	address             string
+	activeConnections   int64
+	totalConnections    uint64
+	rejectedConnections uint64
+	bytesIn             uint64
+	bytesOut            uint64
+	lastSeen            int64
+	lastRejectLog       int64
+}
+
+// socksClients is the per-client table of a SOCKS proxy
+type socksClients struct {
+	mu      sync.Mutex
+	clients map[string]*socksClient
+}
+
+func newSOCKSClients() *socksClients {
+	return &socksClients{clients: make(map[string]*socksClient)}
+}
+
+// get returns the entry of a client address, creating it if needed
+func (t *socksClients) get(address string, now time.Time) *socksClient {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.clients[address]
+	if !ok {
+		if len(t.clients) >= maxSOCKSClients {
+			t.evictLocked()
+		}
+		c = &socksClient{address: address}
+		t.clients[address] = c
+	}
+	atomic.StoreInt64(&c.lastSeen, now.UnixMilli())
+	return c
+}
+
+// evictLocked drops the idle client seen least recently
+func (t *socksClients) evictLocked() {
+	var oldest *socksClient
+	for _, c := range t.clients {
+		if atomic.LoadInt64(&c.activeConnections) > 0 {
+			continue
+		}
+		if oldest == nil || atomic.LoadInt64(&c.lastSeen) < atomic.LoadInt64(&oldest.lastSeen) {
+			oldest = c
+		}
+	}
+	if oldest != nil {
+		delete(t.clients, oldest.address)
+	}
+}
+
+// list returns the stats of all clients, sorted by address
+func (t *socksClients) list(allowed func(net.IP) bool) []SOCKSClientStats {
+	t.mu.Lock()
+	clients := make([]*socksClient, 0, len(t.clients))
+	for _, c := range t.clients {
+		clients = append(clients, c)
+	}
+	t.mu.Unlock()
+
+	stats := make([]SOCKSClientStats, len(clients))
+	for i, c := range clients {
+		stats[i] = SOCKSClientStats{
+			Address:             c.address,
+			Allowed:             allowed(net.ParseIP(c.address)),
+			ActiveConnections:   atomic.LoadInt64(&c.activeConnections),
+			TotalConnections:    atomic.LoadUint64(&c.totalConnections),
+			RejectedConnections: atomic.LoadUint64(&c.rejectedConnections),
+			BytesIn:             atomic.LoadUint64(&c.bytesIn),
+			BytesOut:            atomic.LoadUint64(&c.bytesOut),
+			LastSeen:            atomic.LoadInt64(&c.lastSeen),
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Address < stats[j].Address })
+	return stats
+}
+
+// reset clears the table
+func (t *socksClients) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clients = make(map[string]*socksClient)
+}
+
+// parseClientAllowlist parses client addresses and CIDRs. An empty list
+// allows every client.
+func parseClientAllowlist(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(strings.Trim(entry, "[]"))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid client address: %s", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client subnet: %s", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ValidateClientAllowlist checks the client addresses and CIDRs of a SOCKS
+// proxy allowlist
+func ValidateClientAllowlist(entries []string) error {
+	_, err := parseClientAllowlist(entries)
+	return err
+}
+
+// clientAllowed reports whether ip is in the allowlist
+func clientAllowed(allowlist []*net.IPNet, ip net.IP) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, network := range allowlist {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientListener checks SOCKS clients against the allowlist at accept time
+// and accounts their connections
+type clientListener struct {
+	net.Listener
+	proxy     *SOCKSProxy
+	allowlist []*net.IPNet
+}
+
+// Accept returns the next allowed client connection
+func (l *clientListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		var ip net.IP
+		if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			ip = addr.IP
+		}
+		now := time.Now()
+		client := l.proxy.clients.get(ip.String(), now)
+
+		if !clientAllowed(l.allowlist, ip) {
+			atomic.AddUint64(&client.rejectedConnections, 1)
+			// Log a client again only after rejectLogInterval
+			last := atomic.LoadInt64(&client.lastRejectLog)
+			if now.Sub(time.Unix(0, last)) >= rejectLogInterval &&
+				atomic.CompareAndSwapInt64(&client.lastRejectLog, last, now.UnixNano()) {
+				l.proxy.logger.Warn("Rejected SOCKS client not in allowlist", "remote", conn.RemoteAddr().String())
+			}
+			conn.Close()
+			continue
+		}
+
+		atomic.AddInt64(&client.activeConnections, 1)
+		atomic.AddUint64(&client.totalConnections, 1)
+		return &clientConn{Conn: conn, client: client}, nil
+	}
+}
+
+// clientConn counts the traffic of a client connection
+type clientConn struct {
+	net.Conn
+	client *socksClient
+	closed int32
+}
+
+func (c *clientConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		atomic.AddUint64(&c.client.bytesOut, uint64(n))
+	}
+	return n, err
+}
+
+func (c *clientConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.AddUint64(&c.client.bytesIn, uint64(n))
+	}
+	return n, err
+}
+
+func (c *clientConn) Close() error {
+	if atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		atomic.AddInt64(&c.client.activeConnections, -1)
+		atomic.StoreInt64(&c.client.lastSeen, time.Now().UnixMilli())
+	}
+	return c.Conn.Close()
+}
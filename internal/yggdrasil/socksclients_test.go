@@ -0,0 +1,128 @@
+package yggdrasil
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestParseClientAllowlist(t *testing.T) {
+	allowlist, err := parseClientAllowlist([]string{"192.168.1.0/24", " 10.0.0.5 ", "fd00::/8", "[::1]", ""})
+	if err != nil {
+		t.Fatalf("parseClientAllowlist() error = %v", err)
+	}
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"192.168.1.20", true},
+		{"192.168.2.20", false},
+		{"10.0.0.5", true},
+		{"10.0.0.6", false},
+		{"fd12::1", true},
+		{"::1", true},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := clientAllowed(allowlist, net.ParseIP(tt.ip)); got != tt.allowed {
+			t.Errorf("clientAllowed(%s) = %v, want %v", tt.ip, got, tt.allowed)
+		}
+	}
+
+	if !clientAllowed(nil, net.ParseIP("203.0.113.1")) {
+		t.Error("an empty allowlist should allow every client")
+	}
+	for _, entry := range []string{"192.168.1.0/33", "not-an-ip"} {
+		if _, err := parseClientAllowlist([]string{entry}); err == nil {
+			t.Errorf("parseClientAllowlist(%q) should fail", entry)
+		}
+	}
+}
+
+func TestClientListener(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	sp := NewSOCKSProxy(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	listen := func(entries ...string) (net.Listener, <-chan net.Conn) {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		allowlist, _ := parseClientAllowlist(entries)
+		sp.mu.Lock()
+		sp.allowlist = allowlist
+		sp.mu.Unlock()
+		l := &clientListener{Listener: inner, proxy: sp, allowlist: allowlist}
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				accepted <- conn
+			}
+		}()
+		return l, accepted
+	}
+
+	// Loopback is not in the allowlist, so the connection is closed
+	l, _ := listen("10.0.0.0/8")
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("rejected client read error = %v, want EOF", err)
+	}
+	conn.Close()
+	l.Close()
+
+	// Allow loopback and account a connection
+	l, accepted := listen("127.0.0.0/8")
+	defer l.Close()
+	conn, err = net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var server net.Conn
+	select {
+	case server = <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("allowed client was not accepted")
+	}
+	conn.Write([]byte("hello"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(server, buf); err != nil {
+		t.Fatal(err)
+	}
+	server.Write([]byte("hi"))
+
+	clients := sp.GetClients()
+	if len(clients) != 1 {
+		t.Fatalf("GetClients() = %+v", clients)
+	}
+	c := clients[0]
+	if c.Address != "127.0.0.1" || !c.Allowed || c.ActiveConnections != 1 || c.TotalConnections != 1 ||
+		c.RejectedConnections != 1 || c.BytesOut != 5 || c.BytesIn != 2 {
+		t.Errorf("client stats = %+v", c)
+	}
+
+	server.Close()
+	server.Close()
+	if got := sp.GetClients()[0].ActiveConnections; got != 0 {
+		t.Errorf("ActiveConnections after close = %d, want 0", got)
+	}
+
+	sp.ResetStats()
+	if len(sp.GetClients()) != 0 {
+		t.Error("ResetStats() should clear the clients")
+	}
+}
@@ -0,0 +1,193 @@
+package yggdrasil
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/things-go/go-socks5"
+	"github.com/things-go/go-socks5/statute"
+)
+
+// maxUDPDatagram is the largest UDP payload relayed for a SOCKS client
+const maxUDPDatagram = 65535
+
+// socksAssociation relays the UDP datagrams of one SOCKS UDP ASSOCIATE
+// request. It lives until the TCP control connection closes.
+type socksAssociation struct {
+	proxy    *SOCKSProxy
+	resolver socks5.NameResolver
+	router   *router        // nil when every destination goes over Yggdrasil
+	relay    *net.UDPConn   // Faces the SOCKS client
+	remote   net.PacketConn // Faces the Yggdrasil network
+	clientIP net.IP
+	client   *socksClient
+	peer     atomic.Pointer[net.UDPAddr] // Client UDP address, set by its first datagram
+}
+
+// createAssociateHandler creates the UDP ASSOCIATE handler. listen opens
+// the Yggdrasil side socket of an association.
+func (sp *SOCKSProxy) createAssociateHandler(ctx context.Context, listen func() (net.PacketConn, error),
+	resolver socks5.NameResolver, r *router) func(context.Context, io.Writer, *socks5.Request) error {
+	return func(_ context.Context, writer io.Writer, request *socks5.Request) error {
+		var clientIP, localIP net.IP
+		if addr, ok := request.RemoteAddr.(*net.TCPAddr); ok {
+			clientIP = addr.IP
+		}
+		if addr, ok := request.LocalAddr.(*net.TCPAddr); ok {
+			localIP = addr.IP
+		}
+
+		// Bind the relay to the address the client reached us on, so the
+		// reply carries an address it can send to
+		relay, err := net.ListenUDP("udp", &net.UDPAddr{IP: localIP})
+		if err != nil {
+			socks5.SendReply(writer, statute.RepServerFailure, nil)
+			return fmt.Errorf("failed to open UDP relay: %w", err)
+		}
+		remote, err := listen()
+		if err != nil {
+			relay.Close()
+			socks5.SendReply(writer, statute.RepServerFailure, nil)
+			return fmt.Errorf("failed to open Yggdrasil UDP socket: %w", err)
+		}
+
+		a := &socksAssociation{
+			proxy:    sp,
+			resolver: resolver,
+			router:   r,
+			relay:    relay,
+			remote:   remote,
+			clientIP: clientIP,
+			client:   sp.clients.get(clientIP.String(), time.Now()),
+		}
+		// A client announcing its UDP port fixes the peer upfront
+		if dst := request.DestAddr; dst != nil && dst.Port != 0 && dst.IP != nil && !dst.IP.IsUnspecified() {
+			a.peer.Store(&net.UDPAddr{IP: dst.IP, Port: dst.Port})
+		}
+
+		if err := socks5.SendReply(writer, statute.RepSuccess, relay.LocalAddr()); err != nil {
+			relay.Close()
+			remote.Close()
+			return fmt.Errorf("failed to send reply: %w", err)
+		}
+
+		atomic.AddInt64(&sp.activeConnections, 1)
+		atomic.AddUint64(&sp.totalConnections, 1)
+		atomic.AddInt64(&sp.udpAssociations, 1)
+		defer func() {
+			atomic.AddInt64(&sp.udpAssociations, -1)
+			atomic.AddInt64(&sp.activeConnections, -1)
+		}()
+
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			// Stopping the proxy ends the association and its control
+			// connection
+			select {
+			case <-ctx.Done():
+				if c, ok := writer.(io.Closer); ok {
+					c.Close()
+				}
+			case <-done:
+			}
+			relay.Close()
+			remote.Close()
+		}()
+
+		go a.clientToRemote(ctx)
+		go a.remoteToClient()
+
+		// The association ends when the control connection closes
+		io.Copy(io.Discard, request.Reader)
+		return nil
+	}
+}
+
+// clientToRemote sends the datagrams of the client into Yggdrasil
+func (a *socksAssociation) clientToRemote(ctx context.Context) {
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, from, err := a.relay.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		// Only the client of the control connection may use the relay
+		if !from.IP.Equal(a.clientIP) {
+			continue
+		}
+		if peer := a.peer.Load(); peer == nil {
+			a.peer.Store(from)
+		} else if peer.Port != from.Port {
+			continue
+		}
+
+		datagram, err := statute.ParseDatagram(buf[:n])
+		if err != nil || datagram.Frag != 0 {
+			// Fragmented datagrams are not supported and are dropped
+			continue
+		}
+
+		// Datagrams are only relayed over Yggdrasil
+		if a.router != nil {
+			host := datagram.DstAddr.FQDN
+			if host == "" {
+				host = datagram.DstAddr.IP.String()
+			}
+			if a.router.route(host) != RouteYggdrasil {
+				continue
+			}
+		}
+
+		ip := datagram.DstAddr.IP
+		if datagram.DstAddr.FQDN != "" {
+			if _, ip, err = a.resolver.Resolve(ctx, datagram.DstAddr.FQDN); err != nil {
+				a.proxy.logger.Debug("SOCKS UDP resolve failed", "host", datagram.DstAddr.FQDN, "error", err)
+				continue
+			}
+		}
+
+		written, err := a.remote.WriteTo(datagram.Data, &net.UDPAddr{IP: ip, Port: datagram.DstAddr.Port})
+		if err != nil {
+			a.proxy.logger.Debug("SOCKS UDP send failed", "target", datagram.DstAddr.String(), "error", err)
+			continue
+		}
+		atomic.AddUint64(&a.proxy.bytesOut, uint64(written))
+		atomic.AddUint64(&a.client.bytesOut, uint64(written))
+	}
+}
+
+// remoteToClient returns datagrams from Yggdrasil to the client, with the
+// address of their sender in the SOCKS header
+func (a *socksAssociation) remoteToClient() {
+	buf := make([]byte, maxUDPDatagram)
+	for {
+		n, from, err := a.remote.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		source, ok := from.(*net.UDPAddr)
+		peer := a.peer.Load()
+		if !ok || peer == nil {
+			continue
+		}
+
+		addrType := statute.ATYPIPv6
+		if source.IP.To4() != nil {
+			addrType = statute.ATYPIPv4
+		}
+		datagram := statute.Datagram{
+			DstAddr: statute.AddrSpec{IP: source.IP, Port: source.Port, AddrType: addrType},
+			Data:    buf[:n],
+		}
+		if _, err := a.relay.WriteToUDP(datagram.Bytes(), peer); err != nil {
+			continue
+		}
+		atomic.AddUint64(&a.proxy.bytesIn, uint64(n))
+		atomic.AddUint64(&a.client.bytesIn, uint64(n))
+	}
+}
@@ -0,0 +1,119 @@
+package yggdrasil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/things-go/go-socks5"
+	"github.com/things-go/go-socks5/statute"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestSOCKSProxy_UDPAssociate(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	sp := NewSOCKSProxy(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	// UDP echo service standing in for a host on the Yggdrasil network
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	// Loopback sockets stand in for the Yggdrasil side of associations
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listenUDP := func() (net.PacketConn, error) {
+		return net.ListenPacket("udp", "127.0.0.1:0")
+	}
+	server := socks5.NewServer(socks5.WithAssociateHandle(
+		sp.createAssociateHandler(ctx, listenUDP, NewNameResolver(nil, ""), nil)))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go server.Serve(l)
+
+	control, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Close()
+	control.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Greeting without authentication, then UDP ASSOCIATE from 0.0.0.0:0
+	control.Write([]byte{statute.VersionSocks5, 1, statute.MethodNoAuth})
+	method := make([]byte, 2)
+	if _, err := io.ReadFull(control, method); err != nil || method[1] != statute.MethodNoAuth {
+		t.Fatalf("method reply = %v, %v", method, err)
+	}
+	control.Write([]byte{statute.VersionSocks5, statute.CommandAssociate, 0, statute.ATYPIPv4, 0, 0, 0, 0, 0, 0})
+	reply, err := statute.ParseReply(control)
+	if err != nil || reply.Response != statute.RepSuccess {
+		t.Fatalf("associate reply = %+v, %v", reply, err)
+	}
+
+	client, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	relay := &net.UDPAddr{IP: reply.BndAddr.IP, Port: reply.BndAddr.Port}
+
+	datagram, err := statute.NewDatagram(echo.LocalAddr().String(), []byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.WriteToUDP(datagram.Bytes(), relay); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatalf("reading relayed reply: %v", err)
+	}
+	response, err := statute.ParseDatagram(buf[:n])
+	if err != nil || !bytes.Equal(response.Data, []byte("ping")) || response.DstAddr.String() != echo.LocalAddr().String() {
+		t.Fatalf("relayed reply = %+v, %v", response, err)
+	}
+
+	stats := sp.GetStats()
+	if stats.UDPAssociations != 1 || stats.BytesOut != 4 || stats.BytesIn != 4 {
+		t.Errorf("stats during association = %+v", stats)
+	}
+
+	// Closing the control connection ends the association
+	control.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for sp.GetStats().UDPAssociations != 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if stats := sp.GetStats(); stats.UDPAssociations != 0 || stats.ActiveConnections != 0 {
+		t.Errorf("stats after control connection closed = %+v", stats)
+	}
+	if _, err := client.WriteToUDP(datagram.Bytes(), relay); err == nil {
+		client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, err := client.Read(buf); err == nil {
+			t.Error("relay should be closed with the control connection")
+		}
+	}
+}
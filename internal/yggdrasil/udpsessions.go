@@ -0,0 +1,157 @@
+package yggdrasil
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUDPIdleTimeout is how long a UDP session of a port mapping may
+// stay without traffic before its socket is closed
+const DefaultUDPIdleTimeout = 120 * time.Second
+
+// udpSession is the socket a UDP mapping keeps for one client
+type udpSession struct {
+	conn       net.Conn
+	client     net.Addr
+	lastActive int64 // Unix nanoseconds
+	users      int32 // Packets being forwarded to the session
+}
+
+// release ends the use of a session returned by get or add
+func (s *udpSession) release() {
+	s.touch(time.Now())
+	atomic.AddInt32(&s.users, -1)
+}
+
+// touch records traffic on the session
+func (s *udpSession) touch(now time.Time) {
+	atomic.StoreInt64(&s.lastActive, now.UnixNano())
+}
+
+// idleSince reports whether the session had no traffic after t
+func (s *udpSession) idleSince(t time.Time) bool {
+	return atomic.LoadInt64(&s.lastActive) < t.UnixNano()
+}
+
+// udpSessionTable tracks the client sessions of a UDP mapping and closes
+// those idle for longer than the timeout
+type udpSessionTable struct {
+	mu        sync.Mutex
+	sessions  map[string]*udpSession
+	timeout   time.Duration
+	evictions uint64
+}
+
+// newUDPSessionTable creates a session table. A timeout of 0 uses
+// DefaultUDPIdleTimeout.
+func newUDPSessionTable(timeout time.Duration) *udpSessionTable {
+	if timeout <= 0 {
+		timeout = DefaultUDPIdleTimeout
+	}
+	return &udpSessionTable{
+		sessions: make(map[string]*udpSession),
+		timeout:  timeout,
+	}
+}
+
+// get returns the session of a client and marks it in use, so that it is
+// not expired before the caller releases it
+func (t *udpSessionTable) get(key string) *udpSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[key]
+	if !ok {
+		return nil
+	}
+	s.touch(time.Now())
+	atomic.AddInt32(&s.users, 1)
+	return s
+}
+
+// add registers a new client session, in use like one returned by get
+func (t *udpSessionTable) add(key string, conn net.Conn, client net.Addr) *udpSession {
+	s := &udpSession{conn: conn, client: client, users: 1}
+	s.touch(time.Now())
+
+	t.mu.Lock()
+	t.sessions[key] = s
+	t.mu.Unlock()
+	return s
+}
+
+// remove drops a session from the table if it is still registered
+func (t *udpSessionTable) remove(key string, s *udpSession) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessions[key] == s {
+		delete(t.sessions, key)
+	}
+}
+
+// expire closes the sessions idle since before now minus the timeout and
+// returns how many were evicted. Sessions in use are kept.
+func (t *udpSessionTable) expire(now time.Time) int {
+	cutoff := now.Add(-t.timeout)
+
+	t.mu.Lock()
+	var idle []*udpSession
+	for key, s := range t.sessions {
+		if atomic.LoadInt32(&s.users) == 0 && s.idleSince(cutoff) {
+			idle = append(idle, s)
+			delete(t.sessions, key)
+		}
+	}
+	t.mu.Unlock()
+
+	// Closing ends the read loop of the session
+	for _, s := range idle {
+		s.conn.Close()
+	}
+	atomic.AddUint64(&t.evictions, uint64(len(idle)))
+	return len(idle)
+}
+
+// closeAll closes every session, when the mapping stops
+func (t *udpSessionTable) closeAll() {
+	t.mu.Lock()
+	sessions := t.sessions
+	t.sessions = make(map[string]*udpSession)
+	t.mu.Unlock()
+
+	for _, s := range sessions {
+		s.conn.Close()
+	}
+}
+
+// len returns the number of open sessions
+func (t *udpSessionTable) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.sessions)
+}
+
+// evicted returns the number of sessions closed for being idle
+func (t *udpSessionTable) evicted() uint64 {
+	return atomic.LoadUint64(&t.evictions)
+}
+
+// run expires idle sessions until ctx is cancelled, then closes the rest
+func (t *udpSessionTable) run(ctx context.Context) {
+	ticker := time.NewTicker(t.timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.closeAll()
+			return
+		case now := <-ticker.C:
+			t.expire(now)
+		}
+	}
+}
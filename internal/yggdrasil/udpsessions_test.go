@@ -0,0 +1,143 @@
+package yggdrasil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/logger"
+)
+
+func TestUDPSessionTable_Expire(t *testing.T) {
+	table := newUDPSessionTable(time.Minute)
+	start := time.Now()
+
+	var conns []net.Conn
+	for i := 0; i < 3; i++ {
+		c1, c2 := net.Pipe()
+		defer c2.Close()
+		conns = append(conns, c1)
+		table.add(fmt.Sprintf("client-%d", i), c1, nil).release()
+	}
+	// client-1 stays active
+	table.sessions["client-1"].touch(start.Add(2 * time.Minute))
+
+	if n := table.expire(start.Add(90 * time.Second)); n != 2 {
+		t.Errorf("expire() = %d, want 2", n)
+	}
+	if table.len() != 1 || table.evicted() != 2 {
+		t.Errorf("len = %d, evicted = %d", table.len(), table.evicted())
+	}
+
+	// A session in use is not closed under its user, however idle
+	inUse := table.get("client-1")
+	if inUse == nil {
+		t.Fatal("active session was evicted")
+	}
+	if n := table.expire(start.Add(time.Hour)); n != 0 {
+		t.Errorf("expire() evicted a session in use")
+	}
+	inUse.release()
+	if n := table.expire(time.Now().Add(time.Hour)); n != 1 {
+		t.Errorf("expire() after release = %d, want 1", n)
+	}
+
+	// Evicted sessions are closed
+	if _, err := conns[0].Write([]byte("x")); err == nil {
+		t.Error("evicted session should be closed")
+	}
+
+	// Removing a replaced session keeps the new one
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	old := table.add("client-1", c1, nil)
+	c3, c4 := net.Pipe()
+	defer c3.Close()
+	defer c4.Close()
+	table.add("client-1", c3, nil)
+	table.remove("client-1", old)
+	if table.len() != 1 {
+		t.Error("remove() dropped the replacing session")
+	}
+
+	table.closeAll()
+	if table.len() != 0 {
+		t.Error("closeAll() should empty the table")
+	}
+}
+
+func TestMappingManager_UDPSessionsExpire(t *testing.T) {
+	log := logger.NewWithConfig(logger.Config{Level: "error", Console: false})
+	mm := NewMappingManager(NewServiceWithConfig(newTestConfigManager(t), log), log)
+
+	// Local echo service behind the mapping
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	// A loopback socket stands in for the Yggdrasil listener
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := PortMapping{ID: "dns", Type: MappingRemoteUDP, Source: listener.LocalAddr().String(),
+		Target: echo.LocalAddr().String(), Enabled: true, Active: true}
+	fwd := &portForwarder{mapping: mapping, udpConn: listener, limits: newMappingLimits(mapping), logger: log}
+	fwd.ctx, fwd.cancel = context.WithCancel(context.Background())
+	fwd.sessions = newUDPSessionTable(200 * time.Millisecond)
+	mm.forwarders[mapping.ID] = fwd
+	defer mm.RemoveMapping(mapping.ID)
+
+	go fwd.sessions.run(fwd.ctx)
+	go mm.handleRemoteUDP(fwd, fwd.sessions)
+
+	// Many short-lived clients, each sending one request
+	const clients = 50
+	for i := 0; i < clients; i++ {
+		conn, err := net.Dial("udp", listener.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 16)
+		if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "ping" {
+			t.Fatalf("client %d reply = %q, %v", i, buf[:n], err)
+		}
+		conn.Close()
+	}
+
+	stats, _ := mm.GetMappingStats(mapping.ID)
+	if stats.TotalConnections != clients || stats.ActiveSessions == 0 {
+		t.Fatalf("stats after clients = %+v", stats)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		stats, _ = mm.GetMappingStats(mapping.ID)
+		if (stats.ActiveSessions == 0 && stats.ActiveConnections == 0) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if stats.ActiveSessions != 0 || stats.ActiveConnections != 0 || stats.EvictedSessions != clients {
+		t.Errorf("stats after idle timeout = %+v", stats)
+	}
+}
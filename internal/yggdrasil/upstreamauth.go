@@ -0,0 +1,71 @@
+package yggdrasil
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/JB-SelfCompany/yggstack-gui/internal/security"
+)
+
+// SplitUpstreamCredentials returns the upstream proxy URL without its
+// user info, and the user info. Values that do not parse are returned
+// unchanged.
+func SplitUpstreamCredentials(upstream string) (string, *url.Userinfo) {
+	u, err := url.Parse(upstream)
+	if err != nil || u.User == nil {
+		return upstream, nil
+	}
+	user := u.User
+	u.User = nil
+	return u.String(), user
+}
+
+// WithUpstreamCredentials adds user info to an upstream proxy URL
+func WithUpstreamCredentials(upstream string, user *url.Userinfo) string {
+	u, err := url.Parse(upstream)
+	if err != nil || upstream == "" || user == nil {
+		return upstream
+	}
+	u.User = user
+	return u.String()
+}
+
+// upstreamCredentialsAccount returns the secure storage account of the
+// upstream proxy credentials of a profile
+func upstreamCredentialsAccount(profile string) string {
+	return security.UpstreamCredentialsAccount + ":" + profile
+}
+
+// StoreUpstreamCredentials saves the upstream proxy user info of a profile
+// in the key store
+func StoreUpstreamCredentials(ks KeyStore, profile string, user *url.Userinfo) error {
+	if user == nil || user.Username() == "" {
+		return fmt.Errorf("upstream proxy username is required")
+	}
+	// The store zeroes the value after use
+	return ks.Store(upstreamCredentialsAccount(profile), []byte(user.String()))
+}
+
+// LoadUpstreamCredentials reads the upstream proxy user info of a profile
+// from the key store
+func LoadUpstreamCredentials(ks KeyStore, profile string) (*url.Userinfo, error) {
+	data, err := ks.Retrieve(upstreamCredentialsAccount(profile))
+	if err != nil {
+		return nil, err
+	}
+	defer security.ZeroBytes(data)
+
+	// Parse the user info as part of a URL so escaping is undone the same
+	// way as in the upstream address
+	u, err := url.Parse("socks5://" + string(data) + "@localhost")
+	if err != nil || u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid upstream proxy credentials in secure storage")
+	}
+	return u.User, nil
+}
+
+// DeleteUpstreamCredentials removes the upstream proxy credentials of a
+// profile from the key store
+func DeleteUpstreamCredentials(ks KeyStore, profile string) error {
+	return ks.Delete(upstreamCredentialsAccount(profile))
+}